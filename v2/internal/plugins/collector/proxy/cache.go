@@ -0,0 +1,78 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maximum number of entries kept by a single cache (new entries are not cached when limit is reached)
+const maxCacheEntries = 100000
+
+// boundedCache is a lock-free (for reads) cache of values which are calculated once per key (ie. namespace)
+// and never change during task lifetime.
+// Every entry remembers the last collect in which it was used. Entries not used during the previous collect
+// are evicted when the next one is started (see: evictUnused), so the cache holds only the recently used values.
+type boundedCache struct {
+	entries sync.Map
+	size    int64
+	collect int64 // number of current collect
+}
+
+type cacheEntry struct {
+	value    interface{}
+	lastUsed int64 // number of the last collect in which entry was used
+}
+
+func (c *boundedCache) Load(key string) (interface{}, bool) {
+	v, ok := c.entries.Load(key)
+	if !ok {
+		return nil, false
+	}
+
+	e := v.(*cacheEntry)
+	if collect := atomic.LoadInt64(&c.collect); atomic.LoadInt64(&e.lastUsed) != collect {
+		atomic.StoreInt64(&e.lastUsed, collect)
+	}
+
+	return e.value, true
+}
+
+func (c *boundedCache) Store(key string, value interface{}) {
+	if atomic.LoadInt64(&c.size) >= maxCacheEntries {
+		return
+	}
+
+	e := &cacheEntry{value: value, lastUsed: atomic.LoadInt64(&c.collect)}
+	if _, loaded := c.entries.LoadOrStore(key, e); !loaded {
+		atomic.AddInt64(&c.size, 1)
+	}
+}
+
+// evictUnused should be called when new collect is started. Entries which weren't used during the previous one are removed.
+func (c *boundedCache) evictUnused() {
+	previous := atomic.AddInt64(&c.collect, 1) - 1
+
+	c.entries.Range(func(key, v interface{}) bool {
+		if atomic.LoadInt64(&v.(*cacheEntry).lastUsed) < previous {
+			c.entries.Delete(key)
+			atomic.AddInt64(&c.size, -1)
+		}
+		return true
+	})
+}
@@ -0,0 +1,57 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBoundedCache(t *testing.T) {
+	Convey("Validate that entries not used during the previous collect are evicted", t, func() {
+		c := &boundedCache{}
+
+		c.Store("a", 1)
+		c.Store("b", 2)
+
+		c.evictUnused() // collect 1
+		_, _ = c.Load("a")
+
+		c.evictUnused() // collect 2
+
+		v, ok := c.Load("a")
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 1)
+
+		_, ok = c.Load("b")
+		So(ok, ShouldBeFalse)
+		So(c.size, ShouldEqual, 1)
+	})
+
+	Convey("Validate that new entries aren't cached when limit is reached", t, func() {
+		c := &boundedCache{size: maxCacheEntries}
+
+		c.Store("a", 1)
+
+		_, ok := c.Load("a")
+		So(ok, ShouldBeFalse)
+	})
+}
@@ -40,6 +40,7 @@ type modifiersMetadata struct {
 	nsSelector string
 	modifiers  []plugin.MetricModifier
 	validator  *metrictree.TreeValidator
+	matches    *boundedCache // (namespace -> bool) results of validation, shared by modifiers with the same selector
 	active     bool
 }
 
@@ -57,8 +58,26 @@ type PluginContext struct {
 	metricsFilters  *metrictree.TreeValidator // metric filters defined by task (yaml)
 	sessionMtsMutex sync.RWMutex
 	sessionMts      []*types.Metric
+	limiter         *cardinalityLimiter // enforces cardinality limits defined by plugin
+	dedup           *deduplicator       // detects duplicated metrics (when enabled by plugin)
+	workers         *workerGroup        // goroutines started by Go
+	ctxManager      *ContextManager     // back-reference to context manager
+
+	modifiersMutex  sync.RWMutex
 	modifiersTable  []*modifiersMetadata
-	ctxManager      *ContextManager // back-reference to context manager
+	selectorMatches map[string]*boundedCache // (selector -> cache) kept between collects, since the same selectors are usually applied
+
+	nsCache     boundedCache // (namespace -> *namespaceInfo) results of namespace validation
+	filterCache boundedCache // (namespace -> bool) results of filtering metrics added via handles
+}
+
+// Result of validation of namespace passed to AddMetric (depends only on definition and task filters)
+type namespaceInfo struct {
+	err         error
+	filtered    bool
+	mtNamespace []types.NamespaceElement // should be copied before usage
+	nsDescKey   string
+	meta        metricMetadata
 }
 
 func NewPluginContext(ctxManager *ContextManager, taskID string, rawConfig []byte) (*PluginContext, error) {
@@ -72,58 +91,109 @@ func NewPluginContext(ctxManager *ContextManager, taskID string, rawConfig []byt
 	}
 
 	pc := &PluginContext{
-		Context:        baseContext,
-		ctx:            ctxManager.ctx,
-		taskID:         taskID,
-		metricsFilters: metrictree.NewMetricFilter(ctxManager.metricsDefinition),
-		ctxManager:     ctxManager,
-		sessionMts:     nil,
+		Context:         baseContext,
+		ctx:             ctxManager.ctx,
+		taskID:          taskID,
+		metricsFilters:  metrictree.NewMetricFilter(ctxManager.metricsDefinition),
+		limiter:         newCardinalityLimiter(ctxManager.cardinalityLimits),
+		dedup:           newDeduplicator(ctxManager.dedupPolicy),
+		workers:         newWorkerGroup(ctxManager.collectConcurrency),
+		ctxManager:      ctxManager,
+		sessionMts:      nil,
+		selectorMatches: map[string]*boundedCache{},
 	}
 
 	return pc, nil
 }
 
 func (pc *PluginContext) AddMetric(ns string, v interface{}, modifiers ...plugin.MetricModifier) error {
-	logF := log.WithCtx(pc.ctx).WithFields(moduleFields).WithField("service", "metrics")
-
 	if pc.IsDone() {
 		return fmt.Errorf("task has been canceled")
 	}
 
+	mt, nsDescKey, err := pc.prepareMetric(ns, v, modifiers)
+	if mt == nil {
+		return err
+	}
+
+	return pc.commitMetric(mt, nsDescKey)
+}
+
+// Validate namespace and create metric with all modifiers applied (doesn't require session lock).
+// Returns nil metric when it shouldn't be added (error is nil when metric was just filtered).
+func (pc *PluginContext) prepareMetric(ns string, v interface{}, modifiers []plugin.MetricModifier) (*types.Metric, string, error) {
 	if !pc.isValidValueType(v) {
-		return fmt.Errorf("invalid value type (%T) for metric: %s", v, ns)
+		return nil, "", fmt.Errorf("invalid value type (%T) for metric: %s", v, ns)
 	}
 
 	if len(ns) == 0 {
-		return fmt.Errorf("empty namespace")
+		return nil, "", fmt.Errorf("empty namespace")
+	}
+
+	nsInfo := pc.validateNamespace(ns)
+	if nsInfo.err != nil {
+		return nil, "", nsInfo.err
+	}
+
+	if nsInfo.filtered {
+		if logrus.IsLevelEnabled(logrus.TraceLevel) {
+			logF := log.WithCtx(pc.ctx).WithFields(moduleFields).WithField("service", "metrics")
+			logF.WithField("ns", ns).Trace("couldn't match metrics with plugin filters")
+		}
+		return nil, "", nil // don't throw error when metric is just filtered
+	}
+
+	mtNamespace := make([]types.NamespaceElement, len(nsInfo.mtNamespace))
+	copy(mtNamespace, nsInfo.mtNamespace)
+
+	mt := &types.Metric{
+		Namespace_:   mtNamespace,
+		Value_:       v,
+		Unit_:        nsInfo.meta.unit,
+		Timestamp_:   time.Now(),
+		Description_: nsInfo.meta.description,
 	}
 
+	pc.applyModifiers(mt, ns, modifiers)
+
+	return mt, nsInfo.nsDescKey, nil
+}
+
+// Validate namespace against definition and filters. Result is cached, since the same namespaces are added in every collect.
+func (pc *PluginContext) validateNamespace(ns string) *namespaceInfo {
+	if nsInfo, ok := pc.nsCache.Load(ns); ok {
+		return nsInfo.(*namespaceInfo)
+	}
+
+	nsInfo := pc.parseNamespace(ns)
+	pc.nsCache.Store(ns, nsInfo)
+
+	return nsInfo
+}
+
+func (pc *PluginContext) parseNamespace(ns string) *namespaceInfo {
 	if pc.ctxManager.globalPrefix.enabled {
 		ns = fmt.Sprintf("%s%s%s", string(ns[0]), pc.ctxManager.globalPrefix.name, ns)
 	}
 
 	if err := pc.ctxManager.metricsDefinition.IsUsableForAddition(ns, false); err != nil {
-		return fmt.Errorf("invalid namespace (some elements can't be used when adding metric): %w", err)
+		return &namespaceInfo{err: fmt.Errorf("invalid namespace (some elements can't be used when adding metric): %w", err)}
 	}
 
 	matchDefinition, groupPositions := pc.ctxManager.metricsDefinition.IsValid(ns)
-	matchFilters, _ := pc.metricsFilters.IsValid(ns)
-
 	if !matchDefinition {
-		return fmt.Errorf("couldn't match metric with plugin definition: %v", ns)
+		return &namespaceInfo{err: fmt.Errorf("couldn't match metric with plugin definition: %v", ns)}
 	}
 
+	matchFilters, _ := pc.metricsFilters.IsValid(ns)
 	if !matchFilters {
-		if logrus.IsLevelEnabled(logrus.TraceLevel) {
-			logF.WithField("ns", ns).Trace("couldn't match metrics with plugin filters")
-		}
-		return nil // don't throw error when metric is just filtered
+		return &namespaceInfo{filtered: true}
 	}
 
 	var mtNamespace []types.NamespaceElement
 	nsDefFormat, nsSeparator, err := metrictree.SplitNamespace(ns)
 	if err != nil {
-		return err
+		return &namespaceInfo{err: err}
 	}
 	nsDefFormat = nsDefFormat[1:]
 
@@ -131,7 +201,7 @@ func (pc *PluginContext) AddMetric(ns string, v interface{}, modifiers ...plugin
 		groupName := groupPositions[i]
 		mtNamespace = append(mtNamespace, types.NamespaceElement{
 			Name_:        groupName,
-			Value_:       pc.extractStaticValue(nsElem),
+			Value_:       plugin.UnescapeNsElement(pc.extractStaticValue(nsElem)),
 			Description_: pc.ctxManager.groupsDescription[groupName],
 		})
 
@@ -141,44 +211,119 @@ func (pc *PluginContext) AddMetric(ns string, v interface{}, modifiers ...plugin
 	}
 
 	nsDescKey := nsSeparator + strings.Join(nsDefFormat, nsSeparator)
-	mtMeta := pc.metricMeta(nsDescKey)
 
+	return &namespaceInfo{
+		mtNamespace: mtNamespace,
+		nsDescKey:   nsDescKey,
+		meta:        pc.metricMeta(nsDescKey),
+	}
+}
+
+// Apply modifiers, cardinality limits and deduplication to validated metric and store it in current session.
+// nsKey identifies namespace of a metric (when empty, it's calculated based on metric).
+func (pc *PluginContext) addMetric(mt *types.Metric, nsDescKey string, nsKey string, modifiers []plugin.MetricModifier) error {
+	pc.applyModifiers(mt, nsKey, modifiers)
+
+	return pc.commitMetric(mt, nsDescKey)
+}
+
+func (pc *PluginContext) applyModifiers(mt *types.Metric, nsKey string, modifiers []plugin.MetricModifier) {
+	// modifiers related to AddMetric
+	for _, m := range modifiers {
+		m.UpdateMetric(mt)
+	}
+
+	// modifiers list defined by AlwaysApply calls
+	pc.applyAlwaysModifiers(mt, nsKey)
+}
+
+// Store metric in current session (after applying cardinality limits and deduplication)
+func (pc *PluginContext) commitMetric(mt *types.Metric, nsDescKey string) error {
 	// if performance would suffer at some point in future proposed solution (indefinite chan) may be introduced
 	// https://github.com/solarwinds/snap-plugin-lib/pull/49/files#r390325795
 	// https://medium.com/capital-one-tech/building-an-unbounded-channel-in-go-789e175cd2cd
 	pc.sessionMtsMutex.Lock()
 	defer pc.sessionMtsMutex.Unlock()
 
-	mt := &types.Metric{
-		Namespace_:   mtNamespace,
-		Value_:       v,
-		Unit_:        mtMeta.unit,
-		Timestamp_:   time.Now(),
-		Description_: mtMeta.description,
+	return pc.commitMetricLocked(mt, nsDescKey)
+}
+
+// Store batch of metrics in current session taking session lock only once (see: workerContext)
+func (pc *PluginContext) commitMetrics(batch []pendingMetric) {
+	pc.sessionMtsMutex.Lock()
+	defer pc.sessionMtsMutex.Unlock()
+
+	for _, pm := range batch {
+		_ = pc.commitMetricLocked(pm.mt, pm.nsDescKey) // batching is disabled when errors have to be returned (DedupError)
 	}
+}
 
-	// modifiers related to AddMetric
-	for _, m := range modifiers {
-		m.UpdateMetric(mt)
+func (pc *PluginContext) commitMetricLocked(mt *types.Metric, nsDescKey string) error {
+	mt = pc.limiter.apply(mt, nsDescKey)
+	if mt == nil {
+		return nil // don't throw error when metric exceeds cardinality limits (reported in warnings)
+	}
+
+	if duplicated, err := pc.dedup.resolve(mt, pc.sessionMts); duplicated {
+		return err
+	}
+
+	pc.sessionMts = append(pc.sessionMts, mt)
+
+	return nil
+}
+
+func (pc *PluginContext) applyAlwaysModifiers(mt *types.Metric, nsKey string) {
+	pc.modifiersMutex.RLock()
+	defer pc.modifiersMutex.RUnlock()
+
+	if len(pc.modifiersTable) == 0 {
+		return
+	}
+
+	nsStr := ""
+	if nsKey == "" {
+		nsStr = types.Namespace(mt.Namespace_).EscapedString()
+		nsKey = "\x00" + nsStr // don't mix with namespaces passed to AddMetric (before unescaping and adding global prefix)
 	}
 
-	// modifiers list defined by AlwaysApply calls
 	for _, modElement := range pc.modifiersTable {
 		if !modElement.active {
 			continue
 		}
 
-		isValid, _ := modElement.validator.IsValid(mt.Namespace().String())
+		isValid := false
+		if cached, ok := modElement.matches.Load(nsKey); ok {
+			isValid = cached.(bool)
+		} else {
+			if nsStr == "" {
+				nsStr = types.Namespace(mt.Namespace_).EscapedString()
+			}
+
+			isValid, _ = modElement.validator.IsValid(nsStr)
+			modElement.matches.Store(nsKey, isValid)
+		}
+
 		if isValid {
 			for _, modifier := range modElement.modifiers {
 				modifier.UpdateMetric(mt)
 			}
 		}
 	}
+}
 
-	pc.sessionMts = append(pc.sessionMts, mt)
+func (pc *PluginContext) AddMetricNs(elements []string, v interface{}, modifiers ...plugin.MetricModifier) error {
+	return pc.AddMetric(namespaceFromElements(elements), v, modifiers...)
+}
 
-	return nil
+func namespaceFromElements(elements []string) string {
+	var sb strings.Builder
+	for _, el := range elements {
+		sb.WriteString(metrictree.DefaultNsSeparator)
+		sb.WriteString(plugin.NsElement(el))
+	}
+
+	return sb.String()
 }
 
 func (pc *PluginContext) ShouldProcess(ns string) bool {
@@ -208,8 +353,8 @@ func (pc *PluginContext) metricMeta(nsKey string) metricMetadata {
 }
 
 func (pc *PluginContext) AlwaysApply(namespaceSelector string, modifiers ...plugin.MetricModifier) (plugin.Dismisser, error) {
-	pc.sessionMtsMutex.Lock()
-	defer pc.sessionMtsMutex.Unlock()
+	pc.modifiersMutex.Lock()
+	defer pc.modifiersMutex.Unlock()
 
 	validator := metrictree.NewMetricFilter(metrictree.NewMetricDefinition())
 	err := validator.AddRule(namespaceSelector)
@@ -217,20 +362,28 @@ func (pc *PluginContext) AlwaysApply(namespaceSelector string, modifiers ...plug
 		return nil, fmt.Errorf("can't apply modifiers: %v", err)
 	}
 
+	matches, ok := pc.selectorMatches[namespaceSelector]
+	if !ok {
+		matches = &boundedCache{}
+		pc.selectorMatches[namespaceSelector] = matches
+	}
+
 	modifierMeta := &modifiersMetadata{
 		nsSelector: namespaceSelector,
 		modifiers:  modifiers,
 		validator:  validator,
+		matches:    matches,
 		active:     true,
 	}
 
 	pc.modifiersTable = append(pc.modifiersTable, modifierMeta)
+
 	return modifierMeta, nil
 }
 
 func (pc *PluginContext) DismissAllModifiers() {
-	pc.sessionMtsMutex.Lock()
-	defer pc.sessionMtsMutex.Unlock()
+	pc.modifiersMutex.Lock()
+	defer pc.modifiersMutex.Unlock()
 
 	for _, m := range pc.modifiersTable {
 		m.Dismiss()
@@ -253,7 +406,22 @@ func (pc *PluginContext) ClearCollectorSession() {
 	defer pc.sessionMtsMutex.Unlock()
 
 	pc.sessionMts = nil
+	pc.limiter.reset()
+	pc.dedup.reset()
+
+	pc.modifiersMutex.Lock()
+	defer pc.modifiersMutex.Unlock()
+
 	pc.modifiersTable = nil
+
+	pc.workers.open()
+
+	// new collect is started - cached results not used during the previous one are evicted
+	pc.nsCache.evictUnused()
+	pc.filterCache.evictUnused()
+	for _, matches := range pc.selectorMatches {
+		matches.evictUnused()
+	}
 }
 
 func (pc *PluginContext) Metrics(clear bool) []*types.Metric {
@@ -263,6 +431,8 @@ func (pc *PluginContext) Metrics(clear bool) []*types.Metric {
 	mts := pc.sessionMts
 	if clear {
 		pc.sessionMts = nil
+		pc.limiter.flush()
+		pc.dedup.reset()
 	}
 
 	globalPrefix := pc.ctxManager.globalPrefix
@@ -275,6 +445,23 @@ func (pc *PluginContext) Metrics(clear bool) []*types.Metric {
 	return mts
 }
 
+// Add warnings and update statistics when any of cardinality limits was exceeded or duplicates were detected since last call
+func (pc *PluginContext) reportSessionViolations() {
+	pc.sessionMtsMutex.Lock()
+	counters := pc.limiter.collectCounters()
+	duplicates := pc.dedup.collectDuplicates()
+	pc.sessionMtsMutex.Unlock()
+
+	if !counters.empty() {
+		pc.AddWarning(pc.limiter.warningMessage(counters))
+		pc.ctxManager.statsController.UpdateLimitsStat(pc.taskID, counters.seriesOverflow, counters.tagsOverflow, counters.tagValueOverflow)
+	}
+
+	if duplicates > 0 {
+		pc.AddWarning(pc.dedup.warningMessage(duplicates))
+	}
+}
+
 func (pc *PluginContext) RequestedMetrics() []string {
 	return pc.metricsFilters.ListRules()
 }
@@ -0,0 +1,214 @@
+//go:build bench
+// +build bench
+
+/*
+Benchmark test measures throughput of adding metrics in a single collect (100k metrics):
+- using string namespaces (AddMetric) with and without task filters (first and following collects)
+- using string namespaces with modifiers defined by AlwaysApply
+- using precompiled metric handles
+- adding metrics from several goroutines at the same time
+*/
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/common/stats"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+const (
+	benchHosts = 1000
+	benchCores = 100 // benchHosts * benchCores = number of metrics added during one collect
+
+	benchTaskID = "bench-task"
+)
+
+type benchCollector struct{}
+
+func (bc *benchCollector) PluginDefinition(def plugin.CollectorDefinition) error {
+	def.DefineMetric("/bench/[host]/cpu/[core]/usage", "%", true, "cpu usage")
+	def.DefineMetric("/bench/[host]/cpu/[core]/idle", "%", false, "cpu idle")
+	def.DefineGroup("host", "host name")
+	def.DefineGroup("core", "core number")
+
+	return nil
+}
+
+func (bc *benchCollector) Collect(_ plugin.CollectContext) error {
+	return nil
+}
+
+func newBenchContext(b *testing.B, filters []string) *PluginContext {
+	statsController, _ := stats.NewEmptyController()
+	cm := NewContextManager(context.Background(), types.NewCollector("bench-collector", "1.0.0", &benchCollector{}), statsController)
+
+	err := cm.LoadTask(context.Background(), benchTaskID, []byte("{}"), filters)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pc, _ := cm.contextMap.Load(benchTaskID)
+	return pc.(*PluginContext)
+}
+
+func benchNamespaces() []string {
+	var nss []string
+	for h := 0; h < benchHosts; h++ {
+		for c := 0; c < benchCores; c++ {
+			nss = append(nss, fmt.Sprintf("/bench/[host=host-%d]/cpu/[core=%d]/usage", h, c))
+		}
+	}
+
+	return nss
+}
+
+func reportThroughput(b *testing.B, metricsPerOp int) {
+	b.ReportMetric(float64(metricsPerOp)*float64(b.N)/b.Elapsed().Seconds(), "metrics/s")
+}
+
+func benchmarkAddMetric(b *testing.B, filters []string, alwaysApply []string) {
+	pc := newBenchContext(b, filters)
+	nss := benchNamespaces()
+
+	collect := func() {
+		pc.ClearCollectorSession()
+		for _, selector := range alwaysApply {
+			_, _ = pc.AlwaysApply(selector, plugin.MetricTag("bench", "true"))
+		}
+
+		for j, ns := range nss {
+			if err := pc.AddMetric(ns, j); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	collect() // the first collect validates namespaces, following ones use cached results
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		collect()
+	}
+
+	reportThroughput(b, len(nss))
+}
+
+func BenchmarkAddMetric_100k_FirstCollect(b *testing.B) {
+	nss := benchNamespaces()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		pc := newBenchContext(b, nil)
+		b.StartTimer()
+
+		for j, ns := range nss {
+			if err := pc.AddMetric(ns, j); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	reportThroughput(b, len(nss))
+}
+
+func BenchmarkAddMetric_100k(b *testing.B) {
+	benchmarkAddMetric(b, nil, nil)
+}
+
+func BenchmarkAddMetric_100k_Filtered(b *testing.B) {
+	benchmarkAddMetric(b, []string{"/bench/{host-[0-4].*}/cpu/*/usage"}, nil)
+}
+
+func BenchmarkAddMetric_100k_AlwaysApply(b *testing.B) {
+	benchmarkAddMetric(b, nil, []string{"/bench/*/cpu/*/usage", "/bench/host-1/**", "/bench/*/cpu/{[0-9]}/*"})
+}
+
+func BenchmarkAddMetric_100k_Parallel(b *testing.B) {
+	const workers = 8
+
+	pc := newBenchContext(b, nil)
+	nss := benchNamespaces()
+
+	collect := func() {
+		pc.ClearCollectorSession()
+
+		for w := 0; w < workers; w++ {
+			w := w
+			_ = pc.Go(func(ctx plugin.CollectContext) error {
+				for j := w; j < len(nss); j += workers {
+					_ = ctx.AddMetric(nss[j], j)
+				}
+				return nil
+			})
+		}
+		pc.waitForWorkers()
+	}
+
+	collect() // the first collect validates namespaces, following ones use cached results
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		collect()
+	}
+
+	reportThroughput(b, len(nss))
+}
+
+func BenchmarkMetricHandle_100k(b *testing.B) {
+	pc := newBenchContext(b, nil)
+
+	hosts := make([]string, benchHosts)
+	for h := range hosts {
+		hosts[h] = fmt.Sprintf("host-%d", h)
+	}
+	cores := make([]string, benchCores)
+	for c := range cores {
+		cores[c] = fmt.Sprintf("%d", c)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		pc.ClearCollectorSession()
+
+		usage := pc.Metric("/bench/[host]/cpu/[core]/usage")
+		for _, h := range hosts {
+			for _, c := range cores {
+				if err := usage.With(h, c).Add(1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	reportThroughput(b, benchHosts*benchCores)
+}
@@ -0,0 +1,87 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+// deduplicator detects metrics with the same namespace and tags added during one collect.
+// Not thread-safe - access is synchronized by PluginContext.sessionMtsMutex
+type deduplicator struct {
+	policy plugin.DedupPolicy
+
+	index      map[string]int // position of series in session metrics (key = series)
+	duplicates int            // number of duplicates since last report
+}
+
+func newDeduplicator(policy plugin.DedupPolicy) *deduplicator {
+	d := &deduplicator{policy: policy}
+	d.reset()
+
+	return d
+}
+
+func (d *deduplicator) enabled() bool {
+	return d.policy != plugin.DedupDisabled
+}
+
+func (d *deduplicator) reset() {
+	d.index = map[string]int{}
+}
+
+// resolve checks if metric duplicates one from session. When it does, session is updated according to the policy
+// and true is returned (metric shouldn't be appended). Otherwise, metric is registered as the one to be appended.
+func (d *deduplicator) resolve(mt *types.Metric, sessionMts []*types.Metric) (bool, error) {
+	if !d.enabled() {
+		return false, nil
+	}
+
+	key := seriesKey(mt)
+
+	pos, ok := d.index[key]
+	if !ok {
+		d.index[key] = len(sessionMts)
+		return false, nil
+	}
+
+	d.duplicates++
+
+	switch d.policy {
+	case plugin.DedupLastWins:
+		sessionMts[pos] = mt
+	case plugin.DedupError:
+		return true, fmt.Errorf("metric with the same namespace and tags has already been added: %s", mt.Namespace().String())
+	}
+
+	return true, nil
+}
+
+// collectDuplicates returns number of duplicates detected since last call and resets it
+func (d *deduplicator) collectDuplicates() int {
+	n := d.duplicates
+	d.duplicates = 0
+
+	return n
+}
+
+func (d *deduplicator) warningMessage(duplicates int) string {
+	return fmt.Sprintf("duplicated metrics detected (policy: %s): %d", d.policy, duplicates)
+}
@@ -0,0 +1,229 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/metrictree"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+// value used to validate dynamic elements of handle against metric definition
+const handleValidationValue = "x"
+
+type metricHandle struct {
+	pc  *PluginContext
+	err error
+
+	separator  string
+	elements   []types.NamespaceElement // namespace template (values of dynamic elements are filled when sample is added)
+	dynamicPos []int                    // positions of dynamic elements
+	nsDescKey  string
+	meta       metricMetadata
+	filtered   bool // true, when task defines filters which have to be checked for each sample
+}
+
+// metricSample is single-use - handle is cleared when sample is added
+type metricSample struct {
+	handle *metricHandle
+	values []string
+}
+
+func (pc *PluginContext) Metric(ns string) plugin.MetricHandle {
+	h, err := pc.compileMetric(ns)
+	if err != nil {
+		return &metricHandle{err: fmt.Errorf("can't create metric handle: %w", err)}
+	}
+
+	return h
+}
+
+func (pc *PluginContext) compileMetric(ns string) (*metricHandle, error) {
+	if len(ns) == 0 {
+		return nil, errors.New("empty namespace")
+	}
+
+	if pc.ctxManager.globalPrefix.enabled {
+		ns = fmt.Sprintf("%s%s%s", string(ns[0]), pc.ctxManager.globalPrefix.name, ns)
+	}
+
+	nsElems, nsSeparator, err := metrictree.SplitNamespace(ns)
+	if err != nil {
+		return nil, err
+	}
+	nsElems = nsElems[1:]
+
+	// namespace with values filled by placeholder is validated in the same way as in AddMetric
+	validationElems := make([]string, len(nsElems))
+	dynamicNames := make([]string, len(nsElems))
+	for i, nsElem := range nsElems {
+		validationElems[i] = nsElem
+
+		if strings.HasPrefix(nsElem, "[") && strings.HasSuffix(nsElem, "]") && !strings.Contains(nsElem, "=") {
+			dynamicNames[i] = nsElem[1 : len(nsElem)-1]
+			validationElems[i] = fmt.Sprintf("[%s=%s]", dynamicNames[i], handleValidationValue)
+		}
+	}
+	validationNs := nsSeparator + strings.Join(validationElems, nsSeparator)
+
+	if err := pc.ctxManager.metricsDefinition.IsUsableForAddition(validationNs, false); err != nil {
+		return nil, fmt.Errorf("invalid namespace (some elements can't be used when adding metric): %w", err)
+	}
+
+	matchDefinition, groupPositions := pc.ctxManager.metricsDefinition.IsValid(validationNs)
+	if !matchDefinition {
+		return nil, fmt.Errorf("couldn't match metric with plugin definition: %v", ns)
+	}
+
+	h := &metricHandle{
+		pc:        pc,
+		separator: nsSeparator,
+		filtered:  pc.metricsFilters.HasRules(),
+	}
+
+	nsDefFormat := make([]string, len(nsElems))
+	for i, nsElem := range nsElems {
+		groupName := groupPositions[i]
+		nsDefFormat[i] = nsElem
+
+		if dynamicNames[i] != "" {
+			h.dynamicPos = append(h.dynamicPos, i)
+			nsElem = ""
+		}
+
+		h.elements = append(h.elements, types.NamespaceElement{
+			Name_:        groupName,
+			Value_:       plugin.UnescapeNsElement(pc.extractStaticValue(nsElem)),
+			Description_: pc.ctxManager.groupsDescription[groupName],
+		})
+
+		if groupName != "" {
+			nsDefFormat[i] = fmt.Sprintf("[%s]", groupName)
+		}
+	}
+
+	h.nsDescKey = nsSeparator + strings.Join(nsDefFormat, nsSeparator)
+	h.meta = pc.metricMeta(h.nsDescKey)
+
+	return h, nil
+}
+
+func (h *metricHandle) With(groupValues ...string) plugin.MetricSample {
+	return &metricSample{
+		handle: h,
+		values: groupValues,
+	}
+}
+
+func (h *metricHandle) Add(v interface{}, modifiers ...plugin.MetricModifier) error {
+	return h.add(nil, v, modifiers)
+}
+
+func (h *metricHandle) Err() error {
+	return h.err
+}
+
+func (s *metricSample) Add(v interface{}, modifiers ...plugin.MetricModifier) error {
+	h, values := s.handle, s.values
+	if h == nil {
+		return errors.New("metric sample has already been added")
+	}
+
+	s.handle, s.values = nil, nil
+
+	return h.add(values, v, modifiers)
+}
+
+func (h *metricHandle) add(groupValues []string, v interface{}, modifiers []plugin.MetricModifier) error {
+	if h.err != nil {
+		return h.err
+	}
+
+	pc := h.pc
+
+	if pc.IsDone() {
+		return fmt.Errorf("task has been canceled")
+	}
+
+	if len(groupValues) != len(h.dynamicPos) {
+		return fmt.Errorf("invalid number of dynamic element values (expected %d, got %d)", len(h.dynamicPos), len(groupValues))
+	}
+
+	if !pc.isValidValueType(v) {
+		return fmt.Errorf("invalid value type (%T) for metric: %s", v, h.nsDescKey)
+	}
+
+	mtNamespace := make([]types.NamespaceElement, len(h.elements))
+	copy(mtNamespace, h.elements)
+
+	for i, pos := range h.dynamicPos {
+		if groupValues[i] == "" {
+			return fmt.Errorf("empty value of dynamic element [%s]", mtNamespace[pos].Name_)
+		}
+
+		// value is validated in escaped form, the same way as in AddMetricNs
+		if err := metrictree.ValidateGroupValue(plugin.NsElement(groupValues[i])); err != nil {
+			return fmt.Errorf("invalid value of dynamic element [%s]: %w", mtNamespace[pos].Name_, err)
+		}
+
+		mtNamespace[pos].Value_ = groupValues[i]
+	}
+
+	if h.filtered && !pc.matchFilters(h.separator, mtNamespace) {
+		return nil // don't throw error when metric is just filtered
+	}
+
+	mt := &types.Metric{
+		Namespace_:   mtNamespace,
+		Value_:       v,
+		Unit_:        h.meta.unit,
+		Timestamp_:   time.Now(),
+		Description_: h.meta.description,
+	}
+
+	return h.pc.addMetric(mt, h.nsDescKey, "", modifiers)
+}
+
+// Check if namespace is matching filters defined by task
+func (pc *PluginContext) matchFilters(separator string, ns []types.NamespaceElement) bool {
+	var sb strings.Builder
+
+	for _, el := range ns {
+		sb.WriteString(separator)
+
+		value := plugin.NsElement(el.Value_)
+		if el.Name_ != "" {
+			value = fmt.Sprintf("[%s=%s]", el.Name_, value)
+		}
+		sb.WriteString(value)
+	}
+
+	nsStr := sb.String()
+	if match, ok := pc.filterCache.Load(nsStr); ok {
+		return match.(bool)
+	}
+
+	match, _ := pc.metricsFilters.IsValid(nsStr)
+	pc.filterCache.Store(nsStr, match)
+
+	return match
+}
@@ -0,0 +1,256 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+type limitsCounters struct {
+	seriesOverflow   int
+	tagsOverflow     int
+	tagValueOverflow int
+}
+
+func (lc limitsCounters) empty() bool {
+	return lc.seriesOverflow == 0 && lc.tagsOverflow == 0 && lc.tagValueOverflow == 0
+}
+
+// cardinalityLimiter enforces plugin.CardinalityLimits for a single task.
+// Not thread-safe - access is synchronized by PluginContext.sessionMtsMutex
+type cardinalityLimiter struct {
+	limits plugin.CardinalityLimits
+
+	series      map[string]struct{}      // unique series added during current collect
+	otherSeries map[string]*types.Metric // series aggregating metrics above the limit in current chunk (key = namespace definition)
+	counters    limitsCounters
+}
+
+func newCardinalityLimiter(limits plugin.CardinalityLimits) *cardinalityLimiter {
+	cl := &cardinalityLimiter{limits: limits}
+	cl.reset()
+
+	return cl
+}
+
+func (cl *cardinalityLimiter) enabled() bool {
+	return cl.limits.MaxSeriesPerCollect != plugin.NoLimit ||
+		cl.limits.MaxTagsPerMetric != plugin.NoLimit ||
+		cl.limits.MaxTagValueLength != plugin.NoLimit
+}
+
+// reset should be called when new collect is started
+func (cl *cardinalityLimiter) reset() {
+	cl.series = map[string]struct{}{}
+	cl.flush()
+}
+
+// flush should be called when metrics are sent (ie. chunk of streaming collector), so that "other" series already
+// sent aren't modified. Unique series are still counted until reset.
+func (cl *cardinalityLimiter) flush() {
+	cl.otherSeries = map[string]*types.Metric{}
+}
+
+// apply validates metric against limits. Returns metric which should be added to the session
+// (might be different from input one when series is aggregated) or nil if nothing should be added.
+func (cl *cardinalityLimiter) apply(mt *types.Metric, nsDescKey string) *types.Metric {
+	if !cl.enabled() {
+		return mt
+	}
+
+	policy := cl.limits.Policy
+
+	if maxTags := cl.limits.MaxTagsPerMetric; maxTags != plugin.NoLimit && len(mt.Tags_) > maxTags {
+		cl.counters.tagsOverflow++
+
+		switch policy {
+		case plugin.OverflowDrop:
+			return nil
+		case plugin.OverflowAggregate:
+			keys := sortedTagKeys(mt.Tags_)
+			mt.RemoveTags(keys[maxTags:])
+		}
+	}
+
+	if maxLen := cl.limits.MaxTagValueLength; maxLen != plugin.NoLimit {
+		tooLong := false
+		for k, v := range mt.Tags_ {
+			if len(v) > maxLen {
+				tooLong = true
+				if policy == plugin.OverflowAggregate {
+					mt.Tags_[k] = truncateString(v, maxLen)
+				}
+			}
+		}
+
+		if tooLong {
+			cl.counters.tagValueOverflow++
+			if policy == plugin.OverflowDrop {
+				return nil
+			}
+		}
+	}
+
+	if maxSeries := cl.limits.MaxSeriesPerCollect; maxSeries != plugin.NoLimit {
+		key := seriesKey(mt)
+		if _, ok := cl.series[key]; ok {
+			return mt
+		}
+
+		if len(cl.series) < maxSeries {
+			cl.series[key] = struct{}{}
+			return mt
+		}
+
+		cl.counters.seriesOverflow++
+
+		switch policy {
+		case plugin.OverflowDrop:
+			return nil
+		case plugin.OverflowAggregate:
+			return cl.aggregate(mt, nsDescKey)
+		}
+	}
+
+	return mt
+}
+
+// aggregate folds metric into a series having all dynamic elements set to OverflowSeriesValue and no tags.
+// Only numeric values of sum metrics can be aggregated (summed), others are discarded.
+func (cl *cardinalityLimiter) aggregate(mt *types.Metric, nsDescKey string) *types.Metric {
+	if mt.Type_ != plugin.SumType {
+		return nil
+	}
+
+	v, ok := toFloat64(mt.Value_)
+	if !ok {
+		return nil
+	}
+
+	if other, ok := cl.otherSeries[nsDescKey]; ok {
+		other.Value_ = other.Value_.(float64) + v
+		return nil
+	}
+
+	otherNs := make([]types.NamespaceElement, len(mt.Namespace_))
+	copy(otherNs, mt.Namespace_)
+	for i := range otherNs {
+		if otherNs[i].IsDynamic() {
+			otherNs[i].Value_ = plugin.OverflowSeriesValue
+		}
+	}
+
+	other := &types.Metric{
+		Namespace_:   otherNs,
+		Value_:       v,
+		Unit_:        mt.Unit_,
+		Timestamp_:   mt.Timestamp_,
+		Description_: mt.Description_,
+		Type_:        mt.Type_,
+	}
+
+	cl.otherSeries[nsDescKey] = other
+	return other
+}
+
+// collectCounters returns counters gathered since last call and resets them
+func (cl *cardinalityLimiter) collectCounters() limitsCounters {
+	c := cl.counters
+	cl.counters = limitsCounters{}
+
+	return c
+}
+
+func (cl *cardinalityLimiter) warningMessage(c limitsCounters) string {
+	return fmt.Sprintf("cardinality limits exceeded (policy: %s): series=%d, tags per metric=%d, tag value length=%d",
+		cl.limits.Policy, c.seriesOverflow, c.tagsOverflow, c.tagValueOverflow)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// seriesKey identifies series by namespace and tags (ordered by keys)
+func seriesKey(mt *types.Metric) string {
+	var sb strings.Builder
+
+	sb.WriteString(types.Namespace(mt.Namespace_).EscapedString())
+
+	for _, k := range sortedTagKeys(mt.Tags_) {
+		sb.WriteString("\x00")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(mt.Tags_[k])
+	}
+
+	return sb.String()
+}
+
+// truncateString shortens s to at most maxLen bytes without splitting multi-byte characters
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+
+	return s[:maxLen]
+}
+
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int16:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint16:
+		return float64(val), true
+	case uint32:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,111 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+func limitsTestMetric(disk string, v interface{}, typ plugin.MetricType) *types.Metric {
+	return &types.Metric{
+		Namespace_: []types.NamespaceElement{
+			{Name_: "", Value_: "coll"},
+			{Name_: "disk", Value_: disk},
+			{Name_: "", Value_: "usage"},
+		},
+		Value_: v,
+		Type_:  typ,
+		Tags_:  map[string]string{},
+	}
+}
+
+func TestCardinalityLimiter(t *testing.T) {
+	Convey("Validate that too long tag values are truncated on character boundary", t, func() {
+		cl := newCardinalityLimiter(plugin.CardinalityLimits{
+			MaxSeriesPerCollect: plugin.NoLimit,
+			MaxTagsPerMetric:    plugin.NoLimit,
+			MaxTagValueLength:   5,
+			Policy:              plugin.OverflowAggregate,
+		})
+
+		mt := limitsTestMetric("sda", 1, plugin.GaugeType)
+		mt.Tags_["a"] = "zażółć"
+		mt.Tags_["b"] = "short"
+
+		mt = cl.apply(mt, "/coll/[disk]/usage")
+		So(mt, ShouldNotBeNil)
+		So(mt.Tags_["a"], ShouldEqual, "zaż")
+		So(utf8.ValidString(mt.Tags_["a"]), ShouldBeTrue)
+		So(mt.Tags_["b"], ShouldEqual, "short")
+		So(cl.collectCounters().tagValueOverflow, ShouldEqual, 1)
+	})
+
+	Convey("Validate that only sum metrics are aggregated into other series", t, func() {
+		cl := newCardinalityLimiter(plugin.CardinalityLimits{
+			MaxSeriesPerCollect: 1,
+			MaxTagsPerMetric:    plugin.NoLimit,
+			MaxTagValueLength:   plugin.NoLimit,
+			Policy:              plugin.OverflowAggregate,
+		})
+
+		So(cl.apply(limitsTestMetric("sda", 1, plugin.SumType), "/coll/[disk]/usage"), ShouldNotBeNil)
+		So(cl.apply(limitsTestMetric("sdb", 2, plugin.GaugeType), "/coll/[disk]/usage"), ShouldBeNil)
+
+		other := cl.apply(limitsTestMetric("sdc", 3, plugin.SumType), "/coll/[disk]/usage")
+		So(other, ShouldNotBeNil)
+		So(other.Namespace_[1].Value_, ShouldEqual, plugin.OverflowSeriesValue)
+		So(cl.apply(limitsTestMetric("sdd", 4, plugin.SumType), "/coll/[disk]/usage"), ShouldBeNil)
+		So(other.Value_, ShouldEqual, 7)
+		So(cl.collectCounters().seriesOverflow, ShouldEqual, 3)
+	})
+
+	Convey("Validate that series limit applies to whole collect, not a single chunk", t, func() {
+		cl := newCardinalityLimiter(plugin.CardinalityLimits{
+			MaxSeriesPerCollect: 2,
+			MaxTagsPerMetric:    plugin.NoLimit,
+			MaxTagValueLength:   plugin.NoLimit,
+			Policy:              plugin.OverflowAggregate,
+		})
+
+		So(cl.apply(limitsTestMetric("sda", 1, plugin.SumType), "/coll/[disk]/usage"), ShouldNotBeNil)
+		So(cl.apply(limitsTestMetric("sdb", 2, plugin.SumType), "/coll/[disk]/usage"), ShouldNotBeNil)
+		other := cl.apply(limitsTestMetric("sdc", 3, plugin.SumType), "/coll/[disk]/usage")
+		So(other, ShouldNotBeNil)
+		So(other.Namespace_[1].Value_, ShouldEqual, plugin.OverflowSeriesValue)
+
+		cl.flush() // chunk sent
+
+		So(cl.apply(limitsTestMetric("sda", 1, plugin.SumType), "/coll/[disk]/usage"), ShouldNotBeNil)
+		newOther := cl.apply(limitsTestMetric("sdd", 4, plugin.SumType), "/coll/[disk]/usage")
+		So(newOther, ShouldNotBeNil)
+		So(newOther.Value_, ShouldEqual, 4)
+		So(other.Value_, ShouldEqual, 3)
+
+		cl.reset() // new collect
+
+		So(cl.apply(limitsTestMetric("sdd", 4, plugin.SumType), "/coll/[disk]/usage"), ShouldNotBeNil)
+		So(cl.apply(limitsTestMetric("sde", 5, plugin.SumType), "/coll/[disk]/usage").Namespace_[1].Value_, ShouldEqual, "sde")
+	})
+}
@@ -36,8 +36,10 @@ import (
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/common/stats"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/metrictree"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/tracing"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var moduleFields = logrus.Fields{"layer": "lib", "module": "collector-proxy"}
@@ -52,10 +54,10 @@ const (
 )
 
 type Collector interface {
-	RequestCollect(id string) <-chan types.CollectChunk
-	LoadTask(id string, config []byte, selectors []string) error
-	UnloadTask(id string) error
-	CustomInfo(id string) ([]byte, error)
+	RequestCollect(ctx context.Context, id string) <-chan types.CollectChunk
+	LoadTask(ctx context.Context, id string, config []byte, selectors []string) error
+	UnloadTask(ctx context.Context, id string) error
+	CustomInfo(ctx context.Context, id string) ([]byte, error)
 }
 
 type metricMetadata struct {
@@ -85,6 +87,14 @@ type ContextManager struct {
 	statsController stats.Controller // reference to statistics controller
 
 	globalPrefix globalPrefix
+
+	cardinalityLimits  plugin.CardinalityLimits // limits applied to each task
+	dedupPolicy        plugin.DedupPolicy       // handling of metrics duplicated within one collect
+	collectConcurrency int                      // maximum number of goroutines started by CollectContext.Go
+
+	lastCollectMutex sync.RWMutex // mutex associated with lastCollectTime and lastCollectErr
+	lastCollectTime  time.Time
+	lastCollectErr   error
 }
 
 func NewContextManager(ctx context.Context, collector types.Collector, statsController stats.Controller) *ContextManager {
@@ -115,16 +125,22 @@ func (cm *ContextManager) Context() context.Context {
 	return cm.ctx
 }
 
-func (cm *ContextManager) RequestCollect(id string) <-chan types.CollectChunk {
+func (cm *ContextManager) RequestCollect(ctx context.Context, id string) <-chan types.CollectChunk {
 	chunkCh := make(chan types.CollectChunk)
-	go cm.requestCollect(id, chunkCh)
+	go cm.requestCollect(ctx, id, chunkCh)
 	return chunkCh
 }
 
-func (cm *ContextManager) requestCollect(id string, chunkCh chan<- types.CollectChunk) {
+func (cm *ContextManager) requestCollect(ctx context.Context, id string, chunkCh chan<- types.CollectChunk) {
+	_, span := tracing.StartSpan(ctx, "Collect", id)
+
+	var collectErr error
+	defer func() { tracing.EndSpan(span, collectErr) }()
+
 	if !cm.AcquireTask(id) {
+		collectErr = fmt.Errorf("can't process collect request, other request for the same id (%s) is in progress", id)
 		chunkCh <- types.CollectChunk{
-			Err: fmt.Errorf("can't process collect request, other request for the same id (%s) is in progress", id),
+			Err: collectErr,
 		}
 		close(chunkCh)
 		return
@@ -132,8 +148,9 @@ func (cm *ContextManager) requestCollect(id string, chunkCh chan<- types.Collect
 
 	contextIf, ok := cm.contextMap.Load(id)
 	if !ok {
+		collectErr = fmt.Errorf("can't find a context for a given id: %s", id)
 		chunkCh <- types.CollectChunk{
-			Err: fmt.Errorf("can't find a context for a given id: %s", id),
+			Err: collectErr,
 		}
 		close(chunkCh)
 		return
@@ -142,21 +159,24 @@ func (cm *ContextManager) requestCollect(id string, chunkCh chan<- types.Collect
 	pContext := contextIf.(*PluginContext)
 
 	pContext.AttachContext(cm.TaskContext(id))
+	pContext.AttachSpan(span)
 	pContext.ClearCollectorSession()
 	pContext.ResetWarnings()
 
 	switch cm.collector.Type() {
 	case types.PluginTypeCollector:
-		cm.collect(id, pContext, chunkCh)
+		collectErr = cm.collect(id, pContext, span, chunkCh)
 	case types.PluginTypeStreamingCollector:
-		cm.streamingCollect(id, pContext, chunkCh)
+		collectErr = cm.streamingCollect(id, pContext, span, chunkCh)
 	}
+	cm.setLastCollectStatus(collectErr)
 
+	pContext.AttachSpan(nil)
 	cm.MarkTaskAsCompleted(id)
 	pContext.ReleaseContext()
 }
 
-func (cm *ContextManager) collect(id string, context *PluginContext, chunkCh chan<- types.CollectChunk) {
+func (cm *ContextManager) collect(id string, context *PluginContext, span trace.Span, chunkCh chan<- types.CollectChunk) error {
 	logF := cm.logger()
 	taskCtx := cm.TaskContext(id)
 
@@ -173,14 +193,18 @@ func (cm *ContextManager) collect(id string, context *PluginContext, chunkCh cha
 				err = fmt.Errorf("user-defined function has ended with panic: %v", r)
 			}
 
+			context.waitForWorkers()
 			cm.ReleaseTask(id)
 		}()
 
 		startTime := time.Now()
 		err = cm.collector.Collect(context) // calling to user defined code
+		context.waitForWorkers()
 		endTime := time.Now()
 
 		if !context.Context.IsDone() {
+			context.reportSessionViolations()
+
 			mts = context.Metrics(false)
 			warnings = context.Warnings(false)
 
@@ -204,6 +228,10 @@ func (cm *ContextManager) collect(id string, context *PluginContext, chunkCh cha
 
 	<-taskCtx.Done()
 
+	span.SetAttributes(
+		tracing.MetricsCountKey.Int(len(mts)),
+		tracing.WarningsCountKey.Int(len(warnings)))
+
 	chunkCh <- types.CollectChunk{
 		Metrics:  mts,
 		Warnings: warnings,
@@ -211,9 +239,10 @@ func (cm *ContextManager) collect(id string, context *PluginContext, chunkCh cha
 	}
 
 	close(chunkCh)
+	return err
 }
 
-func (cm *ContextManager) streamingCollect(id string, context *PluginContext, chunkCh chan<- types.CollectChunk) {
+func (cm *ContextManager) streamingCollect(id string, context *PluginContext, span trace.Span, chunkCh chan<- types.CollectChunk) error {
 	logF := cm.logger()
 	errCh := make(chan error, 1)
 
@@ -231,6 +260,7 @@ func (cm *ContextManager) streamingCollect(id string, context *PluginContext, ch
 				err = fmt.Errorf("user-defined function has ended with panic: %v", r)
 			}
 
+			context.waitForWorkers()
 			errCh <- err
 			cm.ReleaseTask(id)
 		}()
@@ -247,27 +277,33 @@ func (cm *ContextManager) streamingCollect(id string, context *PluginContext, ch
 			default:
 			}
 
-			cm.handleChunk(id, err, context, chunkCh, startTime)
+			cm.handleChunk(id, err, context, span, chunkCh, startTime)
 			close(chunkCh)
-			return
+			return err
 		case <-time.After(streamingCheckInterval):
 			select {
 			case err = <-errCh:
 			default:
 			}
 
-			cm.handleChunk(id, err, context, chunkCh, startTime)
+			cm.handleChunk(id, err, context, span, chunkCh, startTime)
 		}
 	}
 }
 
-func (cm *ContextManager) handleChunk(id string, err error, context *PluginContext, chunkCh chan<- types.CollectChunk, startTime time.Time) {
+func (cm *ContextManager) handleChunk(id string, err error, context *PluginContext, span trace.Span, chunkCh chan<- types.CollectChunk, startTime time.Time) {
+	context.reportSessionViolations()
+
 	mts := context.Metrics(true)
 	warnings := context.Warnings(true)
 
 	if len(mts) > 0 || len(warnings) > 0 || err != nil {
 		lastUpdate := time.Now()
 
+		span.AddEvent("chunk", trace.WithAttributes(
+			tracing.MetricsCountKey.Int(len(mts)),
+			tracing.WarningsCountKey.Int(len(warnings))))
+
 		chunkCh <- types.CollectChunk{
 			Metrics:  mts,
 			Warnings: warnings,
@@ -278,7 +314,10 @@ func (cm *ContextManager) handleChunk(id string, err error, context *PluginConte
 	}
 }
 
-func (cm *ContextManager) LoadTask(id string, rawConfig []byte, mtsFilter []string) error {
+func (cm *ContextManager) LoadTask(ctx context.Context, id string, rawConfig []byte, mtsFilter []string) (err error) {
+	_, span := tracing.StartSpan(ctx, "Load", id)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if !cm.AcquireTask(id) {
 		return fmt.Errorf("can't process load request, other request for the same id (%s) is in progress", id)
 	}
@@ -313,7 +352,7 @@ func (cm *ContextManager) LoadTask(id string, rawConfig []byte, mtsFilter []stri
 	}
 
 	if loadable, ok := cm.collector.Unwrap().(plugin.LoadableCollector); ok {
-		err := loadable.Load(newCtx)
+		err := loadable.Load(commonProxy.WithSpan(newCtx, span))
 		if err != nil {
 			return fmt.Errorf("can't load task due to errors returned from user-defined function: %s", err)
 		}
@@ -325,7 +364,10 @@ func (cm *ContextManager) LoadTask(id string, rawConfig []byte, mtsFilter []stri
 	return nil
 }
 
-func (cm *ContextManager) UnloadTask(id string) error {
+func (cm *ContextManager) UnloadTask(ctx context.Context, id string) (err error) {
+	_, span := tracing.StartSpan(ctx, "Unload", id)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	logF := cm.logger()
 
 	// Unload may be called when Collect (especially stream) is in progress. If so, try to cancel it.
@@ -355,7 +397,7 @@ func (cm *ContextManager) UnloadTask(id string) error {
 
 	pluginCtx := contextI.(*PluginContext)
 	if unloadable, ok := cm.collector.Unwrap().(plugin.UnloadableCollector); ok {
-		err := unloadable.Unload(pluginCtx)
+		err := unloadable.Unload(commonProxy.WithSpan(pluginCtx, span))
 		if err != nil {
 			return fmt.Errorf("error occured when trying to unload a task (%s): %v", id, err)
 		}
@@ -367,7 +409,32 @@ func (cm *ContextManager) UnloadTask(id string) error {
 	return nil
 }
 
-func (cm *ContextManager) CustomInfo(id string) ([]byte, error) {
+// TaskIDs returns identifiers of all loaded tasks
+func (cm *ContextManager) TaskIDs() []string {
+	var ids []string
+
+	cm.contextMap.Range(func(k, _ interface{}) bool {
+		ids = append(ids, k.(string))
+		return true
+	})
+
+	return ids
+}
+
+// WaitForActiveTasks blocks until processing of all requests is completed (or ctx is done).
+// Streaming collect lasts until task is unloaded, so there is nothing to wait for.
+func (cm *ContextManager) WaitForActiveTasks(ctx context.Context) error {
+	if cm.collector.Type() == types.PluginTypeStreamingCollector {
+		return nil
+	}
+
+	return cm.ContextManager.WaitForActiveTasks(ctx)
+}
+
+func (cm *ContextManager) CustomInfo(ctx context.Context, id string) (_ []byte, err error) {
+	_, span := tracing.StartSpan(ctx, "Info", id)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	// Do not call cm.AcquireTask as above methods. CustomInfo is read-only
 
 	contextI, ok := cm.contextMap.Load(id)
@@ -377,7 +444,7 @@ func (cm *ContextManager) CustomInfo(id string) ([]byte, error) {
 	pluginCtx := contextI.(*PluginContext)
 
 	if collectorWithCustomInfo, ok := cm.collector.Unwrap().(plugin.CustomizableInfoCollector); ok {
-		infoObj := collectorWithCustomInfo.CustomInfo(pluginCtx)
+		infoObj := collectorWithCustomInfo.CustomInfo(commonProxy.WithSpan(pluginCtx, span))
 
 		infoJSON, err := json.Marshal(infoObj)
 		if err != nil {
@@ -447,6 +514,41 @@ func (cm *ContextManager) SetGlobalMetricPrefix(prefix string, removePrefixFromO
 	return nil
 }
 
+func (cm *ContextManager) DefineCardinalityLimits(limits plugin.CardinalityLimits) error {
+	if limits.MaxSeriesPerCollect < 0 || limits.MaxTagsPerMetric < 0 || limits.MaxTagValueLength < 0 {
+		return fmt.Errorf("invalid cardinality limits")
+	}
+
+	switch limits.Policy {
+	case plugin.OverflowDrop, plugin.OverflowAggregate, plugin.OverflowWarn:
+	default:
+		return fmt.Errorf("invalid overflow policy: %v", limits.Policy)
+	}
+
+	cm.cardinalityLimits = limits
+	return nil
+}
+
+func (cm *ContextManager) DefineDeduplication(policy plugin.DedupPolicy) error {
+	switch policy {
+	case plugin.DedupDisabled, plugin.DedupLastWins, plugin.DedupFirstWins, plugin.DedupError:
+	default:
+		return fmt.Errorf("invalid deduplication policy: %v", policy)
+	}
+
+	cm.dedupPolicy = policy
+	return nil
+}
+
+func (cm *ContextManager) DefineCollectConcurrency(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("invalid collect concurrency limit: %d", limit)
+	}
+
+	cm.collectConcurrency = limit
+	return nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 func (cm *ContextManager) RequestPluginDefinition() {
@@ -457,9 +559,35 @@ func (cm *ContextManager) RequestPluginDefinition() {
 		if err != nil {
 			logF.WithFields(moduleFields).WithError(err).Errorf("Error occurred during plugin definition")
 		}
+		cm.SetDefinitionError(err)
 	}
 }
 
+// HealthCheck calls health check implemented by plugin (if any)
+func (cm *ContextManager) HealthCheck(ctx context.Context) error {
+	if checkable, ok := cm.collector.Unwrap().(plugin.HealthCheckable); ok {
+		return checkable.HealthCheck(ctx)
+	}
+
+	return nil
+}
+
+// LastCollectStatus returns completion time and result of the most recent collect (zero time if there wasn't any)
+func (cm *ContextManager) LastCollectStatus() (time.Time, error) {
+	cm.lastCollectMutex.RLock()
+	defer cm.lastCollectMutex.RUnlock()
+
+	return cm.lastCollectTime, cm.lastCollectErr
+}
+
+func (cm *ContextManager) setLastCollectStatus(err error) {
+	cm.lastCollectMutex.Lock()
+	defer cm.lastCollectMutex.Unlock()
+
+	cm.lastCollectTime = time.Now()
+	cm.lastCollectErr = err
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 func (cm *ContextManager) ListDefaultMetrics() []string {
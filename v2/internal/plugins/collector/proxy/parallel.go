@@ -0,0 +1,194 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+// number of metrics gathered by a goroutine before they are stored in session
+const workerBatchSize = 256
+
+// workerGroup tracks goroutines started by CollectContext.Go and limits number of them running at the same time
+type workerGroup struct {
+	wg  sync.WaitGroup
+	sem chan struct{} // nil when there is no limit
+
+	mu        sync.Mutex // mutex associated with accepting (synchronizes wg.Add with wg.Wait)
+	accepting bool       // true, when collect is in progress (goroutines can be started)
+}
+
+func newWorkerGroup(limit int) *workerGroup {
+	wg := &workerGroup{}
+	if limit != plugin.NoLimit {
+		wg.sem = make(chan struct{}, limit)
+	}
+
+	return wg
+}
+
+// open allows starting goroutines (should be called when collect is started)
+func (w *workerGroup) open() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.accepting = true
+}
+
+// add registers new goroutine, unless collect has been completed
+func (w *workerGroup) add() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.accepting {
+		return false
+	}
+
+	w.wg.Add(1)
+	return true
+}
+
+// wait blocks until all goroutines have finished. New goroutines can't be started until the next call to open.
+func (w *workerGroup) wait() {
+	w.mu.Lock()
+	w.accepting = false
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
+
+func (pc *PluginContext) Go(f func(ctx plugin.CollectContext) error) error {
+	if pc.IsDone() {
+		return errors.New("task has been canceled, goroutine won't be started")
+	}
+
+	if !pc.workers.add() {
+		return errors.New("collect has been completed, goroutine won't be started")
+	}
+
+	if pc.workers.sem != nil {
+		select {
+		case pc.workers.sem <- struct{}{}:
+		case <-pc.Done():
+			pc.workers.wg.Done()
+			return errors.New("task has been canceled, goroutine won't be started")
+		}
+	}
+
+	logF := pc.ctxManager.logger()
+	wc := &workerContext{PluginContext: pc, done: pc.Done()}
+
+	go func() {
+		defer func() {
+			// catch panics (since it's running in it's own goroutine)
+			if r := recover(); r != nil {
+				logF.WithError(fmt.Errorf("%v", r)).Error("user-defined goroutine has ended with panic")
+				logF.WithField("block", "recover").Trace(string(debug.Stack()))
+				pc.AddWarning(fmt.Sprintf("user-defined goroutine has ended with panic: %v", r))
+			}
+
+			wc.flush()
+
+			if pc.workers.sem != nil {
+				<-pc.workers.sem
+			}
+
+			pc.workers.wg.Done()
+		}()
+
+		if err := f(wc); err != nil {
+			pc.AddWarning(fmt.Sprintf("user-defined goroutine has ended with error: %v", err))
+		}
+	}()
+
+	return nil
+}
+
+// Wait for all goroutines started with Go (called by the library when Collect has returned)
+func (pc *PluginContext) waitForWorkers() {
+	pc.workers.wait()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// workerContext is passed to goroutines started by Go. Metrics are gathered in a local batch and stored in session
+// taking session lock once per batch, so goroutines adding metrics in parallel don't contend on every AddMetric.
+type workerContext struct {
+	*PluginContext
+	done  <-chan struct{} // task cancellation (checked without locking task context)
+	batch []pendingMetric
+}
+
+// metric waiting to be stored in session
+type pendingMetric struct {
+	mt        *types.Metric
+	nsDescKey string
+}
+
+func (wc *workerContext) AddMetric(ns string, v interface{}, modifiers ...plugin.MetricModifier) error {
+	select {
+	case <-wc.done:
+		return fmt.Errorf("task has been canceled")
+	default:
+	}
+
+	mt, nsDescKey, err := wc.prepareMetric(ns, v, modifiers)
+	if mt == nil {
+		return err
+	}
+
+	if !wc.batchingEnabled() {
+		return wc.commitMetric(mt, nsDescKey)
+	}
+
+	wc.batch = append(wc.batch, pendingMetric{mt: mt, nsDescKey: nsDescKey})
+	if len(wc.batch) >= workerBatchSize {
+		wc.flush()
+	}
+
+	return nil
+}
+
+// Goroutine can't start other ones - it would block (or deadlock) when concurrency limit is reached
+func (wc *workerContext) Go(_ func(ctx plugin.CollectContext) error) error {
+	return errors.New("goroutine can't be started from goroutine started by Go")
+}
+
+func (wc *workerContext) AddMetricNs(elements []string, v interface{}, modifiers ...plugin.MetricModifier) error {
+	return wc.AddMetric(namespaceFromElements(elements), v, modifiers...)
+}
+
+// Metrics are sent immediately by streaming collector and errors of DedupError policy have to be returned by AddMetric,
+// so batching is used only when neither is the case.
+func (wc *workerContext) batchingEnabled() bool {
+	return wc.ctxManager.collector.Type() == types.PluginTypeCollector && wc.dedup.policy != plugin.DedupError
+}
+
+func (wc *workerContext) flush() {
+	if len(wc.batch) == 0 {
+		return
+	}
+
+	wc.commitMetrics(wc.batch)
+	wc.batch = wc.batch[:0]
+}
@@ -27,6 +27,8 @@ import (
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/simpleconfig"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -48,6 +50,7 @@ type Context struct {
 
 	ctx      context.Context
 	cancelFn context.CancelFunc
+	span     trace.Span // span of current operation (Load, Collect etc.), available to user code via RawContext()
 	ctxMu    sync.RWMutex
 }
 
@@ -176,6 +179,10 @@ func (c *Context) RawContext() context.Context {
 	c.ctxMu.RLock()
 	defer c.ctxMu.RUnlock()
 
+	if c.span != nil {
+		return trace.ContextWithSpan(c.ctx, c.span)
+	}
+
 	return c.ctx
 }
 
@@ -206,6 +213,32 @@ func (c *Context) AttachContext(parentCtx context.Context) {
 	c.ctx, c.cancelFn = context.WithCancel(parentCtx)
 }
 
+// AttachSpan sets span of current operation, so user code can create child spans
+func (c *Context) AttachSpan(span trace.Span) {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+
+	c.span = span
+}
+
+// WithSpan returns task context which exposes span of current operation via RawContext(), without modifying
+// context shared by task. Used by read-only operations (ie. Info) which are not acquiring task.
+func WithSpan(ctx plugin.Context, span trace.Span) plugin.Context {
+	return &spanContext{
+		Context: ctx,
+		rawCtx:  trace.ContextWithSpan(ctx.RawContext(), span),
+	}
+}
+
+type spanContext struct {
+	plugin.Context
+	rawCtx context.Context
+}
+
+func (sc *spanContext) RawContext() context.Context {
+	return sc.rawCtx
+}
+
 func (c *Context) ReleaseContext() {
 	c.ctxMu.Lock()
 	defer c.ctxMu.Unlock()
@@ -20,10 +20,13 @@
 package proxy
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type basicConfig struct {
@@ -279,3 +282,23 @@ func TestContextAPI_Storage(t *testing.T) {
 		})
 	})
 }
+
+func TestWithSpan(t *testing.T) {
+	Convey("Validate that span of read-only operation doesn't replace span attached to task context", t, func() {
+		tracer := sdktrace.NewTracerProvider().Tracer("test")
+		_, collectSpan := tracer.Start(context.Background(), "Collect")
+		_, infoSpan := tracer.Start(context.Background(), "Info")
+
+		ctx, err := NewContext([]byte("{}"))
+		So(err, ShouldBeNil)
+		ctx.AttachSpan(collectSpan)
+
+		// Act
+		infoCtx := WithSpan(ctx, infoSpan)
+
+		// Assert
+		So(trace.SpanFromContext(infoCtx.RawContext()), ShouldEqual, infoSpan)
+		So(trace.SpanFromContext(ctx.RawContext()), ShouldEqual, collectSpan)
+		So(infoCtx.ConfigKeys(), ShouldResemble, ctx.ConfigKeys())
+	})
+}
@@ -33,18 +33,22 @@ type contextHolder struct {
 type ContextManager struct {
 	activeTasksMutex sync.RWMutex             // mutex associated with activeTasks
 	activeTasks      map[string]contextHolder // map of active tasks (tasks for which Collect RPC request is progressing)
+	taskCompletedCh  chan struct{}            // closed (and replaced) each time processing of a task is completed
 
 	TasksLimit     int
 	InstancesLimit int
 
 	ExampleConfig yaml.Node // example config
+
+	definitionErr error // error returned by PluginDefinition
 }
 
 func NewContextManager() *ContextManager {
 	return &ContextManager{
-		activeTasks:    map[string]contextHolder{},
-		TasksLimit:     plugin.NoLimit,
-		InstancesLimit: plugin.NoLimit,
+		activeTasks:     map[string]contextHolder{},
+		taskCompletedCh: make(chan struct{}),
+		TasksLimit:      plugin.NoLimit,
+		InstancesLimit:  plugin.NoLimit,
 	}
 }
 
@@ -71,6 +75,7 @@ func (cm *ContextManager) MarkTaskAsCompleted(id string) {
 
 	if _, ok := cm.activeTasks[id]; ok {
 		cm.activeTasks[id].cancelFn()
+		cm.notifyTaskCompleted()
 	}
 
 	delete(cm.activeTasks, id)
@@ -82,9 +87,16 @@ func (cm *ContextManager) ReleaseTask(id string) {
 
 	if aTask, ok := cm.activeTasks[id]; ok {
 		aTask.cancelFn()
+		cm.notifyTaskCompleted()
 	}
 }
 
+// notifyTaskCompleted wakes up all goroutines waiting in WaitForActiveTasks (activeTasksMutex must be locked)
+func (cm *ContextManager) notifyTaskCompleted() {
+	close(cm.taskCompletedCh)
+	cm.taskCompletedCh = make(chan struct{})
+}
+
 func (cm *ContextManager) TaskContext(id string) context.Context {
 	cm.activeTasksMutex.Lock()
 	defer cm.activeTasksMutex.Unlock()
@@ -92,6 +104,45 @@ func (cm *ContextManager) TaskContext(id string) context.Context {
 	return cm.activeTasks[id].ctx
 }
 
+// WaitForActiveTasks blocks until processing of all requests is completed (or ctx is done)
+func (cm *ContextManager) WaitForActiveTasks(ctx context.Context) error {
+	for {
+		active, completedCh := cm.activeTasksState()
+		if !active {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-completedCh:
+		}
+	}
+}
+
+// activeTasksState returns whether any task is active and channel closed when the next task is completed
+func (cm *ContextManager) activeTasksState() (bool, <-chan struct{}) {
+	cm.activeTasksMutex.RLock()
+	defer cm.activeTasksMutex.RUnlock()
+
+	for _, h := range cm.activeTasks {
+		if h.ctx.Err() == nil {
+			return true, cm.taskCompletedCh
+		}
+	}
+
+	return false, nil
+}
+
+// DefinitionError returns error which occurred when plugin was being defined (nil if definition succeeded)
+func (cm *ContextManager) DefinitionError() error {
+	return cm.definitionErr
+}
+
+func (cm *ContextManager) SetDefinitionError(err error) {
+	cm.definitionErr = err
+}
+
 func (cm *ContextManager) DefineTasksPerInstanceLimit(limit int) error {
 	if limit < -1 {
 		return fmt.Errorf("invalid tasks limit")
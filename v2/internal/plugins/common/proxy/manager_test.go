@@ -0,0 +1,71 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContextManager_WaitForActiveTasks(t *testing.T) {
+	Convey("Validate that waiting is completed when all active tasks are completed", t, func() {
+		cm := NewContextManager()
+		So(cm.WaitForActiveTasks(context.Background()), ShouldBeNil)
+
+		So(cm.AcquireTask("task-1"), ShouldBeTrue)
+		So(cm.AcquireTask("task-2"), ShouldBeTrue)
+
+		waitCh := make(chan error)
+		go func() {
+			waitCh <- cm.WaitForActiveTasks(context.Background())
+		}()
+
+		cm.ReleaseTask("task-1")
+
+		select {
+		case <-waitCh:
+			t.Fatal("waiting shouldn't be completed while task-2 is active")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		cm.MarkTaskAsCompleted("task-2")
+
+		select {
+		case err := <-waitCh:
+			So(err, ShouldBeNil)
+		case <-time.After(time.Second):
+			t.Fatal("waiting should be completed when all tasks are completed")
+		}
+	})
+
+	Convey("Validate that waiting is interrupted when context is done", t, func() {
+		cm := NewContextManager()
+		So(cm.AcquireTask("task-1"), ShouldBeTrue)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		So(errors.Is(cm.WaitForActiveTasks(ctx), context.DeadlineExceeded), ShouldBeTrue)
+	})
+}
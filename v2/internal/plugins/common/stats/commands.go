@@ -78,3 +78,17 @@ type streamTaskStat struct {
 func (ts *streamTaskStat) ApplyStat() {
 	ts.sm.applyStreamStat(ts.taskID, ts.metricsCount, ts.startTime, ts.lastUpdate)
 }
+
+///////////////////////////////////////////////////////////////////////////////
+
+type limitsTaskStat struct {
+	sm               *StatisticsController
+	taskID           string
+	seriesOverflow   int
+	tagsOverflow     int
+	tagValueOverflow int
+}
+
+func (ts *limitsTaskStat) ApplyStat() {
+	ts.sm.applyLimitsStat(ts.taskID, ts.seriesOverflow, ts.tagsOverflow, ts.tagValueOverflow)
+}
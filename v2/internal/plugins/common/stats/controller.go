@@ -47,6 +47,7 @@ type Controller interface {
 	UpdateUnloadStat(taskID string)
 	UpdateExecutionStat(taskID string, metricsCount int, success bool, startTime, endTime time.Time)
 	UpdateStreamingStat(taskID string, metricsCount int, startTime, lastUpdate time.Time)
+	UpdateLimitsStat(taskID string, seriesOverflow, tagsOverflow, tagValueOverflow int)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -182,6 +183,16 @@ func (sc *StatisticsController) UpdateStreamingStat(taskID string, metricsCount
 	}
 }
 
+func (sc *StatisticsController) UpdateLimitsStat(taskID string, seriesOverflow, tagsOverflow, tagValueOverflow int) {
+	sc.incomingStatsCh <- &limitsTaskStat{
+		sm:               sc,
+		taskID:           taskID,
+		seriesOverflow:   seriesOverflow,
+		tagsOverflow:     tagsOverflow,
+		tagValueOverflow: tagValueOverflow,
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 func (sc *StatisticsController) applyLoadStat(taskID string, config string, filters []string) {
@@ -292,6 +303,25 @@ func (sc *StatisticsController) applyStreamStat(taskID string, metricsCount int,
 	sc.stats.TasksDetails[taskID] = td
 }
 
+func (sc *StatisticsController) applyLimitsStat(taskID string, seriesOverflow, tagsOverflow, tagValueOverflow int) {
+	logF := sc.logger()
+	logF.WithFields(moduleFields).WithFields(logrus.Fields{
+		"task-id":        taskID,
+		"statistic-type": "Limits",
+	}).Trace("Applying statistic")
+
+	td, ok := sc.stats.TasksDetails[taskID]
+	if !ok {
+		return
+	}
+
+	td.Limits.SeriesOverflow += seriesOverflow
+	td.Limits.TagsOverflow += tagsOverflow
+	td.Limits.TagValueOverflow += tagValueOverflow
+
+	sc.stats.TasksDetails[taskID] = td
+}
+
 func (sc *StatisticsController) logger() logrus.FieldLogger {
 	return log.WithCtx(sc.ctx).WithFields(moduleFields).WithField("service", "stats")
 }
@@ -329,3 +359,6 @@ func (d *EmptyController) UpdateExecutionStat(taskID string, metricsCount int, s
 
 func (d *EmptyController) UpdateStreamingStat(taskID string, metricsCount int, startTime, lastUpdate time.Time) {
 }
+
+func (d *EmptyController) UpdateLimitsStat(taskID string, seriesOverflow, tagsOverflow, tagValueOverflow int) {
+}
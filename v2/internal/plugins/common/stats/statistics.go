@@ -48,6 +48,7 @@ type taskDetails struct {
 	Filters       []string        `json:"Requested metrics (filters),omitempty"`
 
 	Counters        tasksCounters   `json:"Counters"`
+	Limits          limitsCounters  `json:"Cardinality limits exceeded"`
 	Loaded          eventTimes      `json:"Loaded"`
 	ProcessingTimes processingTimes `json:"Processing times"`
 	LastMeasurement measurementInfo `json:"Last execution"`
@@ -67,6 +68,12 @@ type tasksCounters struct {
 	AvgMetricsPerExecution int `json:"Average metrics / Execution"`
 }
 
+type limitsCounters struct {
+	SeriesOverflow   int `json:"Series"`
+	TagsOverflow     int `json:"Tags per metric"`
+	TagValueOverflow int `json:"Tag value length"`
+}
+
 type measurementInfo struct {
 	Timestamp        eventTimes
 	Duration         time.Duration
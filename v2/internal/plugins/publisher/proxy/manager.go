@@ -17,6 +17,7 @@
 package proxy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -26,6 +27,7 @@ import (
 	"github.com/sirupsen/logrus"
 	commonProxy "github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/common/proxy"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/common/stats"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/tracing"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 )
@@ -37,10 +39,10 @@ func init() {
 }
 
 type Publisher interface {
-	RequestPublish(id string, mts []*types.Metric) types.ProcessingStatus
-	LoadTask(id string, config []byte) error
-	UnloadTask(id string) error
-	CustomInfo(id string) ([]byte, error)
+	RequestPublish(ctx context.Context, id string, mts []*types.Metric) types.ProcessingStatus
+	LoadTask(ctx context.Context, id string, config []byte) error
+	UnloadTask(ctx context.Context, id string) error
+	CustomInfo(ctx context.Context, id string) ([]byte, error)
 }
 
 type ContextManager struct {
@@ -69,7 +71,14 @@ func NewContextManager(publisher plugin.Publisher, statsController stats.Control
 ///////////////////////////////////////////////////////////////////////////////
 // proxy.Publisher related methods
 
-func (cm *ContextManager) RequestPublish(id string, mts []*types.Metric) types.ProcessingStatus {
+func (cm *ContextManager) RequestPublish(ctx context.Context, id string, mts []*types.Metric) (status types.ProcessingStatus) {
+	_, span := tracing.StartSpan(ctx, "Publish", id)
+	span.SetAttributes(tracing.MetricsCountKey.Int(len(mts)))
+	defer func() {
+		span.SetAttributes(tracing.WarningsCountKey.Int(len(status.Warnings)))
+		tracing.EndSpan(span, status.Error)
+	}()
+
 	if !cm.AcquireTask(id) {
 		return types.ProcessingStatus{
 			Error: fmt.Errorf("can't process publish request, other request for the same id (%s) is in progress", id),
@@ -84,6 +93,8 @@ func (cm *ContextManager) RequestPublish(id string, mts []*types.Metric) types.P
 		}
 	}
 	context := contextIf.(*PluginContext)
+	context.AttachSpan(span)
+	defer context.AttachSpan(nil)
 
 	context.sessionMts = mts // metrics to publish are set within context
 	context.ResetWarnings()
@@ -113,7 +124,10 @@ func (cm *ContextManager) RequestPublish(id string, mts []*types.Metric) types.P
 	}
 }
 
-func (cm *ContextManager) LoadTask(id string, config []byte) error {
+func (cm *ContextManager) LoadTask(ctx context.Context, id string, config []byte) (err error) {
+	_, span := tracing.StartSpan(ctx, "Load", id)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if !cm.AcquireTask(id) {
 		return fmt.Errorf("can't process load request, other request for the same id (%s) is in progress", id)
 	}
@@ -129,7 +143,7 @@ func (cm *ContextManager) LoadTask(id string, config []byte) error {
 	}
 
 	if loadable, ok := cm.publisher.(plugin.LoadablePublisher); ok {
-		err := loadable.Load(newCtx)
+		err := loadable.Load(commonProxy.WithSpan(newCtx, span))
 		if err != nil {
 			return fmt.Errorf("can't load task due to errors returned from user-defined function: %s", err)
 		}
@@ -141,7 +155,10 @@ func (cm *ContextManager) LoadTask(id string, config []byte) error {
 	return nil
 }
 
-func (cm *ContextManager) UnloadTask(id string) error {
+func (cm *ContextManager) UnloadTask(ctx context.Context, id string) (err error) {
+	_, span := tracing.StartSpan(ctx, "Unload", id)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if !cm.AcquireTask(id) {
 		return fmt.Errorf("can't process unload request, other request for the same id (%s) is in progress", id)
 	}
@@ -154,7 +171,7 @@ func (cm *ContextManager) UnloadTask(id string) error {
 
 	context := contextI.(*PluginContext)
 	if unloadable, ok := cm.publisher.(plugin.UnloadablePublisher); ok {
-		err := unloadable.Unload(context)
+		err := unloadable.Unload(commonProxy.WithSpan(context, span))
 		if err != nil {
 			return fmt.Errorf("error occured when trying to unload a publisher task (%s): %v", id, err)
 		}
@@ -166,7 +183,22 @@ func (cm *ContextManager) UnloadTask(id string) error {
 	return nil
 }
 
-func (cm *ContextManager) CustomInfo(id string) ([]byte, error) {
+// TaskIDs returns identifiers of all loaded tasks
+func (cm *ContextManager) TaskIDs() []string {
+	var ids []string
+
+	cm.contextMap.Range(func(k, _ interface{}) bool {
+		ids = append(ids, k.(string))
+		return true
+	})
+
+	return ids
+}
+
+func (cm *ContextManager) CustomInfo(ctx context.Context, id string) (_ []byte, err error) {
+	_, span := tracing.StartSpan(ctx, "Info", id)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	// Do not call cm.AcquireTask as above methods. CustomInfo is read-only
 
 	contextI, ok := cm.contextMap.Load(id)
@@ -176,7 +208,7 @@ func (cm *ContextManager) CustomInfo(id string) ([]byte, error) {
 	context := contextI.(*PluginContext)
 
 	if publisherWithCustomInfo, ok := cm.publisher.(plugin.CustomizableInfoPublisher); ok {
-		infoObj := publisherWithCustomInfo.CustomInfo(context)
+		infoObj := publisherWithCustomInfo.CustomInfo(commonProxy.WithSpan(context, span))
 
 		infoJSON, err := json.Marshal(infoObj)
 		if err != nil {
@@ -195,5 +227,15 @@ func (cm *ContextManager) RequestPluginDefinition() {
 		if err != nil {
 			log.WithError(err).Errorf("Error occurred during plugin definition")
 		}
+		cm.SetDefinitionError(err)
+	}
+}
+
+// HealthCheck calls health check implemented by plugin (if any)
+func (cm *ContextManager) HealthCheck(ctx context.Context) error {
+	if checkable, ok := cm.publisher.(plugin.HealthCheckable); ok {
+		return checkable.HealthCheck(ctx)
 	}
+
+	return nil
 }
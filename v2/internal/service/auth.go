@@ -0,0 +1,113 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authMetadataKey  = "authorization"
+	authBearerPrefix = "Bearer "
+)
+
+// Return token which has to be provided by client in each request (empty if authentication is disabled)
+func authToken(opt *plugin.Options) (string, error) {
+	if opt.AuthTokenFile == "" {
+		return opt.AuthToken, nil
+	}
+
+	content, err := os.ReadFile(filepath.Clean(opt.AuthTokenFile))
+	if err != nil {
+		return "", fmt.Errorf("can't read authentication token: %v", err)
+	}
+
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("authentication token file is empty")
+	}
+
+	return token, nil
+}
+
+func authenticate(ctx context.Context, token string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing authentication token")
+	}
+
+	for _, v := range md.Get(authMetadataKey) {
+		reqToken := strings.TrimPrefix(v, authBearerPrefix)
+		if subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) == 1 {
+			return nil
+		}
+	}
+
+	return status.Error(codes.Unauthenticated, "invalid authentication token")
+}
+
+func authUnaryInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authenticate(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStreamInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authenticate(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// tokenCredentials attaches authentication token to each request (client side)
+type tokenCredentials struct {
+	token string
+}
+
+func NewTokenCredentials(token string) credentials.PerRPCCredentials {
+	return &tokenCredentials{token: token}
+}
+
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		authMetadataKey: authBearerPrefix + t.token,
+	}, nil
+}
+
+// Token is a shared secret protecting from local processes, so it can be sent without TLS
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
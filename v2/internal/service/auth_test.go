@@ -0,0 +1,97 @@
+//go:build medium
+// +build medium
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenAuthentication(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	_ = os.WriteFile(tokenFile, []byte("secret-from-file\n"), 0600)
+
+	testCases := []struct {
+		name         string
+		opt          *plugin.Options
+		clientToken  string
+		expectedCode codes.Code
+	}{
+		{"authentication disabled", &plugin.Options{}, "", codes.OK},
+		{"valid token", &plugin.Options{AuthToken: "secret"}, "secret", codes.OK},
+		{"missing token", &plugin.Options{AuthToken: "secret"}, "", codes.Unauthenticated},
+		{"invalid token", &plugin.Options{AuthToken: "secret"}, "other", codes.Unauthenticated},
+		{"valid token from file", &plugin.Options{AuthTokenFile: tokenFile}, "secret-from-file", codes.OK},
+	}
+
+	Convey("Validate that requests without valid token are rejected", t, func() {
+		for _, tc := range testCases {
+			Convey(fmt.Sprintf("Scenario: %s", tc.name), func() {
+				// Arrange
+				initRoutinesNo := runtime.NumGoroutine()
+				ln, _ := net.Listen("tcp", "127.0.0.1:")
+
+				srv, err := NewGRPCServer(context.Background(), tc.opt, ServerHooks{})
+				So(err, ShouldBeNil)
+				pluginrpc.RegisterControllerServer(srv.(*grpc.Server), &controlMock{closeCh: make(chan bool)})
+
+				go func() {
+					_ = srv.Serve(ln)
+				}()
+
+				dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+				if tc.clientToken != "" {
+					dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(NewTokenCredentials(tc.clientToken)))
+				}
+
+				conn, dialErr := grpc.Dial(ln.Addr().String(), dialOpts...)
+				So(dialErr, ShouldBeNil)
+
+				// Act
+				_, errPing := pluginrpc.NewControllerClient(conn).Ping(context.Background(), &pluginrpc.PingRequest{})
+
+				// Assert
+				So(status.Code(errPing), ShouldEqual, tc.expectedCode)
+
+				_ = conn.Close()
+				srv.Stop()
+				waitForRoutines(initRoutinesNo)
+			})
+		}
+	})
+
+	Convey("Validate that GRPC Server can't be created when token file is missing", t, func() {
+		_, err := NewGRPCServer(context.Background(), &plugin.Options{AuthTokenFile: filepath.Join(t.TempDir(), "missing")}, ServerHooks{})
+		So(err, ShouldBeError)
+	})
+}
@@ -47,7 +47,7 @@ func (cs *collectService) Collect(request *pluginrpc.CollectRequest, stream plug
 	logF.Debug("GRPC Collect() received")
 	defer logF.Debug("GRPC Collect() completed")
 
-	chunksCh := cs.proxy.RequestCollect(taskID)
+	chunksCh := cs.proxy.RequestCollect(stream.Context(), taskID)
 
 	for chunk := range chunksCh {
 		// try to send metrics first, even if there were errors during Collect or StreamingCollect
@@ -79,7 +79,7 @@ func (cs *collectService) Load(ctx context.Context, request *pluginrpc.LoadColle
 	jsonConfig := request.GetJsonConfig()
 	metrics := request.GetMetricSelectors()
 
-	return &pluginrpc.LoadCollectorResponse{}, cs.proxy.LoadTask(taskID, jsonConfig, metrics)
+	return &pluginrpc.LoadCollectorResponse{}, cs.proxy.LoadTask(ctx, taskID, jsonConfig, metrics)
 }
 
 func (cs *collectService) Unload(ctx context.Context, request *pluginrpc.UnloadCollectorRequest) (*pluginrpc.UnloadCollectorResponse, error) {
@@ -89,7 +89,7 @@ func (cs *collectService) Unload(ctx context.Context, request *pluginrpc.UnloadC
 	logF.Debug("GRPC Unload() received")
 	defer logF.Debug("GRPC Unload() completed")
 
-	return &pluginrpc.UnloadCollectorResponse{}, cs.proxy.UnloadTask(taskID)
+	return &pluginrpc.UnloadCollectorResponse{}, cs.proxy.UnloadTask(ctx, taskID)
 }
 
 func (cs *collectService) Info(ctx context.Context, request *pluginrpc.InfoRequest) (*pluginrpc.InfoResponse, error) {
@@ -99,7 +99,7 @@ func (cs *collectService) Info(ctx context.Context, request *pluginrpc.InfoReque
 	logF.Debug("GRPC Info() received")
 	defer logF.Debug("GRPC Info() completed")
 
-	cInfo, err := cs.proxy.CustomInfo(taskID)
+	cInfo, err := cs.proxy.CustomInfo(ctx, taskID)
 	if err != nil {
 		return nil, err
 	}
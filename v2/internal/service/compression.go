@@ -0,0 +1,158 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+const (
+	CompressionNone = ""
+	CompressionGzip = gzip.Name
+	CompressionZstd = "zstd"
+)
+
+// Compressors registered by library (plugin is able to receive and send messages compressed with any of them)
+var SupportedCompressions = []string{CompressionGzip, CompressionZstd}
+
+func init() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+}
+
+func IsSupportedCompression(name string) bool {
+	if name == CompressionNone {
+		return true
+	}
+
+	for _, c := range SupportedCompressions {
+		if c == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// zstdCompressor implements encoding.Compressor (encoders and decoders are reused, since their creation is expensive)
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+// zstdReader is created for every message, so the pooled decoder is never shared with reader returned to other caller
+type zstdReader struct {
+	dec  *zstd.Decoder // nil when decoder has been returned to the pool
+	pool *sync.Pool
+}
+
+func (c *zstdCompressor) Name() string {
+	return CompressionZstd
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if zw, ok := c.encoders.Get().(*zstdWriter); ok {
+		zw.Encoder.Reset(w)
+		return zw, nil
+	}
+
+	enc, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdWriter{Encoder: enc, pool: &c.encoders}, nil
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	if dec, ok := c.decoders.Get().(*zstd.Decoder); ok {
+		if err := dec.Reset(r); err != nil {
+			c.decoders.Put(dec)
+			return nil, err
+		}
+		return &zstdReader{dec: dec, pool: &c.decoders}, nil
+	}
+
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdReader{dec: dec, pool: &c.decoders}, nil
+}
+
+func (zw *zstdWriter) Close() error {
+	err := zw.Encoder.Close()
+	zw.pool.Put(zw)
+
+	return err
+}
+
+// Read returns decoder to the pool when the whole message has been read (reader can't be used afterwards)
+func (zr *zstdReader) Read(p []byte) (int, error) {
+	if zr.dec == nil {
+		return 0, io.EOF
+	}
+
+	n, err := zr.dec.Read(p)
+	if err == io.EOF {
+		dec := zr.dec
+		zr.dec = nil
+		_ = dec.Reset(nil)
+		zr.pool.Put(dec)
+	}
+
+	return n, err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// Interceptors requesting compression of responses. Compression is used only when client declared it's supported.
+func compressionUnaryInterceptor(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		setSendCompressor(ctx, name)
+		return handler(ctx, req)
+	}
+}
+
+func compressionStreamInterceptor(name string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		setSendCompressor(ss.Context(), name)
+		return handler(srv, ss)
+	}
+}
+
+func setSendCompressor(ctx context.Context, name string) {
+	err := grpc.SetSendCompressor(ctx, name)
+	if err != nil {
+		log.WithCtx(ctx).WithFields(moduleFields).WithError(err).Tracef("Response won't be compressed with %s", name)
+	}
+}
@@ -0,0 +1,156 @@
+//go:build medium
+// +build medium
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/stats"
+)
+
+// Gathers compression of messages received by client
+type compressionStatsHandler struct {
+	compression string
+}
+
+func (h *compressionStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *compressionStatsHandler) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if inHeader, ok := s.(*stats.InHeader); ok && inHeader.Client {
+		h.compression = inHeader.Compression
+	}
+}
+
+func (h *compressionStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *compressionStatsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestCompressedGRPC(t *testing.T) {
+	testCases := []struct {
+		serverCompression string
+		clientCompression string
+		expectedEncoding  string
+	}{
+		{CompressionZstd, CompressionZstd, CompressionZstd},
+		{CompressionGzip, CompressionGzip, CompressionGzip},
+		{CompressionZstd, CompressionGzip, CompressionZstd},
+		{CompressionNone, CompressionZstd, CompressionZstd}, // by default response is compressed in the same way as request
+	}
+
+	Convey("Validate that GRPC responses are compressed when requested by plugin options", t, func() {
+		for _, tc := range testCases {
+			Convey(fmt.Sprintf("Scenario: server=%q, client=%q", tc.serverCompression, tc.clientCompression), func() {
+				// Arrange
+				initRoutinesNo := runtime.NumGoroutine()
+				opt := &plugin.Options{
+					GRPCCompression:    tc.serverCompression,
+					GRPCMaxRecvMsgSize: 1024,
+				}
+
+				ln, _ := net.Listen("tcp", "127.0.0.1:")
+				controlService := &controlMock{closeCh: make(chan bool)}
+
+				srv, err := NewGRPCServer(context.Background(), opt, ServerHooks{})
+				So(err, ShouldBeNil)
+				pluginrpc.RegisterControllerServer(srv.(*grpc.Server), controlService)
+
+				go func() {
+					_ = srv.Serve(ln)
+				}()
+
+				statsHandler := &compressionStatsHandler{}
+				conn, dialErr := grpc.Dial(ln.Addr().String(),
+					grpc.WithTransportCredentials(insecure.NewCredentials()),
+					grpc.WithStatsHandler(statsHandler),
+					grpc.WithDefaultCallOptions(grpc.UseCompressor(tc.clientCompression)))
+				So(dialErr, ShouldBeNil)
+
+				cc := pluginrpc.NewControllerClient(conn)
+
+				// Act
+				_, errPing := cc.Ping(context.Background(), &pluginrpc.PingRequest{})
+
+				// Assert
+				So(errPing, ShouldBeNil)
+				So(statsHandler.compression, ShouldEqual, tc.expectedEncoding)
+
+				_ = conn.Close()
+				srv.Stop()
+				waitForRoutines(initRoutinesNo)
+			})
+		}
+	})
+}
+
+func TestZstdCompressor(t *testing.T) {
+	Convey("Validate that reader doesn't share decoder returned to the pool", t, func() {
+		c := &zstdCompressor{}
+
+		compress := func(msg string) *bytes.Buffer {
+			buf := &bytes.Buffer{}
+			w, err := c.Compress(buf)
+			So(err, ShouldBeNil)
+			_, _ = w.Write([]byte(msg))
+			So(w.Close(), ShouldBeNil)
+			return buf
+		}
+
+		r1, err := c.Decompress(compress("first message"))
+		So(err, ShouldBeNil)
+		msg1, err := io.ReadAll(r1) // decoder is returned to the pool
+		So(err, ShouldBeNil)
+		So(string(msg1), ShouldEqual, "first message")
+
+		r2, err := c.Decompress(compress("second message"))
+		So(err, ShouldBeNil)
+
+		// reading from completed reader doesn't affect the one using decoder taken from the pool
+		n, err := r1.Read(make([]byte, 16))
+		So(n, ShouldEqual, 0)
+		So(err, ShouldEqual, io.EOF)
+
+		msg2, err := io.ReadAll(r2)
+		So(err, ShouldBeNil)
+		So(string(msg2), ShouldEqual, "second message")
+	})
+}
+
+// Wait until goroutines started by GRPC client and server are completed (not to affect other tests checking leaks)
+func waitForRoutines(routinesNo int) {
+	for i := 0; i < 100 && runtime.NumGoroutine() > routinesNo; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
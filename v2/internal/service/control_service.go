@@ -42,15 +42,19 @@ type controlService struct {
 	pingCh chan struct{}   // notification about received ping
 	ctx    context.Context // check for a notification from top level code (service crash etc.)
 	errCh  chan error
+	health *Health // state of ping monitor is reported by liveness probe
 }
 
-func newControlService(ctx context.Context, errCh chan error, pingTimeout time.Duration, maxMissingPingCounter uint) *controlService {
+func newControlService(ctx context.Context, errCh chan error, pingTimeout time.Duration, maxMissingPingCounter uint, health *Health) *controlService {
 	cs := &controlService{
 		pingCh: make(chan struct{}),
 		ctx:    ctx,
 		errCh:  errCh,
+		health: health,
 	}
 
+	health.setPingMonitor(pingTimeout, maxMissingPingCounter)
+
 	go cs.monitor(pingTimeout, maxMissingPingCounter)
 
 	return cs
@@ -91,6 +95,7 @@ func (cs *controlService) monitor(timeout time.Duration, maxPingMissed uint) {
 				if !ok {
 					return
 				}
+				cs.health.pingReceived()
 			}
 		}
 	}
@@ -100,8 +105,10 @@ func (cs *controlService) monitor(timeout time.Duration, maxPingMissed uint) {
 		select {
 		case <-cs.pingCh:
 			pingMissed = 0
+			cs.health.pingReceived()
 		case <-time.After(timeout):
 			pingMissed++
+			cs.health.pingMissed(pingMissed)
 			cs.logger().WithFields(controlSrvFields).WithFields(logrus.Fields{
 				"missed": pingMissed,
 				"max":    maxPingMissed,
@@ -47,7 +47,7 @@ func TestControlServiceMonitor_MissingPing(t *testing.T) {
 	doneTestCh := make(chan bool)
 
 	ctx, cancelFn := context.WithCancel(context.Background())
-	cs := newControlService(ctx, closeCh, 200*time.Millisecond, 3)
+	cs := newControlService(ctx, closeCh, 200*time.Millisecond, 3, nil)
 
 	go func() {
 		// ok
@@ -93,7 +93,7 @@ func TestControlServiceMonitor_MaxMissedPings(t *testing.T) {
 	doneTestCh := make(chan bool)
 
 	ctx, cancelFn := context.WithCancel(context.Background())
-	cs := newControlService(ctx, closeCh, 200*time.Millisecond, 3)
+	cs := newControlService(ctx, closeCh, 200*time.Millisecond, 3, nil)
 
 	go func() {
 		// ok
@@ -137,7 +137,7 @@ func TestControlServiceMonitor_ClosingInfiniteMonitor(t *testing.T) {
 	doneTestCh := make(chan bool)
 
 	ctx, cancelFn := context.WithCancel(context.Background())
-	cs := newControlService(ctx, closeCh, 0, 0)
+	cs := newControlService(ctx, closeCh, 0, 0, nil)
 
 	go func() {
 		time.Sleep(100 * time.Millisecond)
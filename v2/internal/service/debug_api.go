@@ -0,0 +1,317 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+)
+
+// Debug API exposes the same operations as GRPC services with HTTP/JSON endpoints, so running plugin can be
+// exercised with curl or a browser. Metrics have the same form as in GRPC messages (JSON mapping of protobuf),
+// so output of collect can be used as input of publish.
+//
+//	GET    /tasks               - list of loaded tasks
+//	POST   /tasks/{id}          - load task, body: {"config": {...}, "filter": ["/example/group/*"]}
+//	DELETE /tasks/{id}          - unload task
+//	GET    /tasks/{id}/info     - custom information about task
+//	POST   /tasks/{id}/collect  - collect metrics (collector only), response: {"metricSet": [...], "warnings": [...]}
+//	POST   /tasks/{id}/publish  - publish metrics (publisher only), body: {"metricSet": [...]}
+//
+// When authentication token is configured (the same as for GRPC), it has to be sent in each request
+// (header "Authorization: Bearer <token>").
+
+const maxDebugAPIBodySize = 64 * 1024 * 1024
+
+type debugLoadRequest struct {
+	Config json.RawMessage `json:"config"`
+	Filter []string        `json:"filter"` // collector only
+}
+
+func (r *debugLoadRequest) jsonConfig() []byte {
+	if len(r.Config) == 0 {
+		return []byte("{}")
+	}
+	return r.Config
+}
+
+type debugErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type debugAPI struct {
+	ctx   context.Context
+	token string // empty if authentication is disabled
+}
+
+func newDebugAPI(ctx context.Context, opt *plugin.Options) (*debugAPI, error) {
+	token, err := authToken(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &debugAPI{ctx: ctx, token: token}, nil
+}
+
+// NewCollectorDebugAPI returns HTTP handler of debug API served by collector
+func NewCollectorDebugAPI(ctx context.Context, proxy CollectorProxy, opt *plugin.Options) (http.Handler, error) {
+	api, err := newDebugAPI(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	h := http.NewServeMux()
+
+	api.handleTasks(h, proxy.TaskIDs, proxy.UnloadTask, proxy.CustomInfo)
+
+	h.HandleFunc("POST /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		req := &debugLoadRequest{}
+		if !api.readJSON(w, r, req) {
+			return
+		}
+
+		api.writeResult(w, r, proxy.LoadTask(r.Context(), r.PathValue("id"), req.jsonConfig(), req.Filter))
+	})
+
+	h.HandleFunc("POST /tasks/{id}/collect", func(w http.ResponseWriter, r *http.Request) {
+		api.collect(w, r, proxy)
+	})
+
+	return api.logged(api.authenticated(h)), nil
+}
+
+// NewPublisherDebugAPI returns HTTP handler of debug API served by publisher
+func NewPublisherDebugAPI(ctx context.Context, proxy PublisherProxy, opt *plugin.Options) (http.Handler, error) {
+	api, err := newDebugAPI(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	h := http.NewServeMux()
+
+	api.handleTasks(h, proxy.TaskIDs, proxy.UnloadTask, proxy.CustomInfo)
+
+	h.HandleFunc("POST /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		req := &debugLoadRequest{}
+		if !api.readJSON(w, r, req) {
+			return
+		}
+
+		api.writeResult(w, r, proxy.LoadTask(r.Context(), r.PathValue("id"), req.jsonConfig()))
+	})
+
+	h.HandleFunc("POST /tasks/{id}/publish", func(w http.ResponseWriter, r *http.Request) {
+		api.publish(w, r, proxy)
+	})
+
+	return api.logged(api.authenticated(h)), nil
+}
+
+// handleTasks registers endpoints common for collector and publisher
+func (api *debugAPI) handleTasks(h *http.ServeMux, taskIDs func() []string, unload func(context.Context, string) error, info func(context.Context, string) ([]byte, error)) {
+	h.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		ids := taskIDs()
+		if ids == nil {
+			ids = []string{}
+		}
+
+		api.writeJSON(w, r, http.StatusOK, ids)
+	})
+
+	h.HandleFunc("DELETE /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		api.writeResult(w, r, unload(r.Context(), r.PathValue("id")))
+	})
+
+	h.HandleFunc("GET /tasks/{id}/info", func(w http.ResponseWriter, r *http.Request) {
+		cInfo, err := info(r.Context(), r.PathValue("id"))
+		if err != nil {
+			api.writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		api.write(w, r, http.StatusOK, cInfo)
+	})
+}
+
+func (api *debugAPI) collect(w http.ResponseWriter, r *http.Request, proxy CollectorProxy) {
+	resp := &pluginrpc.CollectResponse{}
+	var collectErr error
+
+	for chunk := range proxy.RequestCollect(r.Context(), r.PathValue("id")) {
+		for _, mt := range chunk.Metrics {
+			protoMt, err := toGRPCMetric(mt)
+			if err != nil {
+				api.logger(r).WithError(err).WithField("metric", mt.String()).Error("can't convert metric")
+				continue
+			}
+			resp.MetricSet = append(resp.MetricSet, protoMt)
+		}
+
+		for _, warn := range chunk.Warnings {
+			resp.Warnings = append(resp.Warnings, toGRPCWarning(warn))
+		}
+
+		if chunk.Err != nil {
+			collectErr = chunk.Err
+		}
+	}
+
+	if collectErr != nil {
+		api.writeError(w, r, http.StatusInternalServerError, fmt.Errorf("plugin errored while collecting metrics: %v", collectErr))
+		return
+	}
+
+	api.writeProto(w, r, resp)
+}
+
+func (api *debugAPI) publish(w http.ResponseWriter, r *http.Request, proxy PublisherProxy) {
+	req := &pluginrpc.PublishRequest{}
+
+	// unknown fields are allowed, so response of collect (containing warnings) can be published as is
+	unmarshaler := &jsonpb.Unmarshaler{AllowUnknownFields: true}
+
+	err := unmarshaler.Unmarshal(io.LimitReader(r.Body, maxDebugAPIBodySize), req)
+	if err != nil {
+		api.writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	mts := make([]*types.Metric, 0, len(req.MetricSet))
+	for _, protoMt := range req.MetricSet {
+		mt, err := fromGRPCMetric(protoMt)
+		if err != nil {
+			api.writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid metric: %v", err))
+			return
+		}
+		mts = append(mts, &mt)
+	}
+
+	status := proxy.RequestPublish(r.Context(), r.PathValue("id"), mts)
+	if status.Error != nil {
+		api.writeError(w, r, http.StatusInternalServerError, status.Error)
+		return
+	}
+
+	resp := &pluginrpc.PublishResponse{}
+	for _, warn := range status.Warnings {
+		resp.Warnings = append(resp.Warnings, toGRPCWarning(warn))
+	}
+
+	api.writeProto(w, r, resp)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func (api *debugAPI) logged(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.logger(r).WithField("method", r.Method).Debug("Debug API request received")
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (api *debugAPI) authenticated(h http.Handler) http.Handler {
+	if api.token == "" {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqToken, ok := strings.CutPrefix(r.Header.Get(authMetadataKey), authBearerPrefix)
+		if !ok || subtle.ConstantTimeCompare([]byte(reqToken), []byte(api.token)) != 1 {
+			api.writeError(w, r, http.StatusUnauthorized, errors.New("invalid authentication token"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (api *debugAPI) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxDebugAPIBodySize))
+	if err == nil && len(body) > 0 {
+		err = json.Unmarshal(body, v)
+	}
+	if err != nil {
+		api.writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return false
+	}
+
+	return true
+}
+
+// writeResult completes request without content or with error
+func (api *debugAPI) writeResult(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		api.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *debugAPI) writeError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	api.logger(r).WithError(err).Warning("Debug API request failed")
+	api.writeJSON(w, r, code, &debugErrorResponse{Error: err.Error()})
+}
+
+func (api *debugAPI) writeProto(w http.ResponseWriter, r *http.Request, msg proto.Message) {
+	content, err := (&jsonpb.Marshaler{}).MarshalToString(msg)
+	if err != nil {
+		api.writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	api.write(w, r, http.StatusOK, []byte(content))
+}
+
+func (api *debugAPI) writeJSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		api.logger(r).WithError(err).Error("Can't marshal debug API response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	api.write(w, r, code, content)
+}
+
+func (api *debugAPI) write(w http.ResponseWriter, r *http.Request, code int, content []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_, err := w.Write(content)
+	if err != nil {
+		api.logger(r).WithError(err).Error("Can't write debug API response")
+	}
+}
+
+func (api *debugAPI) logger(r *http.Request) logrus.FieldLogger {
+	return log.WithCtx(api.ctx).WithFields(moduleFields).WithField("service", "DebugAPI").WithField("URI", r.RequestURI)
+}
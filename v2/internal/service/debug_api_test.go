@@ -0,0 +1,237 @@
+//go:build medium
+// +build medium
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+)
+
+type debugCollectorMock struct {
+	taskManagerMock
+
+	loadedConfig []byte
+	loadedFilter []string
+	collectErr   error
+}
+
+func (m *debugCollectorMock) LoadTask(_ context.Context, id string, rawConfig []byte, mtsSelectors []string) error {
+	if strings.Contains(string(rawConfig), "invalid") {
+		return errors.New("invalid configuration")
+	}
+
+	m.tasks[id] = nil
+	m.loadedConfig = rawConfig
+	m.loadedFilter = mtsSelectors
+	return nil
+}
+
+func (m *debugCollectorMock) RequestCollect(_ context.Context, _ string) <-chan types.CollectChunk {
+	chunkCh := make(chan types.CollectChunk, 2)
+	chunkCh <- types.CollectChunk{
+		Metrics: []*types.Metric{{
+			Namespace_: []types.NamespaceElement{{Value_: "example"}, {Value_: "value"}},
+			Value_:     int64(12),
+			Tags_:      map[string]string{"host": "local"},
+			Timestamp_: time.Now(),
+			Type_:      plugin.GaugeType,
+		}},
+		Warnings: []types.Warning{{Message: "partial data", Timestamp: time.Now()}},
+	}
+	if m.collectErr != nil {
+		chunkCh <- types.CollectChunk{Err: m.collectErr}
+	}
+	close(chunkCh)
+
+	return chunkCh
+}
+
+func (m *debugCollectorMock) CustomInfo(_ context.Context, id string) ([]byte, error) {
+	return []byte(`{"task":"` + id + `"}`), nil
+}
+
+type debugPublisherMock struct {
+	taskManagerMock
+
+	published  []*types.Metric
+	publishErr error
+}
+
+func (m *debugPublisherMock) LoadTask(_ context.Context, id string, _ []byte) error {
+	m.tasks[id] = nil
+	return nil
+}
+
+func (m *debugPublisherMock) RequestPublish(_ context.Context, _ string, mts []*types.Metric) types.ProcessingStatus {
+	m.published = mts
+	return types.ProcessingStatus{Error: m.publishErr}
+}
+
+func (m *debugPublisherMock) CustomInfo(context.Context, string) ([]byte, error) {
+	return nil, errors.New("unknown task")
+}
+
+func debugRequest(h http.Handler, method string, uri string, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, uri, strings.NewReader(body)))
+	return rec
+}
+
+func TestCollectorDebugAPI(t *testing.T) {
+	Convey("Validate that collector operations can be requested via debug API", t, func() {
+		m := &debugCollectorMock{taskManagerMock: taskManagerMock{tasks: map[string]error{}}}
+		h, err := NewCollectorDebugAPI(context.Background(), m, &plugin.Options{})
+		So(err, ShouldBeNil)
+
+		Convey("task can be loaded, used and unloaded", func() {
+			rec := debugRequest(h, http.MethodPost, "/tasks/task-1", `{"config": {"address": "localhost"}, "filter": ["/example/*"]}`)
+			So(rec.Code, ShouldEqual, http.StatusNoContent)
+			So(string(m.loadedConfig), ShouldEqual, `{"address": "localhost"}`)
+			So(m.loadedFilter, ShouldResemble, []string{"/example/*"})
+
+			rec = debugRequest(h, http.MethodGet, "/tasks", "")
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Body.String(), ShouldEqual, `["task-1"]`)
+
+			rec = debugRequest(h, http.MethodPost, "/tasks/task-1/collect", "")
+			So(rec.Code, ShouldEqual, http.StatusOK)
+
+			resp := &pluginrpc.CollectResponse{}
+			So(jsonpb.UnmarshalString(rec.Body.String(), resp), ShouldBeNil)
+			So(resp.MetricSet, ShouldHaveLength, 1)
+			So(resp.MetricSet[0].Namespace[1].Value, ShouldEqual, "value")
+			So(resp.MetricSet[0].Tags, ShouldResemble, map[string]string{"host": "local"})
+			So(resp.Warnings, ShouldHaveLength, 1)
+
+			rec = debugRequest(h, http.MethodGet, "/tasks/task-1/info", "")
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Body.String(), ShouldEqual, `{"task":"task-1"}`)
+
+			rec = debugRequest(h, http.MethodDelete, "/tasks/task-1", "")
+			So(rec.Code, ShouldEqual, http.StatusNoContent)
+			So(m.tasks, ShouldBeEmpty)
+		})
+
+		Convey("task without configuration can be loaded", func() {
+			rec := debugRequest(h, http.MethodPost, "/tasks/task-1", "")
+			So(rec.Code, ShouldEqual, http.StatusNoContent)
+			So(string(m.loadedConfig), ShouldEqual, "{}")
+		})
+
+		Convey("errors are reported", func() {
+			rec := debugRequest(h, http.MethodPost, "/tasks/task-1", `{"config": "invalid"}`)
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+
+			errResp := &debugErrorResponse{}
+			So(json.Unmarshal(rec.Body.Bytes(), errResp), ShouldBeNil)
+			So(errResp.Error, ShouldEqual, "invalid configuration")
+
+			rec = debugRequest(h, http.MethodPost, "/tasks/task-1", `{"config": `)
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+
+			m.collectErr = errors.New("backend unavailable")
+			rec = debugRequest(h, http.MethodPost, "/tasks/task-1/collect", "")
+			So(rec.Code, ShouldEqual, http.StatusInternalServerError)
+			So(rec.Body.String(), ShouldContainSubstring, "backend unavailable")
+		})
+
+		Convey("publish isn't available", func() {
+			rec := debugRequest(h, http.MethodPost, "/tasks/task-1/publish", "{}")
+			So(rec.Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestPublisherDebugAPI(t *testing.T) {
+	Convey("Validate that publisher operations can be requested via debug API", t, func() {
+		m := &debugPublisherMock{taskManagerMock: taskManagerMock{tasks: map[string]error{}}}
+		h, err := NewPublisherDebugAPI(context.Background(), m, &plugin.Options{})
+		So(err, ShouldBeNil)
+
+		collectorH, _ := NewCollectorDebugAPI(context.Background(), &debugCollectorMock{}, &plugin.Options{})
+		collected := debugRequest(collectorH, http.MethodPost, "/tasks/task-1/collect", "").Body.String()
+
+		Convey("collected metrics can be published", func() {
+			rec := debugRequest(h, http.MethodPost, "/tasks/task-1", `{"config": {}}`)
+			So(rec.Code, ShouldEqual, http.StatusNoContent)
+
+			rec = debugRequest(h, http.MethodPost, "/tasks/task-1/publish", collected)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(m.published, ShouldHaveLength, 1)
+			So(m.published[0].Namespace().String(), ShouldEqual, "/example/value")
+			So(m.published[0].Value(), ShouldEqual, int64(12))
+		})
+
+		Convey("errors are reported", func() {
+			rec := debugRequest(h, http.MethodPost, "/tasks/task-1/publish", `{"metricSet": 1}`)
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+
+			m.publishErr = errors.New("backend unavailable")
+			rec = debugRequest(h, http.MethodPost, "/tasks/task-1/publish", collected)
+			So(rec.Code, ShouldEqual, http.StatusInternalServerError)
+			So(rec.Body.String(), ShouldContainSubstring, "backend unavailable")
+
+			rec = debugRequest(h, http.MethodGet, "/tasks/task-1/info", "")
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestDebugAPIAuthentication(t *testing.T) {
+	Convey("Validate that debug API requires authentication token when it's configured", t, func() {
+		m := &debugCollectorMock{taskManagerMock: taskManagerMock{tasks: map[string]error{}}}
+		h, err := NewCollectorDebugAPI(context.Background(), m, &plugin.Options{AuthToken: "secret"})
+		So(err, ShouldBeNil)
+
+		authRequest := func(header string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/tasks/task-1", strings.NewReader(""))
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			h.ServeHTTP(rec, req)
+			return rec
+		}
+
+		So(authRequest("").Code, ShouldEqual, http.StatusUnauthorized)
+		So(authRequest("Bearer invalid").Code, ShouldEqual, http.StatusUnauthorized)
+		So(authRequest("secret").Code, ShouldEqual, http.StatusUnauthorized)
+		So(m.tasks, ShouldBeEmpty)
+
+		So(authRequest("Bearer secret").Code, ShouldEqual, http.StatusNoContent)
+		So(m.tasks, ShouldContainKey, "task-1")
+
+		_, err = NewCollectorDebugAPI(context.Background(), m, &plugin.Options{AuthTokenFile: filepath.Join(t.TempDir(), "missing")})
+		So(err, ShouldBeError)
+	})
+}
@@ -0,0 +1,235 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+)
+
+const healthCheckTimeout = 5 * time.Second
+
+const (
+	probeStatusOK      = "ok"
+	probeStatusFailing = "failing"
+)
+
+// Health gathers state of GRPC services reported by liveness (/healthz) and readiness (/readyz) probes.
+// nil Health is valid (state isn't tracked).
+type Health struct {
+	mu sync.RWMutex
+
+	grpcEnabled bool // false when plugin doesn't serve GRPC (debug mode)
+	serving     bool
+
+	pingTimeout    time.Duration
+	maxMissedPings uint
+	lastPing       time.Time
+	missedPings    uint
+}
+
+func NewHealth(grpcEnabled bool) *Health {
+	return &Health{grpcEnabled: grpcEnabled}
+}
+
+func (h *Health) setServing(serving bool) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.serving = serving
+}
+
+func (h *Health) setPingMonitor(timeout time.Duration, maxMissed uint) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pingTimeout = timeout
+	h.maxMissedPings = maxMissed
+}
+
+func (h *Health) pingReceived() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastPing = time.Now()
+	h.missedPings = 0
+}
+
+func (h *Health) pingMissed(missed uint) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.missedPings = missed
+}
+
+func (h *Health) pingState() (string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	switch {
+	case !h.grpcEnabled:
+		return "GRPC server is not used", nil
+	case h.pingTimeout == 0 || h.maxMissedPings == 0:
+		return "ping monitoring is disabled", nil
+	case h.missedPings > 0:
+		return "", fmt.Errorf("ping missed %d time(s) (max: %d, timeout: %s)", h.missedPings, h.maxMissedPings, h.pingTimeout)
+	case h.lastPing.IsZero():
+		return "no ping received yet", nil
+	default:
+		return fmt.Sprintf("last ping received at %s", h.lastPing.Format(time.RFC3339)), nil
+	}
+}
+
+func (h *Health) grpcState() (string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	switch {
+	case !h.grpcEnabled:
+		return "GRPC server is not used", nil
+	case !h.serving:
+		return "", fmt.Errorf("GRPC server is not serving")
+	default:
+		return "", nil
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// HealthProvider is implemented by collector and publisher proxies
+type HealthProvider interface {
+	DefinitionError() error                // error returned by PluginDefinition
+	HealthCheck(ctx context.Context) error // result of plugin.HealthCheckable (if implemented by plugin)
+}
+
+// collectStatusProvider is implemented by collector proxy
+type collectStatusProvider interface {
+	LastCollectStatus() (time.Time, error)
+}
+
+type probeCheck struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type collectStatus struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+type probeResponse struct {
+	Status      string                `json:"status"`
+	Checks      map[string]probeCheck `json:"checks"`
+	LastCollect *collectStatus        `json:"lastCollect,omitempty"` // informative, doesn't affect status
+}
+
+func (r *probeResponse) add(name string, err error, okMessage string) {
+	if err != nil {
+		r.Checks[name] = probeCheck{Status: probeStatusFailing, Message: err.Error()}
+		r.Status = probeStatusFailing
+		return
+	}
+
+	r.Checks[name] = probeCheck{Status: probeStatusOK, Message: okMessage}
+}
+
+// NewHealthHandler returns HTTP handler serving liveness (/healthz) and readiness (/readyz) probes
+func NewHealthHandler(ctx context.Context, health *Health, provider HealthProvider) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		resp := &probeResponse{Status: probeStatusOK, Checks: map[string]probeCheck{}}
+
+		pingMsg, pingErr := health.pingState()
+		resp.add("ping", pingErr, pingMsg)
+
+		checkCtx, cancelFn := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancelFn()
+		resp.add("plugin", provider.HealthCheck(checkCtx), "")
+
+		if csp, ok := provider.(collectStatusProvider); ok {
+			if t, err := csp.LastCollectStatus(); !t.IsZero() {
+				resp.LastCollect = &collectStatus{Time: t}
+				if err != nil {
+					resp.LastCollect.Error = err.Error()
+				}
+			}
+		}
+
+		writeProbeResponse(ctx, w, r, resp)
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		resp := &probeResponse{Status: probeStatusOK, Checks: map[string]probeCheck{}}
+
+		grpcMsg, grpcErr := health.grpcState()
+		resp.add("grpc", grpcErr, grpcMsg)
+		resp.add("definition", provider.DefinitionError(), "")
+
+		writeProbeResponse(ctx, w, r, resp)
+	})
+
+	return mux
+}
+
+func writeProbeResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, resp *probeResponse) {
+	logF := log.WithCtx(ctx).WithFields(moduleFields).WithFields(logrus.Fields{"service": "Health", "URI": r.RequestURI})
+
+	code := http.StatusOK
+	if resp.Status != probeStatusOK {
+		code = http.StatusServiceUnavailable
+		logF.WithField("checks", resp.Checks).Debug("Probe is failing")
+	}
+
+	content, err := json.Marshal(resp)
+	if err != nil {
+		logF.WithError(err).Error("Can't marshal probe response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_, err = w.Write(content)
+	if err != nil {
+		logF.WithError(err).Error("Can't write probe response")
+	}
+}
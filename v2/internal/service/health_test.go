@@ -0,0 +1,130 @@
+//go:build medium
+// +build medium
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type healthProviderMock struct {
+	definitionErr  error
+	healthCheckErr error
+}
+
+func (m *healthProviderMock) DefinitionError() error {
+	return m.definitionErr
+}
+
+func (m *healthProviderMock) HealthCheck(_ context.Context) error {
+	return m.healthCheckErr
+}
+
+type collectorHealthProviderMock struct {
+	healthProviderMock
+
+	lastCollectTime time.Time
+	lastCollectErr  error
+}
+
+func (m *collectorHealthProviderMock) LastCollectStatus() (time.Time, error) {
+	return m.lastCollectTime, m.lastCollectErr
+}
+
+func doProbeRequest(h http.Handler, uri string) (int, probeResponse) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, uri, nil))
+
+	resp := probeResponse{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp
+}
+
+func TestHealthProbes(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Validate that readiness probe reflects state of GRPC server and plugin definition", t, func() {
+		health := NewHealth(true)
+		provider := &healthProviderMock{}
+		h := NewHealthHandler(ctx, health, provider)
+
+		code, resp := doProbeRequest(h, "/readyz")
+		So(code, ShouldEqual, http.StatusServiceUnavailable)
+		So(resp.Checks["grpc"].Status, ShouldEqual, probeStatusFailing)
+
+		health.setServing(true)
+		code, resp = doProbeRequest(h, "/readyz")
+		So(code, ShouldEqual, http.StatusOK)
+		So(resp.Status, ShouldEqual, probeStatusOK)
+
+		provider.definitionErr = errors.New("invalid definition")
+		code, resp = doProbeRequest(h, "/readyz")
+		So(code, ShouldEqual, http.StatusServiceUnavailable)
+		So(resp.Checks["definition"].Message, ShouldEqual, "invalid definition")
+	})
+
+	Convey("Validate that liveness probe reflects pings and plugin health check", t, func() {
+		health := NewHealth(true)
+		health.setPingMonitor(time.Second, 3)
+		provider := &healthProviderMock{}
+		h := NewHealthHandler(ctx, health, provider)
+
+		code, _ := doProbeRequest(h, "/healthz")
+		So(code, ShouldEqual, http.StatusOK)
+
+		health.pingMissed(1)
+		code, resp := doProbeRequest(h, "/healthz")
+		So(code, ShouldEqual, http.StatusServiceUnavailable)
+		So(resp.Checks["ping"].Status, ShouldEqual, probeStatusFailing)
+
+		health.pingReceived()
+		code, _ = doProbeRequest(h, "/healthz")
+		So(code, ShouldEqual, http.StatusOK)
+
+		provider.healthCheckErr = errors.New("backend unreachable")
+		code, resp = doProbeRequest(h, "/healthz")
+		So(code, ShouldEqual, http.StatusServiceUnavailable)
+		So(resp.Checks["plugin"].Message, ShouldEqual, "backend unreachable")
+	})
+
+	Convey("Validate that liveness probe reports last collect without affecting status", t, func() {
+		provider := &collectorHealthProviderMock{
+			lastCollectTime: time.Now(),
+			lastCollectErr:  errors.New("collect failed"),
+		}
+		h := NewHealthHandler(ctx, NewHealth(false), provider)
+
+		code, resp := doProbeRequest(h, "/healthz")
+		So(code, ShouldEqual, http.StatusOK)
+		So(resp.LastCollect, ShouldNotBeNil)
+		So(resp.LastCollect.Error, ShouldEqual, "collect failed")
+
+		code, resp = doProbeRequest(h, "/readyz")
+		So(code, ShouldEqual, http.StatusOK)
+		So(resp.Checks["grpc"].Message, ShouldEqual, "GRPC server is not used")
+	})
+}
@@ -0,0 +1,101 @@
+//go:build medium
+// +build medium
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func recordingInterceptor(name string, calls *[]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*calls = append(*calls, name+":"+info.FullMethod)
+		return handler(ctx, req)
+	}
+}
+
+func TestGRPCServerHooks(t *testing.T) {
+	Convey("Validate that interceptors defined by plugin author are called", t, func() {
+		var calls []string
+
+		hooks := ServerHooks{
+			UnaryInterceptors: []grpc.UnaryServerInterceptor{recordingInterceptor("first", &calls), recordingInterceptor("second", &calls)},
+			ServerOptions:     []grpc.ServerOption{grpc.MaxRecvMsgSize(1024)},
+		}
+
+		expectedCalls := []string{
+			"first:/pluginrpc.Controller/Ping",
+			"second:/pluginrpc.Controller/Ping",
+		}
+
+		Convey("when GRPC server is served on TCP", func() {
+			// Arrange
+			initRoutinesNo := runtime.NumGoroutine()
+			ln, _ := net.Listen("tcp", "127.0.0.1:")
+
+			srv, err := NewGRPCServer(context.Background(), &plugin.Options{}, hooks)
+			So(err, ShouldBeNil)
+			pluginrpc.RegisterControllerServer(srv.(*grpc.Server), &controlMock{closeCh: make(chan bool)})
+
+			go func() {
+				_ = srv.Serve(ln)
+			}()
+
+			conn, dialErr := grpc.Dial(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			So(dialErr, ShouldBeNil)
+
+			// Act
+			_, errPing := pluginrpc.NewControllerClient(conn).Ping(context.Background(), &pluginrpc.PingRequest{})
+
+			// Assert
+			So(errPing, ShouldBeNil)
+			So(calls, ShouldResemble, expectedCalls)
+
+			_ = conn.Close()
+			srv.Stop()
+			waitForRoutines(initRoutinesNo)
+		})
+
+		Convey("when plugin is run in-process", func() {
+			// Arrange
+			srv, err := NewGRPCServer(context.Background(), &plugin.Options{AsThread: true}, hooks)
+			So(err, ShouldBeNil)
+
+			ch := srv.(*Channel)
+			ch.SetHandlersExpectations(-1) // only controller service is registered
+			pluginrpc.RegisterHandlerController(ch, &controlMock{closeCh: make(chan bool)})
+
+			// Act
+			_, errPing := pluginrpc.NewControllerChannelClient(ch.Channel).Ping(context.Background(), &pluginrpc.PingRequest{})
+
+			// Assert
+			So(errPing, ShouldBeNil)
+			So(calls, ShouldResemble, expectedCalls)
+		})
+	})
+}
@@ -16,17 +16,27 @@
 
 package service
 
-import "github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+import (
+	"context"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+)
 
 type CollectorProxy interface {
-	RequestCollect(id string) <-chan types.CollectChunk
-	LoadTask(id string, rawConfig []byte, mtsSelectors []string) error
-	UnloadTask(id string) error
-	CustomInfo(id string) ([]byte, error)
+	RequestCollect(ctx context.Context, id string) <-chan types.CollectChunk
+	LoadTask(ctx context.Context, id string, rawConfig []byte, mtsSelectors []string) error
+	UnloadTask(ctx context.Context, id string) error
+	CustomInfo(ctx context.Context, id string) ([]byte, error)
+
+	TaskIDs() []string
+	WaitForActiveTasks(ctx context.Context) error
 }
 type PublisherProxy interface {
-	RequestPublish(id string, mts []*types.Metric) types.ProcessingStatus
-	LoadTask(id string, config []byte) error
-	UnloadTask(id string) error
-	CustomInfo(id string) ([]byte, error)
+	RequestPublish(ctx context.Context, id string, mts []*types.Metric) types.ProcessingStatus
+	LoadTask(ctx context.Context, id string, config []byte) error
+	UnloadTask(ctx context.Context, id string) error
+	CustomInfo(ctx context.Context, id string) ([]byte, error)
+
+	TaskIDs() []string
+	WaitForActiveTasks(ctx context.Context) error
 }
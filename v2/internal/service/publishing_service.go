@@ -75,7 +75,7 @@ func (ps *publishingService) Publish(stream pluginrpc.Publisher_PublishServer) e
 	if len(mts) != 0 {
 		logF.WithField("length", len(mts)).Debug("metric will be published")
 
-		status := ps.proxy.RequestPublish(id, mts)
+		status := ps.proxy.RequestPublish(stream.Context(), id, mts)
 
 		protoWarnings := make([]*pluginrpc.Warning, 0, len(status.Warnings))
 		for _, w := range status.Warnings {
@@ -101,7 +101,7 @@ func (ps *publishingService) Load(ctx context.Context, request *pluginrpc.LoadPu
 	taskID := string(request.GetTaskId())
 	jsonConfig := request.GetJsonConfig()
 
-	return &pluginrpc.LoadPublisherResponse{}, ps.proxy.LoadTask(taskID, jsonConfig)
+	return &pluginrpc.LoadPublisherResponse{}, ps.proxy.LoadTask(ctx, taskID, jsonConfig)
 }
 
 func (ps *publishingService) Unload(ctx context.Context, request *pluginrpc.UnloadPublisherRequest) (*pluginrpc.UnloadPublisherResponse, error) {
@@ -109,7 +109,7 @@ func (ps *publishingService) Unload(ctx context.Context, request *pluginrpc.Unlo
 
 	taskID := string(request.GetTaskId())
 
-	return &pluginrpc.UnloadPublisherResponse{}, ps.proxy.UnloadTask(taskID)
+	return &pluginrpc.UnloadPublisherResponse{}, ps.proxy.UnloadTask(ctx, taskID)
 }
 
 func (ps *publishingService) Info(ctx context.Context, request *pluginrpc.InfoRequest) (*pluginrpc.InfoResponse, error) {
@@ -117,7 +117,7 @@ func (ps *publishingService) Info(ctx context.Context, request *pluginrpc.InfoRe
 
 	taskID := request.GetTaskId()
 
-	cInfo, err := ps.proxy.CustomInfo(taskID)
+	cInfo, err := ps.proxy.CustomInfo(ctx, taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +34,7 @@ import (
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 const GRPCGracefulStopTimeout = 10 * time.Second
@@ -53,39 +54,112 @@ type Server interface {
 // * the native go-grpc implementation
 // * https://github.com/solarwinds/grpchan - this one provides a way of using gRPC with a custom transport
 //   (that means sth other than the native h2 - HTTP1.1 or inprocess/channels are available out of the box)
-func NewGRPCServer(ctx context.Context, opt *plugin.Options) (Server, error) {
+func NewGRPCServer(ctx context.Context, opt *plugin.Options, hooks ServerHooks) (Server, error) {
 	if opt.AsThread {
-		return NewChannel(), nil
+		ch := NewChannel()
+		ch.Channel = ch.Channel.
+			WithServerUnaryInterceptor(chainUnaryInterceptors(
+				append([]grpc.UnaryServerInterceptor{tracingUnaryInterceptor}, hooks.UnaryInterceptors...))).
+			WithServerStreamInterceptor(chainStreamInterceptors(
+				append([]grpc.StreamServerInterceptor{tracingStreamInterceptor}, hooks.StreamInterceptors...)))
+
+		return ch, nil
 	}
 
-	if !opt.EnableTLS {
-		return grpc.NewServer(), nil
-	}
+	var srvOpts []grpc.ServerOption
 
-	tlsCreds, err := tlsCredentials(ctx, opt)
+	token, err := authToken(opt)
 	if err != nil {
 		return nil, err
 	}
 
-	return grpc.NewServer(grpc.Creds(tlsCreds)), nil
+	if token != "" {
+		// authentication interceptors have to be called before any other
+		srvOpts = append(srvOpts,
+			grpc.ChainUnaryInterceptor(authUnaryInterceptor(token)),
+			grpc.ChainStreamInterceptor(authStreamInterceptor(token)))
+	}
+
+	srvOpts = append(srvOpts,
+		grpc.ChainUnaryInterceptor(tracingUnaryInterceptor),
+		grpc.ChainStreamInterceptor(tracingStreamInterceptor))
+
+	srvOpts = append(srvOpts,
+		grpc.ChainUnaryInterceptor(hooks.UnaryInterceptors...),
+		grpc.ChainStreamInterceptor(hooks.StreamInterceptors...))
+
+	srvOpts = append(srvOpts, serverOptions(opt)...)
+
+	if opt.EnableTLS {
+		tlsCreds, err := tlsCredentials(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		srvOpts = append(srvOpts, grpc.Creds(tlsCreds))
+	}
+
+	// options provided by plugin author are applied at the end, so they may override library defaults
+	srvOpts = append(srvOpts, hooks.ServerOptions...)
+
+	return grpc.NewServer(srvOpts...), nil
 }
 
-func StartCollectorGRPC(ctx context.Context, srv Server, proxy CollectorProxy, grpcLn net.Listener, pingTimeout time.Duration, pingMaxMissedCount uint, collectChunkSize uint64) {
+func serverOptions(opt *plugin.Options) []grpc.ServerOption {
+	var srvOpts []grpc.ServerOption
+
+	if opt.GRPCCompression != CompressionNone {
+		srvOpts = append(srvOpts,
+			grpc.ChainUnaryInterceptor(compressionUnaryInterceptor(opt.GRPCCompression)),
+			grpc.ChainStreamInterceptor(compressionStreamInterceptor(opt.GRPCCompression)))
+	}
+
+	if opt.GRPCMaxRecvMsgSize > 0 {
+		srvOpts = append(srvOpts, grpc.MaxRecvMsgSize(opt.GRPCMaxRecvMsgSize))
+	}
+
+	if opt.GRPCMaxSendMsgSize > 0 {
+		srvOpts = append(srvOpts, grpc.MaxSendMsgSize(opt.GRPCMaxSendMsgSize))
+	}
+
+	if opt.GRPCKeepaliveTime > 0 || opt.GRPCKeepaliveTimeout > 0 {
+		srvOpts = append(srvOpts, grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    opt.GRPCKeepaliveTime,
+			Timeout: opt.GRPCKeepaliveTimeout,
+		}))
+	}
+
+	if opt.GRPCKeepaliveMinTime > 0 || opt.GRPCKeepalivePermitWithoutStream {
+		srvOpts = append(srvOpts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             opt.GRPCKeepaliveMinTime,
+			PermitWithoutStream: opt.GRPCKeepalivePermitWithoutStream,
+		}))
+	}
+
+	return srvOpts
+}
+
+func StartCollectorGRPC(ctx context.Context, srv Server, proxy CollectorProxy, grpcLn net.Listener, pingTimeout time.Duration, pingMaxMissedCount uint, collectChunkSize uint64, shutdownTimeout time.Duration, health *Health) error {
 	pluginrpc.RegisterHandlerCollector(srv, newCollectService(ctx, proxy, collectChunkSize))
-	startGRPC(ctx, srv, grpcLn, pingTimeout, pingMaxMissedCount)
+	return startGRPC(ctx, srv, grpcLn, proxy, pingTimeout, pingMaxMissedCount, shutdownTimeout, health)
 }
 
-func StartPublisherGRPC(ctx context.Context, srv Server, proxy PublisherProxy, grpcLn net.Listener, pingTimeout time.Duration, pingMaxMissedCount uint) {
+func StartPublisherGRPC(ctx context.Context, srv Server, proxy PublisherProxy, grpcLn net.Listener, pingTimeout time.Duration, pingMaxMissedCount uint, shutdownTimeout time.Duration, health *Health) error {
 	pluginrpc.RegisterHandlerPublisher(srv, newPublishingService(ctx, proxy))
-	startGRPC(ctx, srv, grpcLn, pingTimeout, pingMaxMissedCount)
+	return startGRPC(ctx, srv, grpcLn, proxy, pingTimeout, pingMaxMissedCount, shutdownTimeout, health)
 }
 
-func startGRPC(ctx context.Context, srv Server, grpcLn net.Listener, pingTimeout time.Duration, pingMaxMissedCount uint) {
+// startGRPC serves requests until Kill is requested, major error occurs or ctx is done.
+// In the last case all tasks are unloaded before server is stopped (graceful shutdown).
+func startGRPC(ctx context.Context, srv Server, grpcLn net.Listener, tm taskManager, pingTimeout time.Duration, pingMaxMissedCount uint, shutdownTimeout time.Duration, health *Health) error {
 	logF := log.WithCtx(ctx).WithFields(moduleFields)
 	errChan := make(chan error)
 
 	csCtx, cancelFn := context.WithCancel(ctx)
-	pluginrpc.RegisterHandlerController(srv, newControlService(csCtx, errChan, pingTimeout, pingMaxMissedCount))
+	pluginrpc.RegisterHandlerController(srv, newControlService(csCtx, errChan, pingTimeout, pingMaxMissedCount, health))
+
+	// listener is already open, so connections are accepted (queued) from now on
+	health.setServing(true)
 
 	go func() {
 		err := srv.Serve(grpcLn) // may be blocking (depending on implementation)
@@ -94,26 +168,48 @@ func startGRPC(ctx context.Context, srv Server, grpcLn net.Listener, pingTimeout
 		}
 	}()
 
-	err := <-errChan // may be blocking (depending on implementation)
-	cancelFn()       // signal ping monitor (via ctx)
-
-	if err != nil && err != RequestedKillError {
+	var err error
+	select {
+	case err = <-errChan: // may be blocking (depending on implementation)
+	case <-ctx.Done():
+		err = RequestedShutdownError
+	}
+	cancelFn() // signal ping monitor (via ctx)
+	health.setServing(false)
+
+	switch err {
+	case RequestedKillError:
+		shutdownPlugin(ctx, srv, nil, 0)
+		return nil
+	case RequestedShutdownError:
+		logF.Info("Shutdown requested - completing requests and unloading tasks")
+		return shutdownPlugin(ctx, srv, tm, shutdownTimeout)
+	default:
 		logF.WithError(err).Errorf("Major error occurred - plugin will be shut down")
+		shutdownPlugin(ctx, srv, nil, 0)
+		return err
 	}
-
-	shutdownPlugin(ctx, srv)
 }
 
-func shutdownPlugin(ctx context.Context, srv Server) {
+func shutdownPlugin(ctx context.Context, srv Server, tm taskManager, shutdownTimeout time.Duration) error {
 	logF := log.WithCtx(ctx).WithFields(moduleFields)
 	stopped := make(chan bool, 1)
 
-	// try to complete all remaining rpc calls
+	// try to complete all remaining rpc calls (new ones are rejected)
 	go func() {
 		srv.GracefulStop()
 		stopped <- true
 	}()
 
+	// unloading cancels requests which are still in progress (ie. streaming collect), so server is able to stop
+	var err error
+	if tm != nil {
+		err = unloadAllTasks(ctx, tm, shutdownTimeout)
+		if err != nil {
+			logF.WithError(err).Warn("Tasks couldn't have been unloaded")
+		}
+	}
+
 	// If RPC calls lasting too much, stop server by force
 	select {
 	case <-stopped:
@@ -122,4 +218,6 @@ func shutdownPlugin(ctx context.Context, srv Server) {
 		srv.Stop()
 		logF.Warn("GRPC server couldn't have been stopped gracefully. Some metrics might have been lost")
 	}
+
+	return err
 }
@@ -0,0 +1,87 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+)
+
+const DefaultShutdownTimeout = 10 * time.Second
+
+var RequestedShutdownError = errors.New("shutdown requested")
+
+// taskManager is implemented by both collector and publisher proxies
+type taskManager interface {
+	TaskIDs() []string
+	WaitForActiveTasks(ctx context.Context) error
+	UnloadTask(ctx context.Context, id string) error
+}
+
+// unloadAllTasks waits for in-flight requests to complete and unloads all tasks (so user Unload hooks are called).
+// Requests still being processed after half of timeout are cancelled (by unloading), the rest of time is left for unload.
+func unloadAllTasks(ctx context.Context, tm taskManager, timeout time.Duration) error {
+	logF := log.WithCtx(ctx).WithFields(moduleFields)
+
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	deadlineCtx, cancelFn := context.WithTimeout(context.Background(), timeout)
+	defer cancelFn()
+
+	drainCtx, drainCancelFn := context.WithTimeout(deadlineCtx, timeout/2)
+	defer drainCancelFn()
+
+	err := tm.WaitForActiveTasks(drainCtx)
+	if err != nil {
+		logF.WithError(err).Warn("Not all requests have been completed before shutdown, they will be cancelled")
+	}
+
+	taskIDs := tm.TaskIDs()
+	errCh := make(chan error, len(taskIDs))
+
+	for _, id := range taskIDs {
+		go func(id string) {
+			errCh <- tm.UnloadTask(deadlineCtx, id)
+		}(id)
+	}
+
+	var unloadErrs []error
+	for range taskIDs {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				unloadErrs = append(unloadErrs, err)
+			}
+		case <-deadlineCtx.Done():
+			return fmt.Errorf("tasks haven't been unloaded within %s", timeout)
+		}
+	}
+
+	if len(unloadErrs) > 0 {
+		return fmt.Errorf("some tasks couldn't have been unloaded: %v", errors.Join(unloadErrs...))
+	}
+
+	logF.WithField("tasks", len(taskIDs)).Debug("All tasks have been unloaded")
+
+	return nil
+}
@@ -0,0 +1,115 @@
+//go:build medium
+// +build medium
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type taskManagerMock struct {
+	mu       sync.Mutex
+	tasks    map[string]error // task id -> error returned by unload
+	busy     bool             // request is being processed (and won't complete)
+	unloadFn func()
+}
+
+func (tm *taskManagerMock) TaskIDs() []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	var ids []string
+	for id := range tm.tasks {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func (tm *taskManagerMock) WaitForActiveTasks(ctx context.Context) error {
+	if tm.busy {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func (tm *taskManagerMock) UnloadTask(_ context.Context, id string) error {
+	if tm.unloadFn != nil {
+		tm.unloadFn()
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	err := tm.tasks[id]
+	delete(tm.tasks, id)
+
+	return err
+}
+
+func TestUnloadAllTasks(t *testing.T) {
+	Convey("Validate that all tasks are unloaded when plugin is shutting down", t, func() {
+		Convey("when all tasks can be unloaded", func() {
+			tm := &taskManagerMock{tasks: map[string]error{"task-1": nil, "task-2": nil}}
+
+			err := unloadAllTasks(context.Background(), tm, time.Second)
+
+			So(err, ShouldBeNil)
+			So(tm.TaskIDs(), ShouldBeEmpty)
+		})
+
+		Convey("when requests are not completed in time, tasks are unloaded anyway", func() {
+			tm := &taskManagerMock{tasks: map[string]error{"task-1": nil}, busy: true}
+
+			err := unloadAllTasks(context.Background(), tm, 200*time.Millisecond)
+
+			So(err, ShouldBeNil)
+			So(tm.TaskIDs(), ShouldBeEmpty)
+		})
+
+		Convey("when one of tasks can't be unloaded", func() {
+			tm := &taskManagerMock{tasks: map[string]error{"task-1": nil, "task-2": fmt.Errorf("connection reset")}}
+
+			err := unloadAllTasks(context.Background(), tm, time.Second)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "connection reset")
+		})
+
+		Convey("when unloading lasts too long", func() {
+			tm := &taskManagerMock{
+				tasks:    map[string]error{"task-1": nil},
+				unloadFn: func() { time.Sleep(time.Second) },
+			}
+
+			err := unloadAllTasks(context.Background(), tm, 100*time.Millisecond)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "haven't been unloaded")
+		})
+	})
+}
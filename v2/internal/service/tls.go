@@ -23,58 +23,311 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 	"google.golang.org/grpc/credentials"
 )
 
+// ALPN protocol required by GRPC
+const http2Proto = "h2"
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
 func tlsCredentials(ctx context.Context, opt *plugin.Options) (credentials.TransportCredentials, error) {
-	cert, err := tls.LoadX509KeyPair(opt.TLSServerCertPath, opt.TLSServerKeyPath)
+	minVersion, err := ParseTLSVersion(opt.TLSMinVersion)
 	if err != nil {
-		return nil, fmt.Errorf("invalid TLS certificate: %v", err)
+		return nil, err
 	}
 
-	clientCA, err := loadCACerts(ctx, opt.TLSClientCAPath)
+	cipherSuites, err := ParseCipherSuites(opt.TLSCipherSuites)
 	if err != nil {
-		return nil, fmt.Errorf("can't read client CA Cert(s)")
+		return nil, err
+	}
+
+	r := &tlsReloader{
+		ctx:          ctx,
+		certPath:     opt.TLSServerCertPath,
+		keyPath:      opt.TLSServerKeyPath,
+		clientCAPath: opt.TLSClientCAPath,
+	}
+
+	err = r.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.TLSReloadInterval > 0 {
+		go r.watch(opt.TLSReloadInterval)
+	}
+
+	// client certificates are required only when CAs to verify them are provided
+	clientAuth := tls.NoClientCert
+	if opt.TLSClientCAPath != "" {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	// configuration returned for each connection replaces the one prepared by credentials.NewTLS,
+	// so it has to announce HTTP/2 (ALPN) by itself
+	connConfig := &tls.Config{
+		ClientAuth:            clientAuth,
+		MinVersion:            minVersion,
+		CipherSuites:          cipherSuites,
+		VerifyPeerCertificate: clientSANsVerifier(splitList(opt.TLSClientSANs)),
+		NextProtos:            []string{http2Proto},
 	}
 
 	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		ClientCAs:    clientCA,
-		MinVersion:   tls.VersionTLS12,
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+
+		// certificates are provided for each connection, so the most recent ones (after reload) are used
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cfg := connConfig.Clone()
+			cfg.GetCertificate = r.certificate
+			cfg.ClientCAs = r.clientCAs()
+
+			return cfg, nil
+		},
 	}
 	tlsCreds := credentials.NewTLS(tlsConfig)
 
 	return tlsCreds, nil
 }
 
-func loadCACerts(ctx context.Context, caPath string) (*x509.CertPool, error) {
-	logF := log.WithCtx(ctx).WithFields(moduleFields)
+func ParseTLSVersion(v string) (uint16, error) {
+	if v == "" {
+		return tls.VersionTLS12, nil
+	}
 
-	clientCA := x509.NewCertPool()
-	certFilesToProcess := []string{}
+	version, ok := tlsVersions[v]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version: %s", v)
+	}
 
-	// gather list of files to read
-	for _, p := range filepath.SplitList(caPath) {
-		info, err := os.Stat(p)
+	return version, nil
+}
+
+// ParseCipherSuites converts list of cipher suites names (separated by comma) to their identifiers.
+// Empty list means default cipher suites. Only secure cipher suites are accepted.
+func ParseCipherSuites(names string) ([]uint16, error) {
+	var ids []uint16
+
+	for _, name := range splitList(names) {
+		found := false
+		for _, cs := range tls.CipherSuites() {
+			if cs.Name == name {
+				ids = append(ids, cs.ID)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unsupported cipher suite: %s", name)
+		}
+	}
+
+	return ids, nil
+}
+
+func splitList(s string) []string {
+	var result []string
+	for _, el := range strings.Split(s, ",") {
+		if el = strings.TrimSpace(el); el != "" {
+			result = append(result, el)
+		}
+	}
+
+	return result
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// tlsReloader keeps server certificate and client CAs loaded from files and reloads them when files are modified
+type tlsReloader struct {
+	ctx          context.Context
+	certPath     string
+	keyPath      string
+	clientCAPath string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time // modification time of each file read during last reload
+}
+
+func (r *tlsReloader) certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+func (r *tlsReloader) clientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.clientCA
+}
+
+func (r *tlsReloader) reload() error {
+	modTimes, err := r.filesModTimes()
+	if err != nil {
+		return fmt.Errorf("can't read TLS files: %v", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("invalid TLS certificate: %v", err)
+	}
+
+	clientCA, err := loadCACerts(r.ctx, r.clientCAPath)
+	if err != nil {
+		return fmt.Errorf("can't read client CA Cert(s)")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cert = &cert
+	r.clientCA = clientCA
+	r.modTimes = modTimes
+
+	return nil
+}
+
+// watch periodically checks if certificate, key or CA files were modified and reloads them.
+// When new files are invalid, previous ones are used.
+func (r *tlsReloader) watch(interval time.Duration) {
+	logF := log.WithCtx(r.ctx).WithFields(moduleFields).WithField("service", "tls")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.modified() {
+				continue
+			}
+
+			err := r.reload()
+			if err != nil {
+				logF.WithError(err).Warn("Can't reload TLS certificates, previous ones will be used")
+				continue
+			}
+
+			logF.Info("TLS certificates have been reloaded")
+		}
+	}
+}
+
+func (r *tlsReloader) modified() bool {
+	modTimes, err := r.filesModTimes()
+	if err != nil {
+		return true // reload will report an error
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(modTimes) != len(r.modTimes) {
+		return true
+	}
+
+	for f, t := range modTimes {
+		if prevT, ok := r.modTimes[f]; !ok || !prevT.Equal(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *tlsReloader) filesModTimes() (map[string]time.Time, error) {
+	caFiles, err := listCAFiles(r.clientCAPath)
+	if err != nil {
+		return nil, err
+	}
+
+	modTimes := map[string]time.Time{}
+	for _, f := range append([]string{r.certPath, r.keyPath}, caFiles...) {
+		info, err := os.Stat(f)
 		if err != nil {
-			return clientCA, fmt.Errorf("path doesn't point to any file or directory (%v): %v", p, err)
+			return nil, err
 		}
 
-		if !info.IsDir() {
-			certFilesToProcess = append(certFilesToProcess, p)
-			continue
+		modTimes[f] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// clientSANsVerifier returns function validating that client certificate contains at least one of allowed
+// Subject Alternative Names (DNS names, IP addresses, URIs or emails). DNS names might contain wildcard ("*.example.com").
+func clientSANsVerifier(allowedSANs []string) func([][]byte, [][]*x509.Certificate) error {
+	if len(allowedSANs) == 0 {
+		return nil
+	}
+
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+			return fmt.Errorf("client certificate wasn't verified")
 		}
 
-		_ = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
-			if !info.IsDir() {
-				certFilesToProcess = append(certFilesToProcess, path)
+		cert := verifiedChains[0][0]
+
+		var sans []string
+		sans = append(sans, cert.DNSNames...)
+		sans = append(sans, cert.EmailAddresses...)
+		for _, ip := range cert.IPAddresses {
+			sans = append(sans, ip.String())
+		}
+		for _, uri := range cert.URIs {
+			sans = append(sans, uri.String())
+		}
+
+		for _, allowed := range allowedSANs {
+			for _, san := range sans {
+				if matchSAN(allowed, san) {
+					return nil
+				}
 			}
-			return nil
-		})
+		}
+
+		return fmt.Errorf("client certificate SANs %v don't match any of allowed ones", sans)
+	}
+}
+
+func matchSAN(pattern, san string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		suffix := strings.ToLower(pattern[1:])
+		san = strings.ToLower(san)
+		return strings.HasSuffix(san, suffix) && !strings.Contains(strings.TrimSuffix(san, suffix), ".")
+	}
+
+	return strings.EqualFold(pattern, san)
+}
+
+func loadCACerts(ctx context.Context, caPath string) (*x509.CertPool, error) {
+	logF := log.WithCtx(ctx).WithFields(moduleFields)
+
+	clientCA := x509.NewCertPool()
+
+	certFilesToProcess, err := listCAFiles(caPath)
+	if err != nil {
+		return clientCA, err
 	}
 
 	// read certificate files
@@ -91,3 +344,29 @@ func loadCACerts(ctx context.Context, caPath string) (*x509.CertPool, error) {
 
 	return clientCA, nil
 }
+
+// gather list of files to read (caPath might contain files or/and dirs)
+func listCAFiles(caPath string) ([]string, error) {
+	certFilesToProcess := []string{}
+
+	for _, p := range filepath.SplitList(caPath) {
+		info, err := os.Stat(p)
+		if err != nil {
+			return certFilesToProcess, fmt.Errorf("path doesn't point to any file or directory (%v): %v", p, err)
+		}
+
+		if !info.IsDir() {
+			certFilesToProcess = append(certFilesToProcess, p)
+			continue
+		}
+
+		_ = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				certFilesToProcess = append(certFilesToProcess, path)
+			}
+			return nil
+		})
+	}
+
+	return certFilesToProcess, nil
+}
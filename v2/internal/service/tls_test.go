@@ -22,6 +22,7 @@ package service
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
@@ -78,7 +79,7 @@ func TestConnectingToSecureGRPC(t *testing.T) {
 
 		// Arrange (GRPC Server)
 		go func() {
-			srv, _ := NewGRPCServer(context.Background(), opt)
+			srv, _ := NewGRPCServer(context.Background(), opt, ServerHooks{})
 			pluginrpc.RegisterControllerServer(srv.(*grpc.Server), controlService)
 
 			go func() {
@@ -125,3 +126,205 @@ func TestConnectingToSecureGRPC(t *testing.T) {
 		}
 	})
 }
+
+func startSecureGRPC(opt *plugin.Options) (string, func()) {
+	ln, _ := net.Listen("tcp", "localhost:")
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	srv, err := NewGRPCServer(ctx, opt, ServerHooks{})
+	So(err, ShouldBeNil)
+	pluginrpc.RegisterControllerServer(srv.(*grpc.Server), &controlMock{closeCh: make(chan bool)})
+
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+
+	return ln.Addr().String(), func() {
+		srv.Stop()
+		cancelFn()
+	}
+}
+
+func pingSecureGRPC(addr string) error {
+	caCert, _ := os.ReadFile(filepath.Join(certificateFolderName, "ca.crt"))
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(caCert)
+
+	cert, _ := tls.LoadX509KeyPair(filepath.Join(certificateFolderName, "cli.crt"), filepath.Join(certificateFolderName, "cli.key"))
+
+	creds := credentials.NewTLS(&tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{cert},
+		ServerName:   "localhost",
+	})
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), tlsTestTimeout)
+	defer cancelFn()
+
+	_, err = pluginrpc.NewControllerClient(conn).Ping(ctx, &pluginrpc.PingRequest{})
+	return err
+}
+
+func copyFile(src, dst string) {
+	content, err := os.ReadFile(src)
+	So(err, ShouldBeNil)
+	So(os.WriteFile(dst, content, 0600), ShouldBeNil)
+}
+
+func TestReloadingCertificates(t *testing.T) {
+	Convey("Validate that client CAs are reloaded when files are modified", t, func() {
+		// Arrange
+		caPath := filepath.Join(t.TempDir(), "ca.crt")
+		copyFile(filepath.Join(certificateFolderName, "serv.crt"), caPath) // certificate which doesn't sign client one
+
+		opt := &plugin.Options{
+			EnableTLS:         true,
+			TLSServerKeyPath:  filepath.Join(certificateFolderName, "serv.key"),
+			TLSServerCertPath: filepath.Join(certificateFolderName, "serv.crt"),
+			TLSClientCAPath:   caPath,
+			TLSReloadInterval: 50 * time.Millisecond,
+		}
+
+		addr, stopFn := startSecureGRPC(opt)
+		defer stopFn()
+
+		// Act & Assert
+		So(pingSecureGRPC(addr), ShouldBeError)
+
+		copyFile(filepath.Join(certificateFolderName, "ca.crt"), caPath)
+		So(os.Chtimes(caPath, time.Now(), time.Now().Add(time.Second)), ShouldBeNil)
+		time.Sleep(200 * time.Millisecond)
+
+		So(pingSecureGRPC(addr), ShouldBeNil)
+	})
+}
+
+func TestRestrictingClientSANs(t *testing.T) {
+	testCases := []struct {
+		allowedSANs string
+		shouldPass  bool
+	}{
+		{"", true},
+		{"localhost", true},
+		{"agent.example.com, localhost", true},
+		{"agent.example.com,*.example.com", false},
+	}
+
+	Convey("Validate that only clients with allowed SANs can connect to GRPC Server", t, func() {
+		for _, tc := range testCases {
+			Convey(fmt.Sprintf("Scenario: allowed SANs=%q", tc.allowedSANs), func() {
+				// Arrange
+				opt := &plugin.Options{
+					EnableTLS:         true,
+					TLSServerKeyPath:  filepath.Join(certificateFolderName, "serv.key"),
+					TLSServerCertPath: filepath.Join(certificateFolderName, "serv.crt"),
+					TLSClientCAPath:   filepath.Join(certificateFolderName, "ca.crt"),
+					TLSMinVersion:     "1.3",
+					TLSClientSANs:     tc.allowedSANs,
+				}
+
+				addr, stopFn := startSecureGRPC(opt)
+				defer stopFn()
+
+				// Act
+				err := pingSecureGRPC(addr)
+
+				// Assert
+				if tc.shouldPass {
+					So(err, ShouldBeNil)
+				} else {
+					So(err, ShouldBeError)
+				}
+			})
+		}
+	})
+}
+
+func TestNegotiatingHTTP2(t *testing.T) {
+	Convey("Validate that secure GRPC Server negotiates HTTP/2 with ALPN", t, func() {
+		// Arrange
+		addr, stopFn := startSecureGRPC(&plugin.Options{
+			EnableTLS:         true,
+			TLSServerKeyPath:  filepath.Join(certificateFolderName, "serv.key"),
+			TLSServerCertPath: filepath.Join(certificateFolderName, "serv.crt"),
+			TLSClientCAPath:   filepath.Join(certificateFolderName, "ca.crt"),
+		})
+		defer stopFn()
+
+		caCert, _ := os.ReadFile(filepath.Join(certificateFolderName, "ca.crt"))
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM(caCert)
+
+		cert, _ := tls.LoadX509KeyPair(filepath.Join(certificateFolderName, "cli.crt"), filepath.Join(certificateFolderName, "cli.key"))
+
+		// Act
+		conn, err := tls.Dial("tcp", addr, &tls.Config{
+			RootCAs:      certPool,
+			Certificates: []tls.Certificate{cert},
+			ServerName:   "localhost",
+			NextProtos:   []string{"h2"},
+		})
+
+		// Assert
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		So(conn.ConnectionState().NegotiatedProtocol, ShouldEqual, "h2")
+	})
+}
+
+func TestConnectingWithoutClientCertificate(t *testing.T) {
+	testCases := []struct {
+		clientCAPath string
+		shouldPass   bool
+	}{
+		{"", true},
+		{filepath.Join(certificateFolderName, "ca.crt"), false},
+	}
+
+	Convey("Validate that client certificate is required only when client CAs are provided", t, func() {
+		for _, tc := range testCases {
+			Convey(fmt.Sprintf("Scenario: client CA path=%q", tc.clientCAPath), func() {
+				// Arrange
+				addr, stopFn := startSecureGRPC(&plugin.Options{
+					EnableTLS:         true,
+					TLSServerKeyPath:  filepath.Join(certificateFolderName, "serv.key"),
+					TLSServerCertPath: filepath.Join(certificateFolderName, "serv.crt"),
+					TLSClientCAPath:   tc.clientCAPath,
+				})
+				defer stopFn()
+
+				caCert, _ := os.ReadFile(filepath.Join(certificateFolderName, "ca.crt"))
+				certPool := x509.NewCertPool()
+				certPool.AppendCertsFromPEM(caCert)
+
+				creds := credentials.NewTLS(&tls.Config{
+					RootCAs:    certPool,
+					ServerName: "localhost",
+				})
+
+				conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+				So(err, ShouldBeNil)
+				defer conn.Close()
+
+				ctx, cancelFn := context.WithTimeout(context.Background(), tlsTestTimeout)
+				defer cancelFn()
+
+				// Act
+				_, err = pluginrpc.NewControllerClient(conn).Ping(ctx, &pluginrpc.PingRequest{})
+
+				// Assert
+				if tc.shouldPass {
+					So(err, ShouldBeNil)
+				} else {
+					So(err, ShouldBeError)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,49 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/tracing"
+)
+
+// Trace context sent by snap in request metadata is extracted, so spans created by library (and plugin author)
+// are children of the span representing request on the agent side.
+
+func tracingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(tracing.ExtractFromMetadata(ctx), req)
+}
+
+func tracingStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &tracedServerStream{
+		ServerStream: ss,
+		ctx:          tracing.ExtractFromMetadata(ss.Context()),
+	})
+}
+
+// tracedServerStream overrides context of a stream (the only way to pass values to the stream handler)
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
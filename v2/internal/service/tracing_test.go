@@ -0,0 +1,120 @@
+//go:build medium
+// +build medium
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/tracing"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func spanContextInterceptor(sc *trace.SpanContext) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*sc = trace.SpanContextFromContext(ctx)
+		return handler(ctx, req)
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	Convey("Validate that trace context sent by client is available to request handlers", t, func() {
+		var receivedSC trace.SpanContext
+
+		hooks := ServerHooks{
+			UnaryInterceptors: []grpc.UnaryServerInterceptor{spanContextInterceptor(&receivedSC)},
+		}
+
+		tp := sdktrace.NewTracerProvider()
+		reqCtx, span := tp.Tracer("client").Start(context.Background(), "request")
+		defer span.End()
+
+		reqCtx = tracing.InjectToMetadata(reqCtx)
+
+		Convey("when GRPC server is served on TCP", func() {
+			// Arrange
+			ln, _ := net.Listen("tcp", "127.0.0.1:")
+
+			srv, err := NewGRPCServer(context.Background(), &plugin.Options{}, hooks)
+			So(err, ShouldBeNil)
+			pluginrpc.RegisterControllerServer(srv.(*grpc.Server), &controlMock{closeCh: make(chan bool)})
+
+			go func() {
+				_ = srv.Serve(ln)
+			}()
+
+			conn, dialErr := grpc.Dial(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			So(dialErr, ShouldBeNil)
+
+			// Act
+			_, errPing := pluginrpc.NewControllerClient(conn).Ping(reqCtx, &pluginrpc.PingRequest{})
+
+			// Assert
+			So(errPing, ShouldBeNil)
+			So(receivedSC.TraceID(), ShouldEqual, span.SpanContext().TraceID())
+			So(receivedSC.SpanID(), ShouldEqual, span.SpanContext().SpanID())
+			So(receivedSC.IsRemote(), ShouldBeTrue)
+
+			_ = conn.Close()
+			srv.Stop()
+		})
+
+		Convey("when plugin is run in-process", func() {
+			// Arrange
+			srv, err := NewGRPCServer(context.Background(), &plugin.Options{AsThread: true}, hooks)
+			So(err, ShouldBeNil)
+
+			ch := srv.(*Channel)
+			ch.SetHandlersExpectations(-1) // only controller service is registered
+			pluginrpc.RegisterHandlerController(ch, &controlMock{closeCh: make(chan bool)})
+
+			// Act
+			_, errPing := pluginrpc.NewControllerChannelClient(ch.Channel).Ping(reqCtx, &pluginrpc.PingRequest{})
+
+			// Assert
+			So(errPing, ShouldBeNil)
+			So(receivedSC.TraceID(), ShouldEqual, span.SpanContext().TraceID())
+		})
+
+		Convey("when client doesn't send trace context", func() {
+			// Arrange
+			srv, err := NewGRPCServer(context.Background(), &plugin.Options{AsThread: true}, hooks)
+			So(err, ShouldBeNil)
+
+			ch := srv.(*Channel)
+			ch.SetHandlersExpectations(-1)
+			pluginrpc.RegisterHandlerController(ch, &controlMock{closeCh: make(chan bool)})
+
+			// Act
+			_, errPing := pluginrpc.NewControllerChannelClient(ch.Channel).Ping(context.Background(), &pluginrpc.PingRequest{})
+
+			// Assert
+			So(errPing, ShouldBeNil)
+			So(receivedSC.IsValid(), ShouldBeFalse)
+		})
+	})
+}
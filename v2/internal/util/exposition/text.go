@@ -0,0 +1,366 @@
+/*
+Package exposition converts metrics gathered by collectors to Prometheus text exposition format.
+
+Metric name is built from static elements of namespace (joined with "_"), while dynamic elements and tags are exposed as labels.
+*/
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package exposition
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+// ContentType of the text exposition format (version 0.0.4)
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	TypeGauge     = "gauge"
+	TypeCounter   = "counter"
+	TypeSummary   = "summary"
+	TypeHistogram = "histogram"
+	TypeUntyped   = "untyped"
+)
+
+const nameSeparator = "_"
+
+// MetricName builds Prometheus metric name from static elements of namespace
+func MetricName(ns []types.NamespaceElement) string {
+	parts := make([]string, 0, len(ns))
+	for _, nsElem := range ns {
+		if nsElem.IsDynamic() {
+			continue
+		}
+		parts = append(parts, nsElem.Value_)
+	}
+
+	return sanitize(strings.Join(parts, nameSeparator), true)
+}
+
+// Labels returns labels of metric: dynamic elements of namespace (by their names) and tags.
+// Dynamic elements take precedence over tags with the same (sanitized) name.
+func Labels(mt *types.Metric) map[string]string {
+	labels := make(map[string]string, len(mt.Tags_))
+
+	for k, v := range mt.Tags_ {
+		labels[sanitize(k, false)] = v
+	}
+
+	for _, nsElem := range mt.Namespace_ {
+		if nsElem.IsDynamic() {
+			labels[sanitize(nsElem.Name_, false)] = nsElem.Value_
+		}
+	}
+
+	return labels
+}
+
+// TypeOf maps metric to Prometheus type. Summaries and histograms are recognized by value, as the only
+// representation of them in exposition format are separate samples (_sum, _count, _bucket).
+func TypeOf(mt *types.Metric) string {
+	switch mt.Value_.(type) {
+	case plugin.Summary, *plugin.Summary:
+		return TypeSummary
+	case plugin.Histogram, *plugin.Histogram:
+		return TypeHistogram
+	}
+
+	switch mt.Type_ {
+	case plugin.GaugeType:
+		return TypeGauge
+	case plugin.SumType:
+		return TypeCounter
+	default:
+		return TypeUntyped
+	}
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string
+	metrics []*types.Metric
+}
+
+// WriteText writes metrics in text exposition format. Metrics with the same name are grouped into one family
+// (described by HELP and TYPE of the first one). Metrics with values which can't be represented as numbers are omitted.
+// Metrics with the same name and labels as already written ones (ie. namespaces /a/b_c and /a_b/c) are omitted as well,
+// identifiers of such series are returned.
+func WriteText(w io.Writer, mts []*types.Metric) (duplicates []string, err error) {
+	families := map[string]*family{}
+	var order []string
+
+	for _, mt := range mts {
+		name := MetricName(mt.Namespace_)
+		if name == "" || !representable(mt) {
+			continue
+		}
+
+		f, ok := families[name]
+		if !ok {
+			f = &family{name: name, help: mt.Description_, typ: TypeOf(mt)}
+			families[name] = f
+			order = append(order, name)
+		}
+
+		f.metrics = append(f.metrics, mt)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, name := range order {
+		duplicates = append(duplicates, writeFamily(bw, families[name])...)
+	}
+
+	return duplicates, bw.Flush()
+}
+
+func writeFamily(w *bufio.Writer, f *family) (duplicates []string) {
+	if f.help != "" {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	}
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+	seen := make(map[string]struct{}, len(f.metrics))
+
+	for _, mt := range f.metrics {
+		if !compatible(f.typ, TypeOf(mt)) {
+			continue
+		}
+
+		labels := Labels(mt)
+
+		id := seriesID(f.name, labels)
+		if _, ok := seen[id]; ok {
+			duplicates = append(duplicates, id)
+			continue
+		}
+		seen[id] = struct{}{}
+
+		switch v := mt.Value_.(type) {
+		case plugin.Summary:
+			writeSummary(w, f.name, labels, &v)
+		case *plugin.Summary:
+			writeSummary(w, f.name, labels, v)
+		case plugin.Histogram:
+			writeHistogram(w, f.name, labels, &v)
+		case *plugin.Histogram:
+			writeHistogram(w, f.name, labels, v)
+		default:
+			fv, _ := ToFloat(mt.Value_)
+			writeSample(w, f.name, labels, "", "", fv)
+		}
+	}
+
+	return duplicates
+}
+
+// seriesID identifies series by metric name and labels (ie. name{label1="a",label2="b"})
+func seriesID(name string, labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	sb := strings.Builder{}
+	sb.WriteString(name)
+	sb.WriteByte('{')
+	for i, k := range names {
+		if i != 0 {
+			sb.WriteByte(',')
+		}
+		_, _ = fmt.Fprintf(&sb, "%s=\"%s\"", k, escapeLabelValue(labels[k]))
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+func writeSummary(w *bufio.Writer, name string, labels map[string]string, s *plugin.Summary) {
+	writeSample(w, name+"_sum", labels, "", "", s.Sum)
+	writeSample(w, name+"_count", labels, "", "", float64(s.Count))
+}
+
+func writeHistogram(w *bufio.Writer, name string, labels map[string]string, h *plugin.Histogram) {
+	bounds := make([]float64, 0, len(h.DataPoints))
+	for b := range h.DataPoints {
+		bounds = append(bounds, b)
+	}
+	sort.Float64s(bounds)
+
+	for _, b := range bounds {
+		writeSample(w, name+"_bucket", labels, "le", formatFloat(b), h.DataPoints[b])
+	}
+	if len(bounds) == 0 || !math.IsInf(bounds[len(bounds)-1], 1) {
+		writeSample(w, name+"_bucket", labels, "le", "+Inf", float64(h.Count))
+	}
+
+	writeSample(w, name+"_sum", labels, "", "", h.Sum)
+	writeSample(w, name+"_count", labels, "", "", float64(h.Count))
+}
+
+func writeSample(w *bufio.Writer, name string, labels map[string]string, extraName, extraValue string, value float64) {
+	_, _ = w.WriteString(name)
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		if k != extraName {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) != 0 || extraName != "" {
+		_ = w.WriteByte('{')
+		for i, k := range names {
+			if i != 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = fmt.Fprintf(w, "%s=\"%s\"", k, escapeLabelValue(labels[k]))
+		}
+		if extraName != "" {
+			if len(names) != 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = fmt.Fprintf(w, "%s=\"%s\"", extraName, extraValue)
+		}
+		_ = w.WriteByte('}')
+	}
+
+	_, _ = fmt.Fprintf(w, " %s\n", formatFloat(value))
+}
+
+func representable(mt *types.Metric) bool {
+	if isComplexType(TypeOf(mt)) {
+		return true
+	}
+
+	_, ok := ToFloat(mt.Value_)
+	return ok
+}
+
+// scalar metrics of different types may share a family, summaries and histograms can't
+func compatible(familyType, metricType string) bool {
+	if familyType == metricType {
+		return true
+	}
+
+	return !isComplexType(familyType) && !isComplexType(metricType)
+}
+
+func isComplexType(t string) bool {
+	return t == TypeSummary || t == TypeHistogram
+}
+
+// ToFloat converts numeric (and boolean) metric value to float64
+func ToFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int8:
+		return float64(t), true
+	case int16:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint:
+		return float64(t), true
+	case uint8:
+		return float64(t), true
+	case uint16:
+		return float64(t), true
+	case uint32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case bool:
+		if t {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sanitize replaces characters not allowed in metric (or label) names with "_"
+func sanitize(s string, allowColon bool) string {
+	if s == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		case r == ':' && allowColon:
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+
+	return sb.String()
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
@@ -0,0 +1,158 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package exposition
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+func staticNs(values ...string) []types.NamespaceElement {
+	ns := make([]types.NamespaceElement, 0, len(values))
+	for _, v := range values {
+		ns = append(ns, types.NamespaceElement{Value_: v})
+	}
+	return ns
+}
+
+func TestMetricName(t *testing.T) {
+	Convey("Validate that metric name is built from static elements of namespace", t, func() {
+		ns := append(staticNs("example", "cpu"), types.NamespaceElement{Name_: "core", Value_: "0"})
+		ns = append(ns, staticNs("usage.percent")...)
+
+		So(MetricName(ns), ShouldEqual, "example_cpu_usage_percent")
+		So(MetricName(staticNs("1st", "val-ue")), ShouldEqual, "_1st_val_ue")
+	})
+
+	Convey("Validate that dynamic elements and tags are converted to labels", t, func() {
+		mt := &types.Metric{
+			Namespace_: append(staticNs("example"), types.NamespaceElement{Name_: "core-id", Value_: "1"}),
+			Tags_:      map[string]string{"host.name": "local", "core_id": "overridden"},
+		}
+
+		So(Labels(mt), ShouldResemble, map[string]string{"host_name": "local", "core_id": "1"})
+	})
+}
+
+func TestWriteText(t *testing.T) {
+	Convey("Validate that metrics are written in text exposition format", t, func() {
+		mts := []*types.Metric{
+			{
+				Namespace_:   staticNs("example", "requests"),
+				Value_:       uint64(12),
+				Tags_:        map[string]string{"path": `/a"b`},
+				Type_:        plugin.SumType,
+				Description_: "Number of requests",
+			},
+			{
+				Namespace_: staticNs("example", "requests"),
+				Value_:     uint64(3),
+				Tags_:      map[string]string{"path": "/c"},
+				Type_:      plugin.SumType,
+			},
+			{
+				Namespace_: staticNs("example", "temperature"),
+				Value_:     21.5,
+				Type_:      plugin.GaugeType,
+			},
+			{
+				Namespace_: staticNs("example", "name"),
+				Value_:     "not a number",
+			},
+			{
+				Namespace_: staticNs("example", "latency"),
+				Value_: plugin.Histogram{
+					DataPoints: map[float64]float64{0.5: 2, 1: 5},
+					Count:      7,
+					Sum:        4.5,
+				},
+				Type_: plugin.HistogramType,
+			},
+			{
+				Namespace_: staticNs("example", "size"),
+				Value_:     &plugin.Summary{Count: 2, Sum: 10},
+				Type_:      plugin.SummaryType,
+			},
+			{
+				Namespace_: staticNs("example", "inf"),
+				Value_:     math.Inf(1),
+			},
+		}
+
+		buf := &bytes.Buffer{}
+		duplicates, err := WriteText(buf, mts)
+		So(err, ShouldBeNil)
+		So(duplicates, ShouldBeEmpty)
+		So(buf.String(), ShouldEqual, `# HELP example_requests Number of requests
+# TYPE example_requests counter
+example_requests{path="/a\"b"} 12
+example_requests{path="/c"} 3
+# TYPE example_temperature gauge
+example_temperature 21.5
+# TYPE example_latency histogram
+example_latency_bucket{le="0.5"} 2
+example_latency_bucket{le="1"} 5
+example_latency_bucket{le="+Inf"} 7
+example_latency_sum 4.5
+example_latency_count 7
+# TYPE example_size summary
+example_size_sum 10
+example_size_count 2
+# TYPE example_inf untyped
+example_inf +Inf
+`)
+	})
+}
+
+func TestWriteTextDuplicates(t *testing.T) {
+	Convey("Validate that series with the same name and labels are written only once", t, func() {
+		mts := []*types.Metric{
+			{
+				Namespace_: staticNs("a", "b_c"),
+				Value_:     1,
+				Tags_:      map[string]string{"host": "local"},
+			},
+			{
+				Namespace_: staticNs("a_b", "c"),
+				Value_:     2,
+				Tags_:      map[string]string{"host": "local"},
+			},
+			{
+				Namespace_: staticNs("a_b", "c"),
+				Value_:     3,
+				Tags_:      map[string]string{"host": "remote"},
+			},
+		}
+
+		buf := &bytes.Buffer{}
+		duplicates, err := WriteText(buf, mts)
+		So(err, ShouldBeNil)
+		So(duplicates, ShouldResemble, []string{`a_b_c{host="local"}`})
+		So(buf.String(), ShouldEqual, `# TYPE a_b_c untyped
+a_b_c{host="local"} 1
+a_b_c{host="remote"} 3
+`)
+	})
+}
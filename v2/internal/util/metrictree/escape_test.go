@@ -0,0 +1,37 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package metrictree
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+func TestEscapedIdentifier(t *testing.T) {
+	Convey("Validate that values escaped with plugin.NsElement are valid namespace elements", t, func() {
+		values := []string{"cpu0", "/var/log", "http://host:80/a?b=c", "[grp=val]", "100%", "zażółć"}
+
+		for _, v := range values {
+			So(isValidIdentifier(plugin.NsElement(v)), ShouldBeTrue)
+		}
+	})
+}
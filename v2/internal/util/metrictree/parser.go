@@ -22,6 +22,8 @@ import (
 	"strings"
 	"sync"
 	"unicode"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 )
 
 const (
@@ -45,6 +47,8 @@ var filteredNsBuffer = make(map[string]namespaceElement, initCacheSize)
 var noFilteredNsBufferRWLock = sync.RWMutex{}
 var noFilteredNsBuffer = make(map[string]namespaceElement, initCacheSize)
 
+// Split namespace into elements using its first character as a separator.
+// Elements are returned as is - values escaped with plugin.NsElement or plugin.EscapeNsSeparator should be restored by plugin.UnescapeNsElement.
 func SplitNamespace(s string) ([]string, string, error) {
 	if len(s) == 0 {
 		return nil, "", fmt.Errorf("namespace too short")
@@ -90,22 +94,23 @@ func ParseNamespace(s string, isFilter bool) (*Namespace, error) {
 			if err != nil {
 				return nil, fmt.Errorf("can't parse namespace (%s), error at index %d: %s", s, i, err)
 			}
+
+			// buffer is updated only for new elements, so parsing known namespaces requires only read locks
+			if isFilter {
+				filteredNsBufferRWLock.Lock()
+				filteredNsBuffer[nsElem] = parsedEl
+				filteredNsBufferRWLock.Unlock()
+			} else {
+				noFilteredNsBufferRWLock.Lock()
+				noFilteredNsBuffer[nsElem] = parsedEl
+				noFilteredNsBufferRWLock.Unlock()
+			}
 		}
 
 		if _, ok := parsedEl.(*staticRecursiveAnyElement); ok && i != len(splitNs[1:])-1 {
 			return nil, fmt.Errorf("recursive any-matcher (**) can be placed only as the last element")
 		}
 
-		if isFilter {
-			filteredNsBufferRWLock.Lock()
-			filteredNsBuffer[nsElem] = parsedEl
-			filteredNsBufferRWLock.Unlock()
-		} else {
-			noFilteredNsBufferRWLock.Lock()
-			noFilteredNsBuffer[nsElem] = parsedEl
-			noFilteredNsBufferRWLock.Unlock()
-		}
-
 		ns.elements = append(ns.elements, parsedEl)
 	}
 
@@ -182,6 +187,15 @@ func parseNamespaceElement(s string, isFilter bool) (namespaceElement, error) {
 	return nil, fmt.Errorf("invalid character(s) used for element [%s]", s)
 }
 
+// ValidateGroupValue checks if value can be used as a value of dynamic element (ie. [group=value])
+func ValidateGroupValue(value string) error {
+	if !isValidGroupIdentifier(value) {
+		return fmt.Errorf("invalid character(s) used for group value [%s]", value)
+	}
+
+	return nil
+}
+
 /*****************************************************************************/
 
 func isSurroundedWith(s string, prefix, suffix string) bool {
@@ -206,6 +220,7 @@ func isValidIdentifier(s string) bool {
 		case el == '.':
 		case el == '+':
 		case el == '/':
+		case el == plugin.NsEscapeIndicator: // escaped value, see plugin.NsElement
 		default:
 			return false
 		}
@@ -135,7 +135,7 @@ func (tv *TreeValidator) AddRule(ns string) error {
 			return fmt.Errorf("can't add rule (%s) - some namespace elements are not allowed in definition", ns)
 		}
 	case metricFilteringStrategy:
-		defPresent := tv.definitionTree.hasRules()
+		defPresent := tv.definitionTree.HasRules()
 		if !parsedNs.IsUsableForFiltering(tv.constraints, defPresent) {
 			return fmt.Errorf("can't add rule (%s) - some namespace elements are not allowed in filtering when metric definition wasn't provided", ns)
 		}
@@ -156,7 +156,7 @@ func (tv *TreeValidator) IsUsableForAddition(ns string, isFilter bool) error {
 		return fmt.Errorf("invalid format of namespace: %v", err)
 	}
 
-	ok := parsedNs.IsUsableForAddition(tv.constraints, tv.hasRules(), isFilter)
+	ok := parsedNs.IsUsableForAddition(tv.constraints, tv.HasRules(), isFilter)
 	if !ok {
 		return errors.New("metric not usable for addition")
 	}
@@ -185,7 +185,7 @@ func (tv *TreeValidator) isCompatible(ns string) bool {
 	return isCompatible
 }
 
-func (tv *TreeValidator) hasRules() bool {
+func (tv *TreeValidator) HasRules() bool {
 	return tv.head != nil
 }
 
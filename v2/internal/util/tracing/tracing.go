@@ -0,0 +1,170 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+/*
+Package tracing provides OpenTelemetry spans for operations requested by snap (Load, Collect, Publish etc.).
+Spans are exported only when OTLP endpoint is configured, otherwise global (by default no-op) tracer provider is used.
+Provider configured for exporting is registered globally only when plugin owns the process (isn't run as a thread).
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const tracerName = "github.com/solarwinds/snap-plugin-lib/v2"
+
+const (
+	TaskIDKey        = attribute.Key("snap.task.id")
+	MetricsCountKey  = attribute.Key("snap.metrics.count")
+	WarningsCountKey = attribute.Key("snap.warnings.count")
+)
+
+// propagator used to read trace context sent by snap (not registered globally not to override plugin author's settings)
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// provider configured by Setup (when nil, global tracer provider is used)
+var provider atomic.Pointer[sdktrace.TracerProvider]
+
+// Setup configures exporting spans to OTLP endpoint provided in options. Returned function should be called at
+// plugin shutdown to flush remaining spans.
+func Setup(ctx context.Context, name, version string, opt *plugin.Options) (func(context.Context) error, error) {
+	if opt.OTelExporterEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opt.OTelExporterEndpoint)}
+	if opt.OTelExporterInsecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("can't create OTLP exporter: %v", err)
+	}
+
+	res := resource.NewSchemaless(
+		semconv.ServiceName(name),
+		semconv.ServiceVersion(version),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opt.OTelSamplingRatio))),
+	)
+
+	provider.Store(tp)
+
+	// registered globally, so spans created by plugin author are exported as well.
+	// Plugin run as a thread shares process with host, so host's settings can't be overridden.
+	if !opt.AsThread {
+		otel.SetTracerProvider(tp)
+	}
+
+	return func(ctx context.Context) error {
+		provider.CompareAndSwap(tp, nil)
+		return tp.Shutdown(ctx)
+	}, nil
+}
+
+// StartSpan starts span associated with operation requested for a given task
+func StartSpan(ctx context.Context, name string, taskID string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(TaskIDKey.String(taskID)))
+}
+
+func tracer() trace.Tracer {
+	if tp := provider.Load(); tp != nil {
+		return tp.Tracer(tracerName)
+	}
+
+	return otel.Tracer(tracerName)
+}
+
+// EndSpan completes span setting its status based on operation result
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// ExtractFromMetadata returns context containing trace context received in GRPC metadata (if any)
+func ExtractFromMetadata(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	return propagator.Extract(ctx, metadataCarrier(md))
+}
+
+// InjectToMetadata returns context with outgoing GRPC metadata containing trace context (used by clients)
+func InjectToMetadata(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	} else {
+		md = md.Copy()
+	}
+
+	propagator.Inject(ctx, metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// metadataCarrier adapts GRPC metadata to propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	values := metadata.MD(mc).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (mc metadataCarrier) Set(key string, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for k := range mc {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
@@ -0,0 +1,62 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSetup(t *testing.T) {
+	Convey("Validate that tracer provider is registered globally only when plugin isn't run as a thread", t, func() {
+		hostProvider := sdktrace.NewTracerProvider()
+		prevProvider := otel.GetTracerProvider()
+		otel.SetTracerProvider(hostProvider)
+		defer otel.SetTracerProvider(prevProvider)
+
+		for _, asThread := range []bool{true, false} {
+			opt := &plugin.Options{
+				OTelExporterEndpoint: "127.0.0.1:4317",
+				OTelExporterInsecure: true,
+				OTelSamplingRatio:    1,
+				AsThread:             asThread,
+			}
+
+			// Act
+			shutdown, err := Setup(context.Background(), "plugin", "1.0.0", opt)
+			So(err, ShouldBeNil)
+
+			_, span := StartSpan(context.Background(), "Collect", "task-1")
+
+			// Assert
+			So(span.IsRecording(), ShouldBeTrue)
+			So(otel.GetTracerProvider() == hostProvider, ShouldEqual, asThread)
+			So(provider.Load(), ShouldNotBeNil)
+
+			So(shutdown(context.Background()), ShouldBeNil)
+			So(provider.Load(), ShouldBeNil)
+		}
+	})
+}
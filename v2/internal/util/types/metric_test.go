@@ -143,6 +143,21 @@ func TestDynamicMetric(t *testing.T) {
 			So(mt.Namespace().At(2).Description(), ShouldEqual, "Name of network interface")
 		})
 
+		Convey("Namespace with separator in element value", func() {
+			mt.Namespace_[2].Value_ = "/dev/net0"
+
+			So(mt.Namespace().String(), ShouldEqual, "/system/network/[interface=/dev/net0]/in_bytes")
+			So(Namespace(mt.Namespace_).EscapedString(), ShouldEqual, "/system/network/[interface=%2Fdev%2Fnet0]/in_bytes")
+			So(mt.Namespace().HasElement("[interface=/dev/net0]"), ShouldBeTrue)
+		})
+
+		Convey("Namespace with characters escaped by NsElement in element value", func() {
+			mt.Namespace_[2].Value_ = "eth 0:1"
+
+			So(mt.Namespace().String(), ShouldEqual, "/system/network/[interface=eth 0:1]/in_bytes")
+			So(Namespace(mt.Namespace_).EscapedString(), ShouldEqual, "/system/network/[interface=eth%200%3A1]/in_bytes")
+		})
+
 		Convey("Tags API", func() {
 			So(mt.Value(), ShouldEqual, 10)
 
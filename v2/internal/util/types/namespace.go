@@ -46,12 +46,22 @@ func (ns Namespace) HasElementOn(el string, pos int) bool {
 }
 
 func (ns Namespace) String() string {
+	return ns.join((*NamespaceElement).String)
+}
+
+// EscapedString returns namespace with element values escaped by plugin.NsElement (ie. "/fs/[mount=%2Fvar%2Flog]/usage").
+// It's the form in which namespace is matched against filters and modifier selectors (the same as used in AddMetric).
+func (ns Namespace) EscapedString() string {
+	return ns.join((*NamespaceElement).EscapedString)
+}
+
+func (ns Namespace) join(elementString func(*NamespaceElement) string) string {
 	var sb strings.Builder
 
 	sb.WriteString(metricSeparator)
 
-	for i, nsElem := range ns {
-		sb.WriteString(nsElem.String())
+	for i := range ns {
+		sb.WriteString(elementString(&ns[i]))
 
 		if i != len(ns)-1 {
 			sb.WriteString(metricSeparator)
@@ -93,6 +103,17 @@ func (ns *NamespaceElement) IsDynamic() bool {
 	return ns.Name_ != ""
 }
 
+// Representation of element in escaped namespace string. Value is escaped, so it can't be confused with separator.
+func (ns *NamespaceElement) EscapedString() string {
+	value := plugin.NsElement(ns.Value_)
+
+	if ns.Name_ == "" {
+		return value
+	}
+
+	return fmt.Sprintf("[%s=%s]", ns.Name_, value)
+}
+
 func (ns *NamespaceElement) String() string {
 	if ns.Name_ == "" {
 		return ns.Value_
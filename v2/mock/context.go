@@ -67,6 +67,16 @@ func (m *Context) AddMetric(ns string, value interface{}, modifiers ...plugin.Me
 	return args.Error(0)
 }
 
+func (m *Context) AddMetricNs(elements []string, value interface{}, modifiers ...plugin.MetricModifier) error {
+	args := m.Called(elements, value, modifiers)
+	return args.Error(0)
+}
+
+func (m *Context) Metric(ns string) plugin.MetricHandle {
+	args := m.Called(ns)
+	return args.Get(0).(plugin.MetricHandle)
+}
+
 func (m *Context) AlwaysApply(namespaceSelector string, modifiers ...plugin.MetricModifier) (plugin.Dismisser, error) {
 	args := m.Called(namespaceSelector, modifiers)
 	return args.Get(0).(plugin.Dismisser), args.Error(1)
@@ -76,6 +86,11 @@ func (m *Context) DismissAllModifiers() {
 	m.Called()
 }
 
+func (m *Context) Go(f func(ctx plugin.CollectContext) error) error {
+	args := m.Called(f)
+	return args.Error(0)
+}
+
 func (m *Context) ShouldProcess(ns string) bool {
 	args := m.Called(ns)
 	return args.Bool(0)
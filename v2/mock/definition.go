@@ -16,7 +16,11 @@
 
 package mock
 
-import "github.com/stretchr/testify/mock"
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
 
 type Definition struct {
 	mock.Mock
@@ -61,3 +65,18 @@ func (m *CollectorDefinition) DefineExampleConfig(cfg string) error {
 	args := m.Called(cfg)
 	return args.Error(0)
 }
+
+func (m *CollectorDefinition) DefineCardinalityLimits(limits plugin.CardinalityLimits) error {
+	args := m.Called(limits)
+	return args.Error(0)
+}
+
+func (m *CollectorDefinition) DefineDeduplication(policy plugin.DedupPolicy) error {
+	args := m.Called(policy)
+	return args.Error(0)
+}
+
+func (m *CollectorDefinition) DefineCollectConcurrency(limit int) error {
+	args := m.Called(limit)
+	return args.Error(0)
+}
@@ -54,6 +54,14 @@ type CollectContext interface {
 	// Add concrete metric with calculated value
 	AddMetric(namespace string, value interface{}, modifier ...MetricModifier) error
 
+	// Add concrete metric with namespace provided as a list of element values (ie. ["example", "/var/log", "usage"])
+	// Values may contain any characters (including separator), dynamic elements are recognized based on metric definition.
+	AddMetricNs(elements []string, value interface{}, modifier ...MetricModifier) error
+
+	// Create handle for a metric defined with dynamic elements (ie. "/example/[host]/cpu").
+	// Namespace is validated once, values are provided later: ctx.Metric("/example/[host]/cpu").With(host).Add(v)
+	Metric(namespace string) MetricHandle
+
 	// Always apply specific modifier(s) for a metrics matching namespace selector
 	// Returns object which may be used to dismiss modifiers (make them no-active)
 	AlwaysApply(namespaceSelector string, modifier ...MetricModifier) (Dismisser, error)
@@ -61,6 +69,12 @@ type CollectContext interface {
 	// Dismisses all modifiers created by calling AlwaysApply
 	DismissAllModifiers()
 
+	// Run function in a separate goroutine (ie. to poll one of many targets). Number of goroutines running at the same time
+	// is limited by value set with DefineCollectConcurrency (call blocks until goroutine can be started).
+	// Errors and panics are reported as warnings. Collect is completed when all goroutines have finished.
+	// Returns error when called after Collect has returned, from goroutine started by Go or when task is canceled.
+	Go(f func(ctx CollectContext) error) error
+
 	// Provide information whether metric or metric group is reasonable to process (won't be filtered).
 	ShouldProcess(namespace string) bool
 
@@ -100,4 +114,16 @@ type CollectorDefinition interface {
 	// - removePrefixFromOutput set to true removes global prefix from all namespaces when result is to be sent to agent.
 	// !! Should be set before any call to DefineMetric
 	SetGlobalMetricPrefix(prefix string, removePrefixFromOutput bool) error
+
+	// Define limits of series, tags and tag value lengths which are enforced for each task separately.
+	// Number of metrics exceeding limits is reported in warnings and statistics.
+	DefineCardinalityLimits(limits CardinalityLimits) error
+
+	// Enable deduplication of metrics with the same namespace and tags added during one collect (disabled by default).
+	// Number of duplicates is reported in warnings.
+	DefineDeduplication(policy DedupPolicy) error
+
+	// Define maximum number of goroutines started with CollectContext.Go running at the same time for a single task
+	// (NoLimit by default).
+	DefineCollectConcurrency(limit int) error
 }
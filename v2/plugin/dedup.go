@@ -0,0 +1,44 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package plugin
+
+import "fmt"
+
+// Defines how metrics with the same namespace and tags added during one collect are handled
+type DedupPolicy int
+
+const (
+	DedupDisabled  DedupPolicy = iota // all metrics are sent (default)
+	DedupLastWins                     // metric replaces one added earlier
+	DedupFirstWins                    // metric is discarded when the same one was added earlier
+	DedupError                        // metric is discarded and AddMetric returns error
+)
+
+func (p DedupPolicy) String() string {
+	switch p {
+	case DedupDisabled:
+		return "disabled"
+	case DedupLastWins:
+		return "last-wins"
+	case DedupFirstWins:
+		return "first-wins"
+	case DedupError:
+		return "error"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(p))
+	}
+}
@@ -0,0 +1,25 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package plugin
+
+import "context"
+
+// HealthCheckable might be implemented by collector or publisher to report its own condition (ie. connection with
+// monitored system) in liveness probe (/healthz). Returned error means that plugin is unhealthy.
+type HealthCheckable interface {
+	HealthCheck(ctx context.Context) error
+}
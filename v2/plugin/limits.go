@@ -0,0 +1,54 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package plugin
+
+import "fmt"
+
+// Value of dynamic elements of a series aggregating metrics above the limit (OverflowAggregate policy)
+const OverflowSeriesValue = "other"
+
+// Defines what happens with metric exceeding any of cardinality limits
+type OverflowPolicy int
+
+const (
+	OverflowDrop      OverflowPolicy = iota // metric is discarded
+	OverflowAggregate                       // metric is folded into "other" series (sum metrics only, others are discarded), excessive tags are removed and too long values are truncated
+	OverflowWarn                            // metric is passed unchanged, violation is only reported
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDrop:
+		return "drop"
+	case OverflowAggregate:
+		return "aggregate"
+	case OverflowWarn:
+		return "warn"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(p))
+	}
+}
+
+// CardinalityLimits protects from explosion of a number of series sent by a single task.
+// Use NoLimit for any value which shouldn't be restricted.
+type CardinalityLimits struct {
+	MaxSeriesPerCollect int // maximum number of unique series (namespace + tags) added during one collect (whole stream for streaming collector)
+	MaxTagsPerMetric    int // maximum number of tags associated with a single metric
+	MaxTagValueLength   int // maximum length of a tag value
+
+	Policy OverflowPolicy
+}
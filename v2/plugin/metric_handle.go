@@ -0,0 +1,38 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package plugin
+
+// MetricHandle represents metric namespace validated once against plugin definition (see CollectContext.Metric).
+// Adding samples using handle doesn't require parsing namespace string on every call.
+type MetricHandle interface {
+	// Provide values of dynamic elements (in order of their appearance in namespace).
+	// Values may contain any characters (they are escaped in the same way as in AddMetricNs).
+	With(groupValues ...string) MetricSample
+
+	// Add metric with calculated value (valid only for namespaces without dynamic elements)
+	Add(value interface{}, modifier ...MetricModifier) error
+
+	// Error which occurred when handle was created (the same error is returned by every Add call)
+	Err() error
+}
+
+// MetricSample represents metric with all dynamic elements filled
+type MetricSample interface {
+	// Add metric with calculated value. Sample can be added only once - following calls return error
+	// (ie. ctx.Metric(ns).With(v1, v2).Add(value)).
+	Add(value interface{}, modifier ...MetricModifier) error
+}
@@ -0,0 +1,127 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package plugin
+
+import (
+	"strings"
+)
+
+const (
+	NsEscapeIndicator = '%' // prefix of percent-encoded byte in namespace element
+	hexDigits         = "0123456789ABCDEF"
+)
+
+// NsElement escapes value, so it can be safely used as a namespace element, ie.
+//
+//	ctx.AddMetric(fmt.Sprintf("/example/[mount=%s]/usage", plugin.NsElement("/var/log")), v)
+//
+// All characters except letters, digits and "-_.+" are percent-encoded (ie. "/var/log" -> "%2Fvar%2Flog"),
+// so the value is safe with default ("/") separator.
+// Value is decoded by the library when metric is added (the original one is sent to agent).
+func NsElement(value string) string {
+	return escape(value, func(c byte) bool {
+		return !isSafeElementChar(c)
+	})
+}
+
+// EscapeNsSeparator percent-encodes only separator and escape indicator, so value can be split and restored without loss.
+func EscapeNsSeparator(value string, sep string) string {
+	return escape(value, func(c byte) bool {
+		return c == NsEscapeIndicator || strings.IndexByte(sep, c) != -1
+	})
+}
+
+// UnescapeNsElement reverts NsElement and EscapeNsSeparator. Invalid escape sequences are left untouched.
+func UnescapeNsElement(s string) string {
+	if strings.IndexByte(s, NsEscapeIndicator) == -1 {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == NsEscapeIndicator && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			sb.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+			continue
+		}
+
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
+
+/*****************************************************************************/
+
+func escape(value string, shouldEscape func(c byte) bool) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if !shouldEscape(c) {
+			if sb.Len() != 0 {
+				sb.WriteByte(c)
+			}
+			continue
+		}
+
+		if sb.Len() == 0 { // lazy initialization - most values don't need escaping
+			sb.Grow(len(value) + 2*(len(value)-i))
+			sb.WriteString(value[:i])
+		}
+
+		sb.WriteByte(NsEscapeIndicator)
+		sb.WriteByte(hexDigits[c>>4])
+		sb.WriteByte(hexDigits[c&0x0F])
+	}
+
+	if sb.Len() == 0 {
+		return value
+	}
+
+	return sb.String()
+}
+
+func isSafeElementChar(c byte) bool {
+	switch {
+	case c >= 'A' && c <= 'Z':
+	case c >= 'a' && c <= 'z':
+	case c >= '0' && c <= '9':
+	case c == '-' || c == '_' || c == '.' || c == '+':
+	default:
+		return false
+	}
+
+	return true
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
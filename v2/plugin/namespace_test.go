@@ -0,0 +1,73 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNsElement(t *testing.T) {
+	Convey("Validate that escaped values are valid namespace elements and can be restored", t, func() {
+		testCases := []struct {
+			value   string
+			escaped string
+		}{
+			{value: "cpu0", escaped: "cpu0"},
+			{value: "my-host.local", escaped: "my-host.local"},
+			{value: "/var/log", escaped: "%2Fvar%2Flog"},
+			{value: "http://host:80/a?b=c", escaped: "http%3A%2F%2Fhost%3A80%2Fa%3Fb%3Dc"},
+			{value: "[grp=val]", escaped: "%5Bgrp%3Dval%5D"},
+			{value: "100%", escaped: "100%25"},
+			{value: "zażółć", escaped: "za%C5%BC%C3%B3%C5%82%C4%87"},
+		}
+
+		for _, tc := range testCases {
+			escaped := NsElement(tc.value)
+
+			So(escaped, ShouldEqual, tc.escaped)
+			So(UnescapeNsElement(escaped), ShouldEqual, tc.value)
+		}
+	})
+
+	Convey("Validate that namespace with escaped separators can be split and restored", t, func() {
+		values := []string{"plugin", "/var/log", "50%", "usage"}
+
+		ns := ""
+		for _, v := range values {
+			ns += "/" + EscapeNsSeparator(v, "/")
+		}
+
+		elems := strings.Split(ns, "/")
+		So(len(elems[1:]), ShouldEqual, len(values))
+
+		for i, el := range elems[1:] {
+			So(UnescapeNsElement(el), ShouldEqual, values[i])
+		}
+	})
+
+	Convey("Validate that invalid escape sequences are left untouched", t, func() {
+		So(UnescapeNsElement("100%"), ShouldEqual, "100%")
+		So(UnescapeNsElement("%zz%2"), ShouldEqual, "%zz%2")
+		So(UnescapeNsElement("%2f"), ShouldEqual, "/")
+	})
+}
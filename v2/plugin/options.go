@@ -17,6 +17,7 @@
 package plugin
 
 import (
+	"os"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -25,22 +26,54 @@ import (
 // Structure representing plugin configuration (received by parsing command-line arguments)
 // Visit newFlagParser() to find descriptions associated with each option.
 type Options struct {
+	ConfigFile string `json:",omitempty"` // YAML/JSON file containing values of options (overridden by command-line and env)
+
 	PluginIP          string
 	GRPCPort          int
 	GRPCPingTimeout   time.Duration
 	GRPCPingMaxMissed uint
+	ShutdownTimeout   time.Duration // deadline for unloading tasks when termination signal is received
+
+	GRPCSocket            string      `json:",omitempty"` // Unix domain socket used instead of IP and port
+	GRPCSocketPermissions os.FileMode `json:",omitempty"`
+
+	GRPCCompression                  string // compression of responses (used when supported by client)
+	GRPCMaxRecvMsgSize               int    `json:",omitempty"`
+	GRPCMaxSendMsgSize               int    `json:",omitempty"`
+	GRPCKeepaliveTime                time.Duration
+	GRPCKeepaliveTimeout             time.Duration
+	GRPCKeepaliveMinTime             time.Duration // enforcement policy - minimum interval between client pings
+	GRPCKeepalivePermitWithoutStream bool
 
 	EnableTLS         bool // GRPC Server
 	TLSServerCertPath string
 	TLSServerKeyPath  string
 	TLSClientCAPath   string
+	TLSReloadInterval time.Duration // interval of checking if certificate files were modified (0 - reload disabled)
+	TLSMinVersion     string
+	TLSCipherSuites   string `json:",omitempty"` // names separated by comma
+	TLSClientSANs     string `json:",omitempty"` // allowed SANs of client certificate, separated by comma
+
+	AuthToken     string `json:"-"` // token which has to be sent by client in each request (authorization: Bearer <token>)
+	AuthTokenFile string `json:",omitempty"`
+
+	OTelExporterEndpoint string  `json:",omitempty"` // OTLP/gRPC endpoint receiving spans (tracing is disabled when empty)
+	OTelExporterInsecure bool    `json:",omitempty"`
+	OTelSamplingRatio    float64 `json:",omitempty"`
+
+	LogLevel           logrus.Level
+	EnableProfiling    bool
+	PProfPort          int  `json:",omitempty"`
+	EnableStats        bool // enable calculation statistics
+	EnableStatsServer  bool // if true, start statistics HTTP server
+	StatsPort          int  `json:",omitempty"`
+	EnableDebugAPI     bool // if true, start HTTP server exposing plugin operations (load, collect, publish etc.)
+	DebugAPIPort       int  `json:",omitempty"`
+	EnableHealthServer bool // if true, start HTTP server with liveness (/healthz) and readiness (/readyz) probes
+	HealthPort         int  `json:",omitempty"`
 
-	LogLevel          logrus.Level
-	EnableProfiling   bool
-	PProfPort         int  `json:",omitempty"`
-	EnableStats       bool // enable calculation statistics
-	EnableStatsServer bool // if true, start statistics HTTP server
-	StatsPort         int  `json:",omitempty"`
+	PrometheusExporter     bool // if true, collector is run as Prometheus exporter (collect is requested on each scrape)
+	PrometheusExporterPort int  `json:",omitempty"`
 
 	UseAPIv2 bool
 	AsThread bool
@@ -53,5 +86,13 @@ type Options struct {
 	PluginFilter         string        `json:"-"`
 	DebugCollectCounts   int           `json:"-"`
 	DebugCollectInterval time.Duration `json:"-"`
+	DebugCollectJitter   time.Duration `json:"-"`
+	TaskFile             string        `json:"-"`
+	Sinks                string        `json:"-"`
+	SinkFilePath         string        `json:"-"`
+	SinkFileMaxSize      int           `json:"-"` // in MB
+	SinkFileMaxBackups   int           `json:"-"`
+	SinkHTTPURL          string        `json:"-"`
+	SinkPrometheusPort   int           `json:"-"`
 	PrintVersion         bool          `json:"-"`
 }
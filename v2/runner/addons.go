@@ -110,3 +110,31 @@ func statsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, s
 		w.WriteHeader(http.StatusRequestTimeout)
 	}
 }
+
+///////////////////////////////////////////////////////////////////////////////
+
+func startDebugAPIServer(ctx context.Context, ln net.Listener, h http.Handler) {
+	logF := log.WithCtx(ctx).WithFields(moduleFields)
+	logF.Infof("Running debug API server on address %s", ln.Addr())
+
+	go func() {
+		err := http.Serve(ln, h)
+		if err != nil {
+			logF.WithError(err).Warn("Debug API server stopped")
+		}
+	}()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func startHealthServer(ctx context.Context, ln net.Listener, h http.Handler) {
+	logF := log.WithCtx(ctx).WithFields(moduleFields)
+	logF.Infof("Running health server on address %s", ln.Addr())
+
+	go func() {
+		err := http.Serve(ln, h)
+		if err != nil {
+			logF.WithError(err).Warn("Health server stopped")
+		}
+	}()
+}
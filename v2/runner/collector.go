@@ -23,49 +23,63 @@ package runner
 import (
 	"context"
 	"fmt"
-	"os"
-	"strings"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/collector/proxy"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/common/stats"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/service"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/tracing"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 )
 
 const (
-	normalExitStatus = 0
-	errorExitStatus  = 1
-
-	infiniteDebugCollectCount = -1
+	errorExitStatus = 1
 )
 
 func StartStreamingCollector(collector plugin.StreamingCollector, name string, version string) {
 	StartStreamingCollectorWithContext(context.Background(), collector, name, version)
 }
 
+// StartStreamingCollectorWithContext runs streaming collector until it's requested to stop (Kill, SIGTERM or ctx cancellation).
+// Process is terminated when error occurs (use RunStreamingCollector to handle errors by yourself).
 func StartStreamingCollectorWithContext(ctx context.Context, collector plugin.StreamingCollector, name string, version string) {
-	startCollector(ctx, types.NewStreamingCollector(name, version, collector))
+	ctx, inProc, stop := startContext(ctx, collector)
+	defer stop()
+
+	exitOnError(ctx, RunStreamingCollector(ctx, collector, name, version), inProc)
 }
 
 func StartCollector(collector plugin.Collector, name string, version string) {
 	StartCollectorWithContext(context.Background(), collector, name, version)
 }
 
+// StartCollectorWithContext runs collector until it's requested to stop (Kill, SIGTERM or ctx cancellation).
+// Process is terminated when error occurs (use RunCollector to handle errors by yourself).
 func StartCollectorWithContext(ctx context.Context, collector plugin.Collector, name string, version string) {
-	startCollector(ctx, types.NewCollector(name, version, collector))
+	ctx, inProc, stop := startContext(ctx, collector)
+	defer stop()
+
+	exitOnError(ctx, RunCollector(ctx, collector, name, version), inProc)
 }
 
-func startCollector(ctx context.Context, collector types.Collector) {
-	var err error
+// RunStreamingCollector runs streaming collector until Kill is requested or ctx is done (tasks are unloaded then).
+// Returns error when plugin can't be started or hasn't been stopped gracefully.
+func RunStreamingCollector(ctx context.Context, collector plugin.StreamingCollector, name string, version string, opts ...Option) error {
+	return runCollector(ctx, types.NewStreamingCollector(name, version, collector), newRunConfig(opts))
+}
 
-	var opt *plugin.Options
+// RunCollector runs collector until Kill is requested or ctx is done (tasks are unloaded then).
+// Returns error when plugin can't be started or hasn't been stopped gracefully.
+func RunCollector(ctx context.Context, collector plugin.Collector, name string, version string, opts ...Option) error {
+	return runCollector(ctx, types.NewCollector(name, version, collector), newRunConfig(opts))
+}
+
+func runCollector(ctx context.Context, collector types.Collector, rc *runConfig) error {
 	inprocPlugin, inProc := collector.Unwrap().(inProcessPlugin)
 	if inProc {
-		opt = inprocPlugin.Options()
+		rc.opt = inprocPlugin.Options()
 
 		logger := inprocPlugin.Logger()
 		ctx = log.ToCtx(ctx, logger)
@@ -80,30 +94,25 @@ func startCollector(ctx context.Context, collector types.Collector) {
 
 	logF := logger(ctx).WithField("service", "collector")
 
-	if opt == nil {
-		opt, err = ParseCmdLineOptions(os.Args[0], types.PluginTypeCollector, os.Args[1:])
-		if err != nil {
-			logF.WithError(err).Error("Error occurred during plugin startup while parsing commandline options")
-			os.Exit(errorExitStatus)
-		}
-	}
-
-	opt, err = ParseEnvOptions(os.Environ(), opt)
+	opt, err := rc.options(types.PluginTypeCollector)
 	if err != nil {
-		logF.WithError(err).Error("Error occurred during plugin startup while parsing env")
-		os.Exit(errorExitStatus)
+		return err
 	}
 
-	err = ValidateOptions(opt)
+	shutdownTracing, err := tracing.Setup(ctx, collector.Name(), collector.Version(), opt)
 	if err != nil {
-		logF.WithError(err).Error("Invalid plugin options")
-		os.Exit(errorExitStatus)
+		return fmt.Errorf("can't initialize tracing: %w", err)
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logF.WithError(err).Warn("Can't flush remaining spans")
+		}
+	}()
 
-	statsController, err := stats.NewController(ctx, collector.Name(), collector.Version(), collector.Type(), opt)
+	// statistics are updated until all tasks are unloaded (which happens after ctx is done)
+	statsController, err := stats.NewController(context.WithoutCancel(ctx), collector.Name(), collector.Version(), collector.Type(), opt)
 	if err != nil {
-		logF.WithError(err).Error("Error occured when starting statistics controller")
-		os.Exit(errorExitStatus)
+		return fmt.Errorf("error occured when starting statistics controller: %w", err)
 	}
 	defer statsController.Close()
 
@@ -113,18 +122,22 @@ func startCollector(ctx context.Context, collector types.Collector) {
 
 	if opt.PrintVersion {
 		printVersion(collector.Name(), collector.Version())
-		os.Exit(normalExitStatus)
+		return nil
 	}
 
 	if opt.PrintExampleTask {
 		printExampleTask(ctxMan.ExampleConfig, collector.Name(), collector.Type())
-		os.Exit(normalExitStatus)
+		return nil
+	}
+
+	if opt.PrometheusExporter && !opt.AsThread && collector.Type() == types.PluginTypeStreamingCollector {
+		// scrape requires collect to be completed, streaming one lasts until task is unloaded
+		return fmt.Errorf("streaming collector can't be run as prometheus exporter")
 	}
 
 	r, err := acquireResources(opt)
 	if err != nil {
-		logF.WithError(err).Error("Can't acquire resources for plugin services")
-		os.Exit(errorExitStatus)
+		return fmt.Errorf("can't acquire resources for plugin services: %w", err)
 	}
 
 	if opt.EnableProfiling {
@@ -137,86 +150,54 @@ func startCollector(ctx context.Context, collector types.Collector) {
 		defer r.statsListener.Close() // close stats service when GRPC service has been shut down
 	}
 
-	if opt.DebugMode {
-		startCollectorInDebugMode(ctxMan, opt)
-	} else {
-		jsonMeta := metaInformation(ctx, collector.Name(), collector.Version(), collector.Type(), opt, r, ctxMan.TasksLimit, ctxMan.InstancesLimit)
-		if inProc {
-			inprocPlugin.MetaChannel() <- jsonMeta
-			close(inprocPlugin.MetaChannel())
-		}
-
-		srv, err := service.NewGRPCServer(ctx, opt)
+	if opt.EnableDebugAPI {
+		debugAPI, err := service.NewCollectorDebugAPI(ctx, ctxMan, opt)
 		if err != nil {
-			logF.WithError(err).Error("Can't initialize GRPC Server")
-			os.Exit(errorExitStatus)
+			r.release()
+			return fmt.Errorf("can't initialize debug API: %w", err)
 		}
 
-		// We need to bind the gRPC client on the other end to the same channel so need to return it from here
-		if inProc {
-			inprocPlugin.GRPCChannel() <- srv.(*service.Channel).Channel
-		}
+		startDebugAPIServer(ctx, r.debugAPIListener, debugAPI)
+		defer r.debugAPIListener.Close() // close debug API service when GRPC service has been shut down
+	}
 
-		// main blocking operation
-		service.StartCollectorGRPC(ctx, srv, ctxMan, r.grpcListener, opt.GRPCPingTimeout, opt.GRPCPingMaxMissed, opt.CollectChunkSize)
+	health := service.NewHealth(!opt.DebugMode && !opt.PrometheusExporter)
+	if opt.EnableHealthServer {
+		startHealthServer(ctx, r.healthListener, service.NewHealthHandler(ctx, health, ctxMan))
+		defer r.healthListener.Close() // close health service when GRPC service has been shut down
 	}
-}
 
-func startCollectorInDebugMode(ctxManager *proxy.ContextManager, opt *plugin.Options) {
-	const debugModeTaskID = "task-1"
-
-	// Load task based on command line options
-	var filter []string
-	if opt.PluginFilter != defaultFilter {
-		filter = strings.Split(opt.PluginFilter, filterSeparator)
-	}
-
-	errLoad := ctxManager.LoadTask(debugModeTaskID, []byte(opt.PluginConfig), filter)
-	if errLoad != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Couldn't load a task in a standalone mode (reason: %v)\n", errLoad)
-		os.Exit(errorExitStatus)
-	}
-
-	for runCount := 0; ; {
-		// Request metrics collection
-		chunkCh := ctxManager.RequestCollect(debugModeTaskID)
-
-		for chunk := range chunkCh {
-			if chunk.Err != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "Error occurred during metrics collection in a standalone mode (reason: %v)\n", chunk.Err)
-				os.Exit(errorExitStatus)
-			}
-
-			// Print out metrics
-			fmt.Printf("Gathered metrics (length=%d): \n", len(chunk.Metrics))
-			for _, mt := range chunk.Metrics {
-				fmt.Printf("%s\n", mt)
-			}
-			fmt.Printf("\n")
-
-			if len(chunk.Warnings) != 0 {
-				fmt.Printf("Gathered warnings (length=%d): \n", len(chunk.Warnings))
-				for _, w := range chunk.Warnings {
-					fmt.Printf("%s\n", w)
-				}
-				fmt.Printf("\n")
-			}
-		}
+	if opt.DebugMode {
+		return startCollectorInDebugMode(ctx, ctxMan, opt, collector.Type() == types.PluginTypeStreamingCollector)
+	}
 
-		// wait to request new collection or exit
-		if opt.DebugCollectCounts != infiniteDebugCollectCount {
-			runCount++
-			if runCount == opt.DebugCollectCounts {
-				break
-			}
-		}
+	if opt.PrometheusExporter {
+		defer r.exporterListener.Close() // listener isn't handed over to server when task can't be loaded
+		return startCollectorAsExporter(ctx, ctxMan, opt, r.exporterListener)
+	}
+
+	jsonMeta, err := metaInformation(collector.Name(), collector.Version(), collector.Type(), opt, r, ctxMan.TasksLimit, ctxMan.InstancesLimit)
+	if err != nil {
+		r.release()
+		return err
+	}
+
+	if inProc {
+		inprocPlugin.MetaChannel() <- jsonMeta
+		close(inprocPlugin.MetaChannel())
+	}
 
-		time.Sleep(opt.DebugCollectInterval)
+	srv, err := service.NewGRPCServer(ctx, opt, rc.grpcHooks)
+	if err != nil {
+		r.release()
+		return fmt.Errorf("can't initialize GRPC server: %w", err)
 	}
 
-	errUnload := ctxManager.UnloadTask(debugModeTaskID)
-	if errUnload != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Couldn't unload a task in a standalone mode (reason: %v)\n", errUnload)
-		os.Exit(errorExitStatus)
+	// We need to bind the gRPC client on the other end to the same channel so need to return it from here
+	if inProc {
+		inprocPlugin.GRPCChannel() <- srv.(*service.Channel).Channel
 	}
+
+	// main blocking operation
+	return service.StartCollectorGRPC(ctx, srv, ctxMan, r.grpcListener, opt.GRPCPingTimeout, opt.GRPCPingMaxMissed, opt.CollectChunkSize, opt.ShutdownTimeout, health)
 }
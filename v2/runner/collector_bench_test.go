@@ -191,13 +191,13 @@ func genMetricAddition(addRatio int, b *testing.B) {
 			addMetricRatio: addRatio,
 		}
 		ctxMan := proxy.NewContextManager(benchColl, "benchmark collector", "0.0.2")
-		err := ctxMan.LoadTask(taskId, []byte("{}"), []string{})
+		err := ctxMan.LoadTask(context.Background(), taskId, []byte("{}"), []string{})
 		if err != nil {
 			panic(err)
 		}
 
 		b.ResetTimer()
-		chunkCh = ctxMan.RequestCollect(taskId)
+		chunkCh = ctxMan.RequestCollect(context.Background(), taskId)
 
 		select {
 		case <-chunkCh:
@@ -25,6 +25,12 @@ import (
 	"io"
 	"math"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,10 +39,15 @@ import (
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/collector/proxy"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/common/stats"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/service"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/tracing"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -85,7 +96,7 @@ func (s *SuiteT) startCollector(collector plugin.Collector) net.Listener {
 	go func() {
 		statsController, _ := stats.NewEmptyController()
 		contextManager := proxy.NewContextManager(context.Background(), types.NewCollector("test-collector", "1.0.0", collector), statsController)
-		service.StartCollectorGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize)
+		_ = service.StartCollectorGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize, service.DefaultShutdownTimeout, nil)
 		s.endCh <- true
 	}()
 
@@ -101,7 +112,7 @@ func (s *SuiteT) startStreamingCollector(collector plugin.StreamingCollector) ne
 	go func() {
 		statsController, _ := stats.NewEmptyController()
 		contextManager := proxy.NewContextManager(context.Background(), types.NewStreamingCollector("test-collector", "1.0.0", collector), statsController)
-		service.StartCollectorGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize)
+		_ = service.StartCollectorGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize, service.DefaultShutdownTimeout, nil)
 		s.endCh <- true
 	}()
 
@@ -163,6 +174,7 @@ func (s *SuiteT) sendCollect(taskID string) (*pluginrpc.CollectResponse, error)
 		}
 
 		aggregatedMts.MetricSet = append(aggregatedMts.MetricSet, partialResponse.MetricSet...)
+		aggregatedMts.Warnings = append(aggregatedMts.Warnings, partialResponse.Warnings...)
 	}
 
 	return aggregatedMts, nil
@@ -1278,3 +1290,884 @@ func (s *SuiteT) TestCollectorWithGlobalPrefix_DifferentSeparators() {
 		So(mts.MetricSet[4].Namespace[0].Value, ShouldEqual, "mongodb")
 	})
 }
+
+/*****************************************************************************/
+
+type collectorWithCardinalityLimits struct {
+	t      *testing.T
+	policy plugin.OverflowPolicy
+}
+
+func (c *collectorWithCardinalityLimits) PluginDefinition(ctx plugin.CollectorDefinition) error {
+	ctx.DefineMetric("/coll/[disk]/usage", "", true, "disk usage")
+
+	return ctx.DefineCardinalityLimits(plugin.CardinalityLimits{
+		MaxSeriesPerCollect: 3,
+		MaxTagsPerMetric:    2,
+		MaxTagValueLength:   5,
+		Policy:              c.policy,
+	})
+}
+
+func (c *collectorWithCardinalityLimits) Collect(ctx plugin.CollectContext) error {
+	Convey("Validate AddMetric doesn't return errors when limits are exceeded", c.t, func() {
+		for i := 1; i <= 5; i++ {
+			So(ctx.AddMetric(fmt.Sprintf("/coll/disk%d/usage", i), i, plugin.MetricTypeSum()), ShouldBeNil)
+		}
+
+		So(ctx.AddMetric("/coll/disk1/usage", 10, plugin.MetricTypeSum(), plugin.MetricTags(map[string]string{"a": "1", "b": "2", "c": "3"})), ShouldBeNil)
+		So(ctx.AddMetric("/coll/disk2/usage", 20, plugin.MetricTypeSum(), plugin.MetricTag("a", "too-long-value")), ShouldBeNil)
+	})
+
+	return nil
+}
+
+func (s *SuiteT) TestCollectorWithCardinalityLimits() {
+	// Arrange
+	jsonConfig := []byte(`{}`)
+	var mtsSelector []string
+
+	Convey("Validate collector enforces cardinality limits according to overflow policy", s.T(), func() {
+		Convey("Drop policy", func() {
+			collector := &collectorWithCardinalityLimits{t: s.T(), policy: plugin.OverflowDrop}
+			ln := s.startCollector(collector)
+			s.startClient(ln.Addr().String())
+
+			_, _ = s.sendLoad("task-1", jsonConfig, mtsSelector)
+			mts, err := s.sendCollect("task-1")
+
+			So(err, ShouldBeNil)
+			So(len(mts.MetricSet), ShouldEqual, 3)
+			So(len(mts.Warnings), ShouldEqual, 1)
+			So(mts.Warnings[0].Message, ShouldContainSubstring, "series=2, tags per metric=1, tag value length=1")
+
+			_, _ = s.sendKill()
+		})
+
+		Convey("Aggregate policy", func() {
+			collector := &collectorWithCardinalityLimits{t: s.T(), policy: plugin.OverflowAggregate}
+			ln := s.startCollector(collector)
+			s.startClient(ln.Addr().String())
+
+			_, _ = s.sendLoad("task-1", jsonConfig, mtsSelector)
+			mts, err := s.sendCollect("task-1")
+
+			So(err, ShouldBeNil)
+			So(len(mts.MetricSet), ShouldEqual, 4)
+			So(mts.MetricSet[3].Namespace[1].Value, ShouldEqual, plugin.OverflowSeriesValue)
+			So(mts.MetricSet[3].Value.GetVDouble(), ShouldEqual, 4+5+10+20)
+			So(len(mts.Warnings), ShouldEqual, 1)
+
+			_, _ = s.sendKill()
+		})
+
+		Convey("Warn policy", func() {
+			collector := &collectorWithCardinalityLimits{t: s.T(), policy: plugin.OverflowWarn}
+			ln := s.startCollector(collector)
+			s.startClient(ln.Addr().String())
+
+			_, _ = s.sendLoad("task-1", jsonConfig, mtsSelector)
+			mts, err := s.sendCollect("task-1")
+
+			So(err, ShouldBeNil)
+			So(len(mts.MetricSet), ShouldEqual, 7)
+			So(len(mts.Warnings), ShouldEqual, 1)
+			So(mts.Warnings[0].Message, ShouldContainSubstring, "series=4, tags per metric=1, tag value length=1")
+
+			_, _ = s.sendKill()
+		})
+	})
+}
+
+/*****************************************************************************/
+
+type collectorWithDeduplication struct {
+	t      *testing.T
+	policy plugin.DedupPolicy
+}
+
+func (c *collectorWithDeduplication) PluginDefinition(ctx plugin.CollectorDefinition) error {
+	return ctx.DefineDeduplication(c.policy)
+}
+
+func (c *collectorWithDeduplication) Collect(ctx plugin.CollectContext) error {
+	Convey("Validate AddMetric handles duplicates according to policy", c.t, func() {
+		So(ctx.AddMetric("/coll/dedup/m1", 1, plugin.MetricTag("k", "v1")), ShouldBeNil)
+		So(ctx.AddMetric("/coll/dedup/m1", 2, plugin.MetricTag("k", "v2")), ShouldBeNil) // different tags - not a duplicate
+		So(ctx.AddMetric("/coll/dedup/m2", 3), ShouldBeNil)
+
+		errDup := ctx.AddMetric("/coll/dedup/m1", 4, plugin.MetricTag("k", "v1"))
+		if c.policy == plugin.DedupError {
+			So(errDup, ShouldBeError)
+		} else {
+			So(errDup, ShouldBeNil)
+		}
+	})
+
+	return nil
+}
+
+func (s *SuiteT) TestCollectorWithDeduplication() {
+	// Arrange
+	jsonConfig := []byte(`{}`)
+	var mtsSelector []string
+
+	testCases := []struct {
+		policy         plugin.DedupPolicy
+		expectedValues []int64
+		expectedWarns  int
+	}{
+		{policy: plugin.DedupDisabled, expectedValues: []int64{1, 2, 3, 4}, expectedWarns: 0},
+		{policy: plugin.DedupLastWins, expectedValues: []int64{4, 2, 3}, expectedWarns: 1},
+		{policy: plugin.DedupFirstWins, expectedValues: []int64{1, 2, 3}, expectedWarns: 1},
+		{policy: plugin.DedupError, expectedValues: []int64{1, 2, 3}, expectedWarns: 1},
+	}
+
+	Convey("Validate collector deduplicates metrics according to policy", s.T(), func() {
+		for _, tc := range testCases {
+			collector := &collectorWithDeduplication{t: s.T(), policy: tc.policy}
+			ln := s.startCollector(collector)
+			s.startClient(ln.Addr().String())
+
+			_, _ = s.sendLoad("task-1", jsonConfig, mtsSelector)
+			mts, err := s.sendCollect("task-1")
+
+			So(err, ShouldBeNil)
+			So(len(mts.MetricSet), ShouldEqual, len(tc.expectedValues))
+			for i, v := range tc.expectedValues {
+				So(mts.MetricSet[i].Value.GetVInt64(), ShouldEqual, v)
+			}
+			So(len(mts.Warnings), ShouldEqual, tc.expectedWarns)
+
+			_, _ = s.sendKill()
+		}
+	})
+}
+
+/*****************************************************************************/
+
+type collectorWithEscapedNamespaces struct {
+	t *testing.T
+}
+
+func (c *collectorWithEscapedNamespaces) PluginDefinition(ctx plugin.CollectorDefinition) error {
+	ctx.DefineMetric("/coll/[mount]/usage", "%", true, "disk usage")
+	ctx.DefineGroup("mount", "mount point")
+
+	return nil
+}
+
+func (c *collectorWithEscapedNamespaces) Collect(ctx plugin.CollectContext) error {
+	Convey("Validate metrics with values containing separator can be added", c.t, func() {
+		_, err := ctx.AlwaysApply(fmt.Sprintf("/coll/[mount=%s]/usage", plugin.NsElement("C: data")), plugin.MetricTag("k", "v"))
+		So(err, ShouldBeNil)
+
+		So(ctx.AddMetricNs([]string{"coll", "/var/log", "usage"}, 10), ShouldBeNil)
+		So(ctx.AddMetric(fmt.Sprintf("/coll/[mount=%s]/usage", plugin.NsElement("/home")), 20), ShouldBeNil)
+		So(ctx.AddMetric("/coll/[mount=/tmp]/usage", 30), ShouldBeError) // separator in value corrupts namespace
+		So(ctx.AddMetricNs([]string{"coll", "/var/log", "unknown"}, 10), ShouldBeError)
+		So(ctx.AddMetric(fmt.Sprintf("/coll/[mount=%s]/usage", plugin.NsElement("C: data")), 40), ShouldBeNil)
+	})
+
+	return nil
+}
+
+func (s *SuiteT) TestCollectorWithEscapedNamespaces() {
+	// Arrange
+	jsonConfig := []byte(`{}`)
+	mtsSelector := []string{
+		"/coll/[mount=%2Fvar%2Flog]/*",
+		"/coll/%2Fhome/*",
+		"/coll/C%3A%20data/*",
+	}
+
+	collector := &collectorWithEscapedNamespaces{t: s.T()}
+	ln := s.startCollector(collector)
+	s.startClient(ln.Addr().String())
+
+	Convey("Validate namespace element values are escaped and restored", s.T(), func() {
+		_, _ = s.sendLoad("task-1", jsonConfig, mtsSelector)
+
+		mts, err := s.sendCollect("task-1")
+		So(err, ShouldBeNil)
+		So(len(mts.MetricSet), ShouldEqual, 3)
+
+		So(mts.MetricSet[0].Namespace[1].Name, ShouldEqual, "mount")
+		So(mts.MetricSet[0].Namespace[1].Value, ShouldEqual, "/var/log")
+		So(mts.MetricSet[1].Namespace[1].Value, ShouldEqual, "/home")
+
+		// modifier selector is matched in the same (escaped) form as namespace passed to AddMetric
+		So(mts.MetricSet[2].Namespace[1].Value, ShouldEqual, "C: data")
+		So(mts.MetricSet[2].Tags, ShouldResemble, map[string]string{"k": "v"})
+		So(mts.MetricSet[0].Tags, ShouldBeEmpty)
+
+		_, _ = s.sendKill()
+	})
+}
+
+/*****************************************************************************/
+
+type collectorWithMetricHandles struct {
+	t *testing.T
+}
+
+func (c *collectorWithMetricHandles) PluginDefinition(ctx plugin.CollectorDefinition) error {
+	ctx.DefineMetric("/coll/[host]/cpu/[core]/usage", "%", true, "cpu usage")
+	ctx.DefineMetric("/coll/total/count", "", true, "number of hosts")
+	ctx.DefineGroup("host", "host name")
+
+	return nil
+}
+
+func (c *collectorWithMetricHandles) Collect(ctx plugin.CollectContext) error {
+	Convey("Validate metrics can be added using precompiled handles", c.t, func() {
+		usage := ctx.Metric("/coll/[host]/cpu/[core]/usage")
+		So(usage.Err(), ShouldBeNil)
+
+		So(usage.With("host1", "0").Add(10.5), ShouldBeNil)
+		So(usage.With("host1", "1").Add(20.5, plugin.MetricTag("k", "v")), ShouldBeNil)
+		So(usage.With("host2", "0").Add(30.5), ShouldBeNil) // filtered
+		So(usage.With("host1", "cpu/2").Add(40.5), ShouldBeNil)
+		So(usage.With("host1/a", "0").Add(50.5), ShouldBeNil) // filtered (value is matched in escaped form)
+		So(usage.With("host1").Add(1), ShouldBeError)
+		So(usage.With("host1", "").Add(1), ShouldBeError)
+
+		sample := usage.With("host1", "3")
+		So(sample.Add(1), ShouldBeNil)
+		other := usage.With("host1", "4")
+		So(sample.Add(1), ShouldBeError) // sample is single-use (and isn't reused by following With calls)
+		So(other.Add(1), ShouldBeNil)
+		So(usage.Add(1), ShouldBeError)
+
+		total := ctx.Metric("/coll/total/count")
+		So(total.Err(), ShouldBeNil)
+		So(total.Add(1), ShouldBeNil)
+
+		So(ctx.Metric("/coll/[hosts]/cpu/[core]/usage").Err(), ShouldBeError)
+		So(ctx.Metric("/coll/[host]/cpu/[core]/unknown").Add(1), ShouldBeError)
+	})
+
+	return nil
+}
+
+func (s *SuiteT) TestCollectorWithMetricHandles() {
+	// Arrange
+	jsonConfig := []byte(`{}`)
+	mtsSelector := []string{
+		"/coll/host1/**",
+		"/coll/total/*",
+	}
+
+	collector := &collectorWithMetricHandles{t: s.T()}
+	ln := s.startCollector(collector)
+	s.startClient(ln.Addr().String())
+
+	Convey("Validate metrics added using handles contain proper namespaces and metadata", s.T(), func() {
+		_, _ = s.sendLoad("task-1", jsonConfig, mtsSelector)
+
+		mts, err := s.sendCollect("task-1")
+		So(err, ShouldBeNil)
+		So(len(mts.MetricSet), ShouldEqual, 6)
+
+		So(mts.MetricSet[0].Namespace[1].Name, ShouldEqual, "host")
+		So(mts.MetricSet[0].Namespace[1].Value, ShouldEqual, "host1")
+		So(mts.MetricSet[0].Namespace[1].Description, ShouldEqual, "host name")
+		So(mts.MetricSet[0].Namespace[3].Name, ShouldEqual, "core")
+		So(mts.MetricSet[0].Namespace[3].Value, ShouldEqual, "0")
+		So(mts.MetricSet[0].Unit, ShouldEqual, "%")
+		So(mts.MetricSet[1].Tags, ShouldContainKey, "k")
+		So(mts.MetricSet[2].Namespace[3].Value, ShouldEqual, "cpu/2")
+		So(mts.MetricSet[3].Namespace[3].Value, ShouldEqual, "3")
+		So(mts.MetricSet[4].Namespace[3].Value, ShouldEqual, "4")
+		So(mts.MetricSet[5].Namespace[2].Value, ShouldEqual, "count")
+
+		_, _ = s.sendKill()
+	})
+}
+
+/*****************************************************************************/
+
+type collectorWithParallelCollection struct {
+	running    int32
+	maxRunning int32
+
+	collectCtx plugin.CollectContext
+}
+
+func (c *collectorWithParallelCollection) PluginDefinition(ctx plugin.CollectorDefinition) error {
+	ctx.DefineMetric("/coll/[target]/up", "", true, "target availability")
+	return ctx.DefineCollectConcurrency(2)
+}
+
+func (c *collectorWithParallelCollection) Collect(ctx plugin.CollectContext) error {
+	c.collectCtx = ctx
+
+	for i := 0; i < 6; i++ {
+		target := fmt.Sprintf("target%d", i)
+
+		err := ctx.Go(func(ctx plugin.CollectContext) error {
+			running := atomic.AddInt32(&c.running, 1)
+			defer atomic.AddInt32(&c.running, -1)
+
+			if ctx.Go(func(plugin.CollectContext) error { return nil }) == nil {
+				return fmt.Errorf("goroutine shouldn't be started from other goroutine")
+			}
+
+			for {
+				maxRunning := atomic.LoadInt32(&c.maxRunning)
+				if running <= maxRunning || atomic.CompareAndSwapInt32(&c.maxRunning, maxRunning, running) {
+					break
+				}
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			switch target {
+			case "target4":
+				return fmt.Errorf("%s is unreachable", target)
+			case "target5":
+				_ = ctx.AddMetric(fmt.Sprintf("/coll/%s/up", target), 0) // metrics added before panic are kept
+				panic(fmt.Sprintf("%s has crashed", target))
+			}
+
+			return ctx.AddMetric(fmt.Sprintf("/coll/%s/up", target), 1)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SuiteT) TestCollectorWithParallelCollection() {
+	// Arrange
+	jsonConfig := []byte(`{}`)
+	mtsSelector := []string{"/coll/*/up"}
+
+	collector := &collectorWithParallelCollection{}
+	ln := s.startCollector(collector)
+	s.startClient(ln.Addr().String())
+
+	Convey("Validate metrics are gathered by goroutines started with CollectContext.Go", s.T(), func() {
+		_, _ = s.sendLoad("task-1", jsonConfig, mtsSelector)
+
+		mts, err := s.sendCollect("task-1")
+		So(err, ShouldBeNil)
+		So(len(mts.MetricSet), ShouldEqual, 5)
+		So(atomic.LoadInt32(&collector.maxRunning), ShouldEqual, 2)
+
+		So(len(mts.Warnings), ShouldEqual, 2)
+		warnings := []string{mts.Warnings[0].Message, mts.Warnings[1].Message}
+		So(warnings, ShouldContain, "user-defined goroutine has ended with error: target4 is unreachable")
+		So(warnings, ShouldContain, "user-defined goroutine has ended with panic: target5 has crashed")
+
+		So(collector.collectCtx.Go(func(plugin.CollectContext) error { return nil }), ShouldBeError)
+
+		_, _ = s.sendKill()
+	})
+}
+
+/*****************************************************************************/
+
+type collectorWithTracing struct{}
+
+func (c *collectorWithTracing) Collect(ctx plugin.CollectContext) error {
+	_, span := otel.Tracer("collector-with-tracing").Start(ctx.RawContext(), "poll")
+	defer span.End()
+
+	return ctx.AddMetric("/trace/up", 1)
+}
+
+func (s *SuiteT) TestCollectorWithTracing() {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prevProvider)
+
+	ln := s.startCollector(&collectorWithTracing{})
+	s.startClient(ln.Addr().String())
+
+	Convey("Validate spans are created for requested operations", s.T(), func() {
+		_, _ = s.sendLoad("task-1", []byte(`{}`), nil)
+
+		mts, err := s.sendCollect("task-1")
+		So(err, ShouldBeNil)
+		So(len(mts.MetricSet), ShouldEqual, 1)
+
+		_, _ = s.sendKill()
+
+		spans := map[string]sdktrace.ReadOnlySpan{}
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+
+		So(spans, ShouldContainKey, "Load")
+		So(spans, ShouldContainKey, "Collect")
+		So(spans, ShouldContainKey, "poll")
+
+		collectSpan := spans["Collect"]
+		So(collectSpan.Attributes(), ShouldContain, tracing.TaskIDKey.String("task-1"))
+		So(collectSpan.Attributes(), ShouldContain, tracing.MetricsCountKey.Int(1))
+
+		// span created by plugin author is a child of Collect span
+		So(spans["poll"].Parent().SpanID(), ShouldEqual, collectSpan.SpanContext().SpanID())
+	})
+}
+
+type streamingCollectorWithTracing struct {
+	streamingCollectorWithMetrics
+
+	collectCtx        plugin.CollectContext
+	collectCtxSpanIDs []trace.SpanID // span seen by collect context during unload
+}
+
+func (c *streamingCollectorWithTracing) StreamingCollect(ctx plugin.CollectContext) error {
+	c.collectCtx = ctx
+	return c.streamingCollectorWithMetrics.StreamingCollect(ctx)
+}
+
+func (c *streamingCollectorWithTracing) Unload(ctx plugin.Context) error {
+	_, span := otel.Tracer("collector-with-tracing").Start(ctx.RawContext(), "cleanup")
+	defer span.End()
+
+	c.collectCtxSpanIDs = append(c.collectCtxSpanIDs, trace.SpanFromContext(c.collectCtx.RawContext()).SpanContext().SpanID())
+	return nil
+}
+
+func (s *SuiteT) TestStreamingCollectorWithTracing() {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prevProvider)
+
+	collector := &streamingCollectorWithTracing{}
+	ln := s.startStreamingCollector(collector)
+	s.startClient(ln.Addr().String())
+
+	Convey("Validate that span of unload request doesn't replace span of running streaming collect", s.T(), func() {
+		_, _ = s.sendLoad("task-1", []byte(`{}`), nil)
+
+		errCollectCh := make(chan error, 1)
+		go func() {
+			_, err := s.sendCollect("task-1")
+			errCollectCh <- err
+		}()
+
+		time.Sleep(2 * time.Second) // a few chunks are sent before unload
+		_, err := s.sendUnload("task-1")
+		So(err, ShouldBeNil)
+
+		select {
+		case <-errCollectCh:
+		case <-time.After(expectedUnloadTimeout):
+			s.T().Fatal("streaming collect should have been ended")
+		}
+
+		spans := map[string]sdktrace.ReadOnlySpan{}
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+
+		So(spans, ShouldContainKey, "Collect")
+		So(spans, ShouldContainKey, "Unload")
+		So(spans, ShouldContainKey, "cleanup")
+
+		So(len(spans["Collect"].Events()), ShouldBeGreaterThan, 0)
+		So(spans["Unload"].Events(), ShouldBeEmpty)
+		So(spans["cleanup"].Parent().SpanID(), ShouldEqual, spans["Unload"].SpanContext().SpanID())
+		So(collector.collectCtxSpanIDs, ShouldHaveLength, 1)
+		So(collector.collectCtxSpanIDs[0], ShouldNotEqual, spans["Unload"].SpanContext().SpanID())
+	})
+}
+
+/*****************************************************************************/
+
+type collectorWithUnload struct {
+	unloadCalls int32
+}
+
+func (c *collectorWithUnload) Collect(ctx plugin.CollectContext) error {
+	time.Sleep(500 * time.Millisecond)
+	return ctx.AddMetric("/shutdown/up", 1)
+}
+
+func (c *collectorWithUnload) Unload(ctx plugin.Context) error {
+	atomic.AddInt32(&c.unloadCalls, 1)
+	return nil
+}
+
+func (s *SuiteT) TestGracefulShutdown() {
+	// Arrange
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	collector := &collectorWithUnload{}
+	ln, _ := net.Listen("tcp", "127.0.0.1:")
+	errShutdownCh := make(chan error, 1)
+
+	go func() {
+		statsController, _ := stats.NewEmptyController()
+		contextManager := proxy.NewContextManager(context.Background(), types.NewCollector("test-collector", "1.0.0", collector), statsController)
+		errShutdownCh <- service.StartCollectorGRPC(ctx, grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize, service.DefaultShutdownTimeout, nil)
+	}()
+
+	s.startClient(ln.Addr().String())
+
+	Convey("Validate that requests are completed and tasks are unloaded when shutdown is requested", s.T(), func() {
+		_, _ = s.sendLoad("task-1", []byte(`{}`), nil)
+		_, _ = s.sendLoad("task-2", []byte(`{}`), nil)
+
+		errCollectCh := make(chan error, 1)
+		go func() {
+			mts, err := s.sendCollect("task-1")
+			if err == nil && len(mts.MetricSet) != 1 {
+				err = fmt.Errorf("unexpected number of metrics: %d", len(mts.MetricSet))
+			}
+			errCollectCh <- err
+		}()
+
+		time.Sleep(100 * time.Millisecond) // be sure that collect is in progress
+
+		// Act
+		cancelFn()
+
+		// Assert
+		select {
+		case err := <-errShutdownCh:
+			So(err, ShouldBeNil)
+		case <-time.After(expectedGracefulShutdownTimeout):
+			s.T().Fatal("plugin should have been ended")
+		}
+
+		So(<-errCollectCh, ShouldBeNil)
+		So(atomic.LoadInt32(&collector.unloadCalls), ShouldEqual, 2)
+	})
+}
+
+func (s *SuiteT) TestGracefulShutdownOfStreamingCollector() {
+	// Arrange
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	collector := &streamingCollector{}
+	ln, _ := net.Listen("tcp", "127.0.0.1:")
+	errShutdownCh := make(chan error, 1)
+
+	go func() {
+		statsController, _ := stats.NewEmptyController()
+		contextManager := proxy.NewContextManager(context.Background(), types.NewStreamingCollector("test-collector", "1.0.0", collector), statsController)
+		errShutdownCh <- service.StartCollectorGRPC(ctx, grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize, service.DefaultShutdownTimeout, nil)
+	}()
+
+	s.startClient(ln.Addr().String())
+
+	Convey("Validate that streaming is stopped when shutdown is requested", s.T(), func() {
+		_, _ = s.sendLoad("task-1", []byte(`{}`), nil)
+
+		errCollectCh := make(chan error, 1)
+		go func() {
+			_, err := s.sendCollect("task-1")
+			errCollectCh <- err
+		}()
+
+		time.Sleep(100 * time.Millisecond) // be sure that streaming is in progress
+
+		// Act
+		cancelFn()
+
+		// Assert
+		select {
+		case err := <-errShutdownCh:
+			So(err, ShouldBeNil)
+		case <-time.After(expectedUnloadTimeout):
+			s.T().Fatal("plugin should have been ended")
+		}
+
+		So(<-errCollectCh, ShouldBeNil)
+		So(collector.completed, ShouldBeTrue)
+	})
+}
+
+/*****************************************************************************/
+
+func (s *SuiteT) TestRunCollector() {
+	Convey("Validate that collector run by RunCollector returns errors instead of terminating process", s.T(), func() {
+		Convey("when options are invalid", func() {
+			err := RunCollector(context.Background(), &collectorWithUnload{}, "test-collector", "1.0.0",
+				WithArgs([]string{"-grpc-compression=lz4"}))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unsupported GRPC compression")
+		})
+
+		Convey("when plugin is stopped by cancelling context", func() {
+			// Arrange
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+
+			socketPath := filepath.Join(s.T().TempDir(), "collector.sock")
+			collector := &collectorWithUnload{}
+
+			errRunCh := make(chan error, 1)
+			go func() {
+				errRunCh <- RunCollector(ctx, collector, "test-collector", "1.0.0",
+					WithArgs(nil), WithGRPCSocket(socketPath), WithGRPCPingTimeout(0, 0))
+			}()
+
+			for i := 0; i < 50; i++ {
+				if _, err := os.Stat(socketPath); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			s.startClient("unix://" + socketPath)
+
+			_, errLoad := s.sendLoad("task-1", []byte(`{}`), nil)
+			So(errLoad, ShouldBeNil)
+
+			info, errStat := os.Stat(socketPath)
+			So(errStat, ShouldBeNil)
+			So(info.Mode()&os.ModeSocket, ShouldNotEqual, 0)
+			So(info.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+
+			entries, _ := os.ReadDir(filepath.Dir(socketPath))
+			So(len(entries), ShouldEqual, 1) // private directory used to create socket is removed
+
+			// Act
+			cancelFn()
+
+			// Assert
+			select {
+			case err := <-errRunCh:
+				So(err, ShouldBeNil)
+			case <-time.After(expectedGracefulShutdownTimeout):
+				s.T().Fatal("plugin should have been ended")
+			}
+
+			So(atomic.LoadInt32(&collector.unloadCalls), ShouldEqual, 1)
+
+			_, errStat = os.Stat(socketPath)
+			So(os.IsNotExist(errStat), ShouldBeTrue)
+		})
+	})
+}
+
+/*****************************************************************************/
+
+func (s *SuiteT) TestPrometheusExporter() {
+	Convey("Validate that collector run as Prometheus exporter collects metrics on each scrape", s.T(), func() {
+		// Arrange
+		ctx, cancelFn := context.WithCancel(context.Background())
+		defer cancelFn()
+
+		ln, _ := net.Listen("tcp", "127.0.0.1:")
+		port := ln.Addr().(*net.TCPAddr).Port
+		_ = ln.Close()
+
+		collector := &collectorWithUnload{}
+
+		errRunCh := make(chan error, 1)
+		go func() {
+			errRunCh <- RunCollector(ctx, collector, "test-collector", "1.0.0",
+				WithArgs(nil), WithPrometheusExporter(port, `{}`, []string{"/shutdown/*"}))
+		}()
+
+		// Act
+		var resp *http.Response
+		var err error
+		for i := 0; i < 50; i++ {
+			resp, err = http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// Assert
+		So(err, ShouldBeNil)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+		So(resp.Header.Get("Content-Type"), ShouldStartWith, "text/plain")
+		So(string(body), ShouldContainSubstring, "\nshutdown_up 1\n")
+		So(string(body), ShouldContainSubstring, "\nsnap_exporter_collect_success 1\n")
+
+		// Act
+		cancelFn()
+
+		// Assert
+		select {
+		case err := <-errRunCh:
+			So(err, ShouldBeNil)
+		case <-time.After(expectedGracefulShutdownTimeout):
+			s.T().Fatal("plugin should have been ended")
+		}
+
+		So(atomic.LoadInt32(&collector.unloadCalls), ShouldEqual, 1)
+	})
+
+	Convey("Validate that streaming collector can't be run as Prometheus exporter", s.T(), func() {
+		err := RunStreamingCollector(context.Background(), &streamingCollectorWithMetrics{}, "test-collector", "1.0.0",
+			WithArgs(nil), WithPrometheusExporter(0, `{}`, nil))
+
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "streaming collector can't be run as prometheus exporter")
+	})
+}
+
+/*****************************************************************************/
+
+type collectorWithFailingCollect struct {
+	collectCalls int32
+}
+
+func (c *collectorWithFailingCollect) Collect(ctx plugin.CollectContext) error {
+	calls := atomic.AddInt32(&c.collectCalls, 1)
+	_ = ctx.AddMetric("/debug/calls", calls)
+
+	if calls == 1 {
+		return fmt.Errorf("backend unavailable")
+	}
+	return nil
+}
+
+type streamingCollectorWithMetrics struct{}
+
+func (c *streamingCollectorWithMetrics) StreamingCollect(ctx plugin.CollectContext) error {
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(10 * time.Millisecond):
+			_ = ctx.AddMetric("/debug/stream", i)
+		}
+	}
+}
+
+// streamingCollectorWithChunks sends different series in each chunk
+type streamingCollectorWithChunks struct{}
+
+func (c *streamingCollectorWithChunks) StreamingCollect(ctx plugin.CollectContext) error {
+	for i := 0; ; i++ {
+		_ = ctx.AddMetric("/debug/stream", i, plugin.MetricTag("chunk", strconv.Itoa(i)))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(1200 * time.Millisecond): // longer than interval of sending chunks
+		}
+	}
+}
+
+func (s *SuiteT) TestDebugMode() {
+	Convey("Validate that collector run in debug mode writes metrics to sinks", s.T(), func() {
+		sinkPath := filepath.Join(s.T().TempDir(), "metrics.json")
+
+		readSink := func() string {
+			content, _ := os.ReadFile(sinkPath)
+			return string(content)
+		}
+
+		Convey("when collect fails, task is collected again and error is returned", func() {
+			// Arrange
+			collector := &collectorWithFailingCollect{}
+
+			// Act
+			err := RunCollector(context.Background(), collector, "test-collector", "1.0.0",
+				WithArgs([]string{"-debug-mode", "-debug-collect-counts=2", "-debug-collect-interval=10ms", "-sink=file", "-sink-file-path=" + sinkPath}))
+
+			// Assert
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "backend unavailable")
+			So(atomic.LoadInt32(&collector.collectCalls), ShouldEqual, 2)
+
+			So(readSink(), ShouldNotContainSubstring, `"namespace":"/debug/calls","value":1`) // metrics of failed collect are dropped
+			So(readSink(), ShouldContainSubstring, `"namespace":"/debug/calls","value":2`)
+		})
+
+		Convey("when streaming collector sends metrics, they are written before collect is completed", func() {
+			// Arrange
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+
+			errRunCh := make(chan error, 1)
+			go func() {
+				errRunCh <- RunStreamingCollector(ctx, &streamingCollectorWithMetrics{}, "test-collector", "1.0.0",
+					WithArgs([]string{"-debug-mode", "-sink=file", "-sink-file-path=" + sinkPath}))
+			}()
+
+			// Act
+			for i := 0; i < 50 && readSink() == ""; i++ {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			// Assert
+			So(readSink(), ShouldContainSubstring, `"namespace":"/debug/stream"`)
+
+			// Act
+			cancelFn()
+
+			// Assert
+			select {
+			case err := <-errRunCh:
+				So(err, ShouldBeNil)
+			case <-time.After(expectedGracefulShutdownTimeout):
+				s.T().Fatal("plugin should have been ended")
+			}
+		})
+
+		Convey("when streaming collector sends metrics, series of all chunks are exposed by prometheus sink", func() {
+			// Arrange
+			ctx, cancelFn := context.WithCancel(context.Background())
+			defer cancelFn()
+
+			ln, _ := net.Listen("tcp", "127.0.0.1:")
+			port := ln.Addr().(*net.TCPAddr).Port
+			_ = ln.Close()
+
+			errRunCh := make(chan error, 1)
+			go func() {
+				errRunCh <- RunStreamingCollector(ctx, &streamingCollectorWithChunks{}, "test-collector", "1.0.0",
+					WithArgs([]string{"-debug-mode", "-sink=prometheus", "-plugin-ip=127.0.0.1", fmt.Sprintf("-sink-prometheus-port=%d", port)}))
+			}()
+
+			scrape := func() string {
+				resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+				if err != nil {
+					return ""
+				}
+				defer resp.Body.Close()
+
+				body, _ := io.ReadAll(resp.Body)
+				return string(body)
+			}
+
+			// Act
+			for i := 0; i < 100 && !strings.Contains(scrape(), `chunk="2"`); i++ {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			// Assert
+			body := scrape()
+			So(body, ShouldContainSubstring, `debug_stream{chunk="0"} 0`)
+			So(body, ShouldContainSubstring, `debug_stream{chunk="1"} 1`)
+			So(body, ShouldContainSubstring, `debug_stream{chunk="2"} 2`)
+
+			// Act
+			cancelFn()
+
+			// Assert
+			select {
+			case err := <-errRunCh:
+				So(err, ShouldBeNil)
+			case <-time.After(expectedGracefulShutdownTimeout):
+				s.T().Fatal("plugin should have been ended")
+			}
+		})
+	})
+}
@@ -0,0 +1,93 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile sets flags to values read from YAML (or JSON) file. Keys are names of flags, ie:
+//
+//	grpc-port: 50123
+//	tls: true
+//	log-level: debug
+//
+// Flags provided in command-line are not changed.
+func applyConfigFile(flagParser *flag.FlagSet, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read config file: %v", err)
+	}
+
+	values := map[string]interface{}{}
+	err = yaml.Unmarshal(content, &values)
+	if err != nil {
+		return fmt.Errorf("can't parse config file %s: %v", path, err)
+	}
+
+	setInCmdLine := map[string]bool{}
+	flagParser.Visit(func(f *flag.Flag) {
+		setInCmdLine[f.Name] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if flagParser.Lookup(name) == nil {
+			return fmt.Errorf("unknown option in config file %s: %s", path, name)
+		}
+
+		if setInCmdLine[name] {
+			continue
+		}
+
+		switch v := values[name].(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("invalid value of option %s in config file %s: should be a scalar", name, path)
+		case nil:
+			continue
+		default:
+			err = flagParser.Set(name, fmt.Sprint(v))
+			if err != nil {
+				return fmt.Errorf("invalid value of option %s in config file %s: %v", name, path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func lookupEnvOption(environ []string, key string) string {
+	for _, e := range environ {
+		pair := strings.SplitN(e, "=", 2)
+		if len(pair) == 2 && pair[0] == EnvOptionPrefix+key {
+			return pair[1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,155 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/collector/proxy"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/exposition"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+const (
+	exporterCollectSuccessMetric  = "snap_exporter_collect_success"
+	exporterCollectDurationMetric = "snap_exporter_collect_duration_seconds"
+)
+
+// prometheusExporter requests collection of all preconfigured tasks on each scrape of /metrics
+type prometheusExporter struct {
+	ctxManager *proxy.ContextManager
+	tasks      []standaloneTask
+	withTaskID bool
+
+	scrapeMutex sync.Mutex // task can't be collected concurrently
+	logF        logrus.FieldLogger
+}
+
+func startCollectorAsExporter(ctx context.Context, ctxManager *proxy.ContextManager, opt *plugin.Options, ln net.Listener) error {
+	tasks, err := standaloneTasks(opt)
+	if err != nil {
+		return err
+	}
+
+	for i, task := range tasks {
+		errLoad := ctxManager.LoadTask(ctx, task.id, task.config, task.filter)
+		if errLoad != nil {
+			_ = unloadStandaloneTasks(ctx, ctxManager, tasks[:i])
+			return fmt.Errorf("couldn't load a task %s for prometheus exporter: %w", task.id, errLoad)
+		}
+	}
+
+	e := &prometheusExporter{
+		ctxManager: ctxManager,
+		tasks:      tasks,
+		withTaskID: len(tasks) > 1,
+		logF:       logger(ctx).WithField("service", "prometheus-exporter"),
+	}
+
+	h := http.NewServeMux()
+	h.HandleFunc("GET /metrics", e.handle)
+	srv := &http.Server{Handler: h}
+
+	e.logF.Infof("Running prometheus exporter on address %s", ln.Addr())
+
+	serveErrCh := make(chan error, 1)
+	go func() {
+		serveErrCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancelFn := context.WithTimeout(context.Background(), opt.ShutdownTimeout)
+		defer cancelFn()
+
+		err = srv.Shutdown(shutdownCtx)
+	case err = <-serveErrCh:
+	}
+
+	errUnload := unloadStandaloneTasks(context.WithoutCancel(ctx), ctxManager, tasks)
+	if errUnload != nil {
+		return fmt.Errorf("couldn't unload a task of prometheus exporter: %w", errUnload)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("prometheus exporter stopped: %w", err)
+	}
+
+	return nil
+}
+
+func (e *prometheusExporter) handle(w http.ResponseWriter, r *http.Request) {
+	e.scrapeMutex.Lock()
+	defer e.scrapeMutex.Unlock()
+
+	var mts []*types.Metric
+
+	for i := range e.tasks {
+		task := &e.tasks[i]
+
+		start := time.Now()
+		var taskMts []*types.Metric
+		errCollect := collectStandaloneTask(r.Context(), e.ctxManager, task, false, func(mts []*types.Metric, _ []types.Warning) {
+			taskMts = mts
+		})
+		duration := time.Since(start)
+
+		success := 1
+		if errCollect != nil {
+			e.logF.WithError(errCollect).WithField("task-id", task.id).Warn("Error occurred during metrics collection")
+			success = 0
+		}
+
+		taskMts = append(taskMts,
+			exporterMetric(exporterCollectSuccessMetric, success, "Whether collect request triggered by the scrape succeeded (1) or failed (0)"),
+			exporterMetric(exporterCollectDurationMetric, duration.Seconds(), "Duration of collect request triggered by the scrape"),
+		)
+
+		if e.withTaskID {
+			taskMts = withTaskLabel(task.id, taskMts)
+		}
+
+		mts = append(mts, taskMts...)
+	}
+
+	w.Header().Set("Content-Type", exposition.ContentType)
+	duplicates, err := exposition.WriteText(w, mts)
+	if len(duplicates) > 0 {
+		e.logF.WithField("series", duplicates).Warn("Metrics with the same name and labels as other ones were omitted")
+	}
+	if err != nil {
+		e.logF.WithError(err).Warn("Can't write scrape response")
+	}
+}
+
+func exporterMetric(name string, value interface{}, description string) *types.Metric {
+	return &types.Metric{
+		Namespace_:   []types.NamespaceElement{{Value_: name}},
+		Value_:       value,
+		Type_:        plugin.GaugeType,
+		Description_: description,
+		Timestamp_:   time.Now(),
+	}
+}
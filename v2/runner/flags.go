@@ -21,6 +21,8 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"net/url"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -33,10 +35,19 @@ import (
 )
 
 const (
-	defaultPluginIP  = "127.0.0.1"
-	defaultGRPCPort  = 0
-	defaultPProfPort = 0
-	defaultStatsPort = 0
+	defaultPluginIP     = "127.0.0.1"
+	defaultGRPCPort     = 0
+	defaultPProfPort    = 0
+	defaultStatsPort    = 0
+	defaultDebugAPIPort = 0
+	defaultHealthPort   = 0
+
+	defaultGRPCSocketPermissions = 0600
+
+	defaultTLSReloadInterval = 1 * time.Minute
+	defaultTLSMinVersion     = "1.2"
+
+	defaultOTelSamplingRatio = 1.0
 
 	defaultConfig = "{}"
 	defaultFilter = ""
@@ -45,11 +56,18 @@ const (
 	defaultCollectCount     = 1
 	defaultCollectChunkSize = 100
 
+	defaultSinks              = sinkPrint
+	defaultSinkFileMaxSize    = 100 // MB
+	defaultSinkFileMaxBackups = 3
+	defaultPrometheusPort     = 9464
+
 	defaultLogLevel = logrus.WarnLevel
 
 	filterSeparator = ";"
 
 	EnvOptionPrefix = "SNAP_PLUGIN_OPT_"
+
+	envConfigFileKey = "CONFIG_FILE"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -62,6 +80,10 @@ func newFlagParser(name string, pType types.PluginType, opt *plugin.Options) *fl
 		"version", false,
 		"Print version of plugin")
 
+	flagParser.StringVar(&opt.ConfigFile,
+		"config-file", "",
+		fmt.Sprintf("Path to YAML/JSON file containing values of options (ie. grpc-port: 50123), overridden by command-line and %s<OPTION> variables", EnvOptionPrefix))
+
 	flagParser.StringVar(&opt.PluginIP,
 		"plugin-ip", defaultPluginIP,
 		"IP Address on which GRPC server will be served")
@@ -70,6 +92,15 @@ func newFlagParser(name string, pType types.PluginType, opt *plugin.Options) *fl
 		"grpc-port", defaultGRPCPort,
 		"Port on which GRPC server will be served")
 
+	flagParser.StringVar(&opt.GRPCSocket,
+		"grpc-socket", "",
+		"Path to Unix domain socket on which GRPC server will be served (instead of IP and port)")
+
+	opt.GRPCSocketPermissions = defaultGRPCSocketPermissions
+	flagParser.Var(&fileModeHandler{mode: &opt.GRPCSocketPermissions},
+		"grpc-socket-permissions",
+		"Permissions of Unix domain socket file (octal, ie. 0660)")
+
 	flagParser.DurationVar(&opt.GRPCPingTimeout,
 		"grpc-ping-timeout", service.DefaultPingTimeout,
 		"Deadline for receiving single ping messages")
@@ -78,6 +109,50 @@ func newFlagParser(name string, pType types.PluginType, opt *plugin.Options) *fl
 		"grpc-ping-max-missed", service.DefaultMaxMissingPingCounter,
 		"Number of missed ping messages after which plugin should exit")
 
+	flagParser.DurationVar(&opt.ShutdownTimeout,
+		"shutdown-timeout", service.DefaultShutdownTimeout,
+		"Deadline for completing requests and unloading tasks when plugin is terminated (SIGTERM, SIGINT)")
+
+	flagParser.StringVar(&opt.GRPCCompression,
+		"grpc-compression", service.CompressionNone,
+		fmt.Sprintf("Compression of GRPC responses (%s), applied only when supported by client", strings.Join(service.SupportedCompressions, ", ")))
+
+	flagParser.IntVar(&opt.GRPCMaxRecvMsgSize,
+		"grpc-max-recv-msg-size", 0,
+		"Maximum size (in bytes) of GRPC message which can be received (0 - GRPC default)")
+
+	flagParser.IntVar(&opt.GRPCMaxSendMsgSize,
+		"grpc-max-send-msg-size", 0,
+		"Maximum size (in bytes) of GRPC message which can be sent (0 - GRPC default)")
+
+	flagParser.DurationVar(&opt.GRPCKeepaliveTime,
+		"grpc-keepalive-time", 0,
+		"Interval after which server pings idle client to check if connection is alive (0 - GRPC default)")
+
+	flagParser.DurationVar(&opt.GRPCKeepaliveTimeout,
+		"grpc-keepalive-timeout", 0,
+		"Time after which connection is closed when keepalive ping isn't acknowledged (0 - GRPC default)")
+
+	flagParser.DurationVar(&opt.GRPCKeepaliveMinTime,
+		"grpc-keepalive-min-time", 0,
+		"Minimum interval between keepalive pings sent by client, connection is closed when client pings more often (0 - GRPC default)")
+
+	flagParser.BoolVar(&opt.GRPCKeepalivePermitWithoutStream,
+		"grpc-keepalive-permit-without-stream", false,
+		"Allow client to send keepalive pings when there are no active streams")
+
+	flagParser.StringVar(&opt.OTelExporterEndpoint,
+		"otel-exporter-endpoint", "",
+		"Address (host:port) of OTLP/gRPC endpoint to which trace spans are exported (tracing is disabled when empty)")
+
+	flagParser.BoolVar(&opt.OTelExporterInsecure,
+		"otel-exporter-insecure", false,
+		"Disable TLS when connecting to OTLP endpoint")
+
+	flagParser.Float64Var(&opt.OTelSamplingRatio,
+		"otel-sampling-ratio", defaultOTelSamplingRatio,
+		"Ratio of sampled traces (0.0 - 1.0), applied when trace isn't started by snap")
+
 	allLogLevels := strings.Replace(fmt.Sprintf("%v", logrus.AllLevels), " ", ", ", -1)
 	flagParser.Var(&logLevelHandler{opt: opt},
 		"log-level",
@@ -103,6 +178,22 @@ func newFlagParser(name string, pType types.PluginType, opt *plugin.Options) *fl
 		"stats-port", defaultStatsPort,
 		"Port on which stats server will be available")
 
+	flagParser.BoolVar(&opt.EnableDebugAPI,
+		"enable-debug-api", false,
+		"Enable HTTP server exposing JSON endpoints to load tasks, collect or publish metrics (for debugging). Authentication token (if provided) is required in requests")
+
+	flagParser.IntVar(&opt.DebugAPIPort,
+		"debug-api-port", defaultDebugAPIPort,
+		"Port on which debug API server will be available")
+
+	flagParser.BoolVar(&opt.EnableHealthServer,
+		"enable-health-server", false,
+		"Enable HTTP server with liveness (/healthz) and readiness (/readyz) probes")
+
+	flagParser.IntVar(&opt.HealthPort,
+		"health-port", defaultHealthPort,
+		"Port on which health server will be available")
+
 	flagParser.BoolVar(&opt.UseAPIv2,
 		"plugin-api-v2", true,
 		"If a plugin supports multiple plugin API versions, set it to use v2")
@@ -121,7 +212,27 @@ func newFlagParser(name string, pType types.PluginType, opt *plugin.Options) *fl
 
 	flagParser.StringVar(&opt.TLSClientCAPath,
 		"root-cert-paths", "",
-		fmt.Sprintf("Path to CA root path certificate(s). Might also be provided as files or/and dirs separated with '%c'.", filepath.Separator))
+		fmt.Sprintf("Path to CA root path certificate(s). Might also be provided as files or/and dirs separated with '%c'. When empty, client certificates aren't verified.", filepath.Separator))
+
+	flagParser.DurationVar(&opt.TLSReloadInterval,
+		"tls-reload-interval", defaultTLSReloadInterval,
+		"Interval of checking if certificate, key and CA files were modified and should be reloaded (0 to disable)")
+
+	flagParser.StringVar(&opt.TLSMinVersion,
+		"tls-min-version", defaultTLSMinVersion,
+		"Minimum TLS version accepted by GRPC Server (1.2, 1.3)")
+
+	flagParser.StringVar(&opt.TLSCipherSuites,
+		"tls-cipher-suites", "",
+		"List of cipher suites accepted by GRPC Server, separated by comma (default list is used when empty)")
+
+	flagParser.StringVar(&opt.TLSClientSANs,
+		"tls-client-sans", "",
+		"List of accepted Subject Alternative Names of client certificate (DNS names, IPs, URIs or emails), separated by comma")
+
+	flagParser.StringVar(&opt.AuthTokenFile,
+		"auth-token-file", "",
+		fmt.Sprintf("Path to file containing token which has to be sent by client in each request (token might be also provided with %sAUTH_TOKEN)", EnvOptionPrefix))
 
 	// custom flags
 
@@ -142,6 +253,46 @@ func newFlagParser(name string, pType types.PluginType, opt *plugin.Options) *fl
 			"debug-collect-interval", defaultCollectInterval,
 			"Interval between consecutive collect requests")
 
+		flagParser.DurationVar(&opt.DebugCollectJitter,
+			"debug-collect-jitter", 0,
+			"Maximal random delay added to each scheduled collect request")
+
+		flagParser.StringVar(&opt.TaskFile,
+			"task-file", "",
+			"YAML file with task(s) executed in debug mode (format of -print-example-task, documents separated by ---)")
+
+		flagParser.StringVar(&opt.Sinks,
+			"sink", defaultSinks,
+			fmt.Sprintf("Destination(s) of metrics gathered in debug mode, separated by %s (%s)", sinkSeparator, strings.Join(availableSinks, sinkSeparator)))
+
+		flagParser.StringVar(&opt.SinkFilePath,
+			"sink-file-path", "",
+			"Path to file to which metrics are written (JSON lines) by file sink")
+
+		flagParser.IntVar(&opt.SinkFileMaxSize,
+			"sink-file-max-size", defaultSinkFileMaxSize,
+			"Maximal size (in MB) of file written by file sink, before it's rotated")
+
+		flagParser.IntVar(&opt.SinkFileMaxBackups,
+			"sink-file-max-backups", defaultSinkFileMaxBackups,
+			"Number of rotated files kept by file sink")
+
+		flagParser.StringVar(&opt.SinkHTTPURL,
+			"sink-http-url", "",
+			"URL to which metrics are sent (POST, JSON array) by http sink")
+
+		flagParser.IntVar(&opt.SinkPrometheusPort,
+			"sink-prometheus-port", defaultPrometheusPort,
+			"Port on which prometheus sink exposes metrics (/metrics)")
+
+		flagParser.BoolVar(&opt.PrometheusExporter,
+			"prometheus-exporter", false,
+			"Run collector (not streaming) as Prometheus exporter (task defined by -plugin-config/-plugin-filter or -task-file is collected on each scrape)")
+
+		flagParser.IntVar(&opt.PrometheusExporterPort,
+			"prometheus-exporter-port", 0,
+			fmt.Sprintf("Port on which Prometheus exporter exposes metrics (/metrics) (%d when not set)", defaultPrometheusPort))
+
 		flagParser.Uint64Var(&opt.CollectChunkSize,
 			"collect-chunk-size", defaultCollectChunkSize,
 			"Collected metrics chunk size")
@@ -188,14 +339,47 @@ func (l *logLevelHandler) Set(s string) error {
 	return nil
 }
 
+type fileModeHandler struct {
+	mode *os.FileMode
+}
+
+func (f *fileModeHandler) String() string {
+	if f.mode == nil {
+		return fmt.Sprintf("%#o", defaultGRPCSocketPermissions)
+	}
+
+	return fmt.Sprintf("%#o", uint32(*f.mode))
+}
+
+func (f *fileModeHandler) Set(s string) error {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > uint64(os.ModePerm) {
+		return fmt.Errorf("invalid file permissions: %s", s)
+	}
+
+	*f.mode = os.FileMode(mode)
+	return nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 func ParseCmdLineOptions(pluginName string, pluginType types.PluginType, args []string) (*plugin.Options, error) {
+	return parseOptions(pluginName, pluginType, args, nil, nil)
+}
+
+// parseOptions builds options from (in order of increasing precedence): defaults, values set by code (modifiers),
+// config file and command-line arguments. Config file might be also pointed by SNAP_PLUGIN_OPT_CONFIG_FILE
+// (which takes precedence over -config-file). Environment variables, applied by parseEnvOptions, take precedence over all of them.
+func parseOptions(pluginName string, pluginType types.PluginType, args []string, environ []string, modifiers []func(opt *plugin.Options)) (*plugin.Options, error) {
 	opt := &plugin.Options{
 		LogLevel: defaultLogLevel,
 	}
 
 	flagParser := newFlagParser(pluginName, pluginType, opt)
+
+	for _, modify := range modifiers {
+		modify(opt)
+	}
 	argsToParse := args[:]
 	if len(args) > 0 && strings.HasSuffix(args[0], ".py") {
 		// ignore first parameter if plugin is an interpreted code
@@ -212,25 +396,66 @@ func ParseCmdLineOptions(pluginName string, pluginType types.PluginType, args []
 		return opt, fmt.Errorf("unexpected option(s) provided: %v %v", v, len(v))
 	}
 
+	if envConfigFile := lookupEnvOption(environ, envConfigFileKey); envConfigFile != "" {
+		opt.ConfigFile = envConfigFile
+	}
+
+	if opt.ConfigFile != "" {
+		err = applyConfigFile(flagParser, opt.ConfigFile)
+		if err != nil {
+			return opt, err
+		}
+	}
+
 	return opt, nil
 }
 
+// ParseEnvOptions applies collector options set by environment variables (SNAP_PLUGIN_OPT_<OPTION>)
 func ParseEnvOptions(environ []string, opt *plugin.Options) (*plugin.Options, error) {
+	return parseEnvOptions(environ, types.PluginTypeCollector, opt)
+}
+
+// Plugin run as a thread shares environment with host and other plugins, so only these options can be set by env for it
+var threadEnvOptions = map[string]bool{
+	"COLLECT_CHUNK_SIZE": true,
+}
+
+// parseEnvOptions applies options set by environment variables. Only options defined for a given plugin type are
+// accepted, others are ignored (as unknown ones).
+// Options of plugin run as a thread (opt.AsThread) are limited to threadEnvOptions.
+func parseEnvOptions(environ []string, pluginType types.PluginType, opt *plugin.Options) (*plugin.Options, error) {
 	if opt == nil {
 		return nil, errors.New("empty options")
 	}
 
+	// Flags are bound to fields of options, so any of them can be set by name (SNAP_PLUGIN_OPT_GRPC_PORT -> grpc-port).
+	// Binding resets fields to default values, so current ones have to be restored.
+	current := *opt
+	flagParser := newFlagParser("env", pluginType, opt)
+	*opt = current
+
 	for _, e := range environ {
 		pair := strings.SplitN(e, "=", 2)
 
-		if strings.HasPrefix(pair[0], EnvOptionPrefix) {
+		if strings.HasPrefix(pair[0], EnvOptionPrefix) && len(pair) == 2 {
 			key := strings.TrimPrefix(pair[0], EnvOptionPrefix)
 			val := pair[1]
 
+			if opt.AsThread && !threadEnvOptions[key] {
+				continue
+			}
+
 			var err error
 			switch key {
-			case "COLLECT_CHUNK_SIZE":
-				opt.CollectChunkSize, err = strconv.ParseUint(val, 10, 0)
+			case "AUTH_TOKEN":
+				opt.AuthToken = val
+			case envConfigFileKey:
+				// already handled by parseOptions
+			default:
+				name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+				if flagParser.Lookup(name) != nil {
+					err = flagParser.Set(name, val)
+				}
 			}
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse %v: %w", key, err)
@@ -258,19 +483,75 @@ func ValidateOptions(opt *plugin.Options) error {
 		opt.PluginFilter = defaultFilter
 	}
 
+	if opt.Sinks == "" {
+		opt.Sinks = defaultSinks
+	}
+
+	if opt.SinkFileMaxSize == 0 {
+		opt.SinkFileMaxSize = defaultSinkFileMaxSize
+	}
+
+	if opt.SinkPrometheusPort == 0 {
+		opt.SinkPrometheusPort = defaultPrometheusPort
+	}
+
 	if opt.CollectChunkSize <= 0 {
 		opt.CollectChunkSize = defaultCollectChunkSize
 	}
 
+	if opt.AuthToken != "" && opt.AuthTokenFile != "" {
+		return fmt.Errorf("authentication token should be provided either by -auth-token-file or %sAUTH_TOKEN", EnvOptionPrefix)
+	}
+
+	if opt.OTelSamplingRatio < 0 || opt.OTelSamplingRatio > 1 {
+		return fmt.Errorf("OTel sampling ratio should be in range [0.0, 1.0]")
+	}
+
+	if !service.IsSupportedCompression(opt.GRPCCompression) {
+		return fmt.Errorf("unsupported GRPC compression: %s", opt.GRPCCompression)
+	}
+
+	if opt.GRPCMaxRecvMsgSize < 0 || opt.GRPCMaxSendMsgSize < 0 {
+		return fmt.Errorf("GRPC message size limit can't be negative")
+	}
+
+	if opt.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown timeout can't be negative")
+	}
+
+	if opt.GRPCKeepaliveTime < 0 || opt.GRPCKeepaliveTimeout < 0 || opt.GRPCKeepaliveMinTime < 0 {
+		return fmt.Errorf("GRPC keepalive parameters can't be negative")
+	}
+
+	if opt.GRPCSocket != "" && opt.GRPCPort != defaultGRPCPort {
+		return fmt.Errorf("-grpc-socket and -grpc-port flags can't be used together")
+	}
+
 	grpcIp := net.ParseIP(opt.PluginIP)
 	if grpcIp == nil {
 		return fmt.Errorf("GRPC IP contains invalid address")
 	}
 
+	if opt.EnableDebugAPI && !grpcIp.IsLoopback() && opt.AuthToken == "" && opt.AuthTokenFile == "" {
+		return fmt.Errorf("debug API can be served on non-loopback address (%s) only when authentication token is provided", opt.PluginIP)
+	}
+
 	if opt.EnableTLS {
 		if opt.TLSServerCertPath == "" || opt.TLSServerKeyPath == "" {
 			return fmt.Errorf("certificate and key path have to be provided when TLS is enabled")
 		}
+
+		if _, err := service.ParseTLSVersion(opt.TLSMinVersion); err != nil {
+			return err
+		}
+
+		if _, err := service.ParseCipherSuites(opt.TLSCipherSuites); err != nil {
+			return err
+		}
+
+		if opt.TLSReloadInterval < 0 {
+			return fmt.Errorf("TLS reload interval can't be negative")
+		}
 	}
 
 	if opt.PProfPort > 0 && !opt.EnableProfiling {
@@ -281,20 +562,89 @@ func ValidateOptions(opt *plugin.Options) error {
 		return fmt.Errorf("-enable-stats flag should be set when configuring stats port")
 	}
 
+	if opt.DebugAPIPort > 0 && !opt.EnableDebugAPI {
+		return fmt.Errorf("-enable-debug-api flag should be set when configuring debug API port")
+	}
+
+	if opt.HealthPort > 0 && !opt.EnableHealthServer {
+		return fmt.Errorf("-enable-health-server flag should be set when configuring health port")
+	}
+
 	if opt.EnableStatsServer && !opt.EnableStats {
 		return fmt.Errorf("-enable-stats should be set when -enable-stats-server=1")
 	}
 
+	if opt.PrometheusExporterPort > 0 && !opt.PrometheusExporter {
+		return fmt.Errorf("-prometheus-exporter flag should be set when configuring prometheus exporter port")
+	}
+
+	if opt.PrometheusExporter && opt.DebugMode {
+		return fmt.Errorf("-prometheus-exporter and -debug-mode flags can't be used together")
+	}
+
+	if !opt.DebugMode && !opt.PrometheusExporter && anyTaskFlagSet(opt) {
+		return fmt.Errorf("-debug-mode or -prometheus-exporter flag should be set when configuring task")
+	}
+
 	if !opt.DebugMode && anyDebugFlagSet(opt) {
 		return fmt.Errorf("-debug-mode flag should be set when configuring debug options")
 	}
 
+	if opt.TaskFile != "" && (opt.PluginConfig != defaultConfig || opt.PluginFilter != defaultFilter) {
+		return fmt.Errorf("-task-file flag can't be used together with -plugin-config or -plugin-filter")
+	}
+
+	if opt.DebugMode {
+		err := validateStandaloneOptions(opt)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func anyTaskFlagSet(opt *plugin.Options) bool {
+	return opt.PluginConfig != defaultConfig ||
+		opt.PluginFilter != defaultFilter ||
+		opt.TaskFile != ""
+}
+
 func anyDebugFlagSet(opt *plugin.Options) bool {
 	return opt.DebugCollectCounts != defaultCollectCount ||
 		opt.DebugCollectInterval != defaultCollectInterval ||
-		opt.PluginConfig != defaultConfig ||
-		opt.PluginFilter != defaultFilter
+		opt.DebugCollectJitter != 0 ||
+		opt.Sinks != defaultSinks ||
+		opt.SinkFilePath != "" ||
+		opt.SinkHTTPURL != ""
+}
+
+func validateStandaloneOptions(opt *plugin.Options) error {
+	if opt.DebugCollectJitter < 0 {
+		return fmt.Errorf("collect jitter can't be negative")
+	}
+
+	sinks, err := parseSinks(opt.Sinks)
+	if err != nil {
+		return err
+	}
+
+	for _, sink := range sinks {
+		switch sink {
+		case sinkFile:
+			if opt.SinkFilePath == "" {
+				return fmt.Errorf("-sink-file-path flag should be set when using file sink")
+			}
+			if opt.SinkFileMaxSize < 0 || opt.SinkFileMaxBackups < 0 {
+				return fmt.Errorf("file sink limits can't be negative")
+			}
+		case sinkHTTP:
+			u, err := url.Parse(opt.SinkHTTPURL)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("-sink-http-url flag should contain valid URL when using http sink")
+			}
+		}
+	}
+
+	return nil
 }
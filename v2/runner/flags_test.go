@@ -20,13 +20,19 @@
 package runner
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"google.golang.org/grpc"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -102,6 +108,161 @@ func TestParseCmdLineOptions(t *testing.T) {
 			shouldBeParsed: true,
 			shouldBeValid:  true,
 		},
+		{ // 13
+			inputCmdLine:   "--grpc-compression=zstd --grpc-max-recv-msg-size=8388608 --grpc-keepalive-min-time=10s --grpc-keepalive-permit-without-stream",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 14
+			inputCmdLine:   "--grpc-compression=lz4",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 15
+			inputCmdLine:   "--grpc-max-send-msg-size=-1",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 16
+			inputCmdLine:   "--grpc-socket=/tmp/plugin.sock --grpc-socket-permissions=0660",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 17
+			inputCmdLine:   "--grpc-socket=/tmp/plugin.sock --grpc-port=456",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 18
+			inputCmdLine:   "--grpc-socket-permissions=rw",
+			shouldBeParsed: false,
+			shouldBeValid:  false,
+		},
+		{ // 19
+			inputCmdLine:   "--tls --cert-path=a.crt --key-path=a.key --tls-min-version=1.3 --tls-cipher-suites=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 --tls-client-sans=agent.example.com",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 20
+			inputCmdLine:   "--tls --cert-path=a.crt --key-path=a.key --tls-min-version=1.0",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 21
+			inputCmdLine:   "--tls --cert-path=a.crt --key-path=a.key --tls-cipher-suites=TLS_RSA_WITH_RC4_128_SHA",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 22
+			inputCmdLine:   "--auth-token-file=/etc/plugin/token",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 23
+			inputCmdLine:   "--otel-exporter-endpoint=localhost:4317 --otel-exporter-insecure --otel-sampling-ratio=0.25",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 24
+			inputCmdLine:   "--otel-exporter-endpoint=localhost:4317 --otel-sampling-ratio=1.5",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 25
+			inputCmdLine:   "--shutdown-timeout=30s",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 26
+			inputCmdLine:   "--shutdown-timeout=-1s",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 27
+			inputCmdLine:   "--debug-api-port=5678",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 28
+			inputCmdLine:   "--enable-debug-api --debug-api-port=5678",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 29
+			inputCmdLine:   "--health-port=5679",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 30
+			inputCmdLine:   "--enable-health-server --health-port=5679",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 31
+			inputCmdLine:   "--sink=stdout,prometheus",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 32
+			inputCmdLine:   "--debug-mode --sink=stdout,prometheus --debug-collect-jitter=2s",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 33
+			inputCmdLine:   "--debug-mode --sink=console",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 34
+			inputCmdLine:   "--debug-mode --sink=file",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 35
+			inputCmdLine:   "--debug-mode --sink=file,http --sink-file-path=/tmp/metrics.json --sink-http-url=http://127.0.0.1:8080/metrics",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 36
+			inputCmdLine:   "--debug-mode --task-file=task.yaml --plugin-filter=/example/*",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 37
+			inputCmdLine:   "--prometheus-exporter-port=9500",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 38
+			inputCmdLine:   "--prometheus-exporter --prometheus-exporter-port=9500 --plugin-filter=/example/*",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 39
+			inputCmdLine:   "--prometheus-exporter --debug-mode",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 40
+			inputCmdLine:   "--prometheus-exporter --sink=stdout",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 41
+			inputCmdLine:   "--enable-debug-api --plugin-ip=0.0.0.0",
+			shouldBeParsed: true,
+			shouldBeValid:  false,
+		},
+		{ // 42
+			inputCmdLine:   "--enable-debug-api --plugin-ip=0.0.0.0 --auth-token-file=token",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
+		{ // 43
+			inputCmdLine:   "--enable-debug-api --plugin-ip=::1",
+			shouldBeParsed: true,
+			shouldBeValid:  true,
+		},
 	}
 
 	Convey("Validate that options can be parsed", t, func() {
@@ -156,6 +317,54 @@ func TestParseEnvOptions(t *testing.T) {
 			},
 			"",
 		},
+		{
+			"auth token",
+			[]string{
+				"SNAP_PLUGIN_OPT_AUTH_TOKEN=secret",
+				"OTHER_VAR=1",
+			},
+			&plugin.Options{},
+			&plugin.Options{
+				AuthToken: "secret",
+			},
+			"",
+		},
+		{
+			"any flag",
+			[]string{
+				"SNAP_PLUGIN_OPT_GRPC_PORT=50123",
+				"SNAP_PLUGIN_OPT_TLS=true",
+				"SNAP_PLUGIN_OPT_LOG_LEVEL=debug",
+				"SNAP_PLUGIN_OPT_SHUTDOWN_TIMEOUT=30s",
+			},
+			&plugin.Options{PluginIP: "127.0.0.1"},
+			&plugin.Options{
+				PluginIP:        "127.0.0.1",
+				GRPCPort:        50123,
+				EnableTLS:       true,
+				LogLevel:        logrus.DebugLevel,
+				ShutdownTimeout: 30 * time.Second,
+			},
+			"",
+		},
+		{
+			"unknown option",
+			[]string{
+				"SNAP_PLUGIN_OPT_UNKNOWN=1",
+			},
+			&plugin.Options{},
+			&plugin.Options{},
+			"",
+		},
+		{
+			"invalid value",
+			[]string{
+				"SNAP_PLUGIN_OPT_GRPC_PORT=port",
+			},
+			&plugin.Options{},
+			nil,
+			"failed to parse GRPC_PORT",
+		},
 	}
 
 	Convey("Validate that environmental variables are parsed and applied to options", t, func() {
@@ -175,3 +384,138 @@ func TestParseEnvOptions(t *testing.T) {
 		}
 	})
 }
+
+func TestParsePublisherEnvOptions(t *testing.T) {
+	Convey("Validate that options specific to collector can't be set by environmental variables for publisher", t, func() {
+		environ := []string{
+			"SNAP_PLUGIN_OPT_DEBUG_MODE=true",
+			"SNAP_PLUGIN_OPT_PROMETHEUS_EXPORTER=true",
+			"SNAP_PLUGIN_OPT_GRPC_PORT=50123",
+		}
+
+		opt, err := parseEnvOptions(environ, types.PluginTypePublisher, &plugin.Options{})
+		So(err, ShouldBeNil)
+		So(opt, ShouldResemble, &plugin.Options{GRPCPort: 50123})
+
+		opt, err = parseEnvOptions(environ, types.PluginTypeCollector, &plugin.Options{})
+		So(err, ShouldBeNil)
+		So(opt, ShouldResemble, &plugin.Options{GRPCPort: 50123, DebugMode: true, PrometheusExporter: true})
+	})
+}
+
+func TestParseThreadEnvOptions(t *testing.T) {
+	Convey("Validate that only chunk size can be set by environmental variables for plugin run as a thread", t, func() {
+		environ := []string{
+			"SNAP_PLUGIN_OPT_COLLECT_CHUNK_SIZE=15",
+			"SNAP_PLUGIN_OPT_GRPC_PORT=50123",
+			"SNAP_PLUGIN_OPT_DEBUG_MODE=true",
+			"SNAP_PLUGIN_OPT_AUTH_TOKEN=secret",
+		}
+
+		opt, err := parseEnvOptions(environ, types.PluginTypeCollector, &plugin.Options{AsThread: true, GRPCPort: 50001})
+		So(err, ShouldBeNil)
+		So(opt, ShouldResemble, &plugin.Options{AsThread: true, GRPCPort: 50001, CollectChunkSize: 15})
+	})
+}
+
+func TestOptionsPrecedence(t *testing.T) {
+	Convey("Validate that options are built from code, config file, command-line and env", t, func() {
+		configFile := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(configFile, []byte("grpc-port: 50001\nstats-port: 50002\npprof-port: 50003\nlog-level: info\n"), 0600)
+		So(err, ShouldBeNil)
+
+		modifiers := []func(opt *plugin.Options){
+			func(opt *plugin.Options) {
+				opt.GRPCPort = 40001
+				opt.StatsPort = 40002
+				opt.PProfPort = 40003
+				opt.PluginIP = "0.0.0.0"
+				opt.ConfigFile = configFile
+			},
+		}
+
+		// Act
+		opt, err := parseOptions("plugin", types.PluginTypeCollector, []string{"-stats-port=60002", "-pprof-port=60003"}, nil, modifiers)
+		So(err, ShouldBeNil)
+
+		opt, err = ParseEnvOptions([]string{"SNAP_PLUGIN_OPT_PPROF_PORT=70003"}, opt)
+		So(err, ShouldBeNil)
+
+		// Assert
+		So(opt.PluginIP, ShouldEqual, "0.0.0.0")        // code
+		So(opt.GRPCPort, ShouldEqual, 50001)            // config file
+		So(opt.LogLevel, ShouldEqual, logrus.InfoLevel) // config file
+		So(opt.StatsPort, ShouldEqual, 60002)           // command-line
+		So(opt.PProfPort, ShouldEqual, 70003)           // env
+		So(opt.TLSMinVersion, ShouldEqual, defaultTLSMinVersion)
+	})
+
+	Convey("Validate that config file may be pointed by env and JSON format is supported", t, func() {
+		configFile := filepath.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(configFile, []byte(`{"grpc-port": 50001, "tls-client-sans": "snap.local", "grpc-keepalive-time": "1m"}`), 0600)
+		So(err, ShouldBeNil)
+
+		opt, err := parseOptions("plugin", types.PluginTypeCollector, nil, []string{"SNAP_PLUGIN_OPT_CONFIG_FILE=" + configFile}, nil)
+
+		So(err, ShouldBeNil)
+		So(opt.GRPCPort, ShouldEqual, 50001)
+		So(opt.TLSClientSANs, ShouldEqual, "snap.local")
+		So(opt.GRPCKeepaliveTime, ShouldEqual, time.Minute)
+	})
+
+	Convey("Validate that config file pointed by env takes precedence over command-line", t, func() {
+		cmdConfigFile := filepath.Join(t.TempDir(), "cmd.yaml")
+		So(os.WriteFile(cmdConfigFile, []byte("grpc-port: 50001\n"), 0600), ShouldBeNil)
+		envConfigFile := filepath.Join(t.TempDir(), "env.yaml")
+		So(os.WriteFile(envConfigFile, []byte("grpc-port: 50002\n"), 0600), ShouldBeNil)
+
+		opt, err := parseOptions("plugin", types.PluginTypeCollector, []string{"-config-file=" + cmdConfigFile}, []string{"SNAP_PLUGIN_OPT_CONFIG_FILE=" + envConfigFile}, nil)
+
+		So(err, ShouldBeNil)
+		So(opt.ConfigFile, ShouldEqual, envConfigFile)
+		So(opt.GRPCPort, ShouldEqual, 50002)
+	})
+
+	Convey("Validate that invalid config file is reported", t, func() {
+		for _, content := range []string{
+			"unknown-option: 1",
+			"grpc-port: port",
+			"tls-cipher-suites: [a, b]",
+			"grpc-port: [",
+		} {
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			err := os.WriteFile(configFile, []byte(content), 0600)
+			So(err, ShouldBeNil)
+
+			_, err = parseOptions("plugin", types.PluginTypeCollector, []string{"-config-file=" + configFile}, nil, nil)
+			So(err, ShouldBeError)
+		}
+
+		_, err := parseOptions("plugin", types.PluginTypeCollector, []string{"-config-file=/not/existing.yaml"}, nil, nil)
+		So(err, ShouldBeError)
+	})
+}
+
+func TestGRPCHookOptions(t *testing.T) {
+	Convey("Validate that GRPC interceptors and server options are collected from all options", t, func() {
+		unary := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(ctx, req)
+		}
+		stream := func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, ss)
+		}
+
+		rc := newRunConfig([]Option{
+			WithGRPCUnaryInterceptors(unary),
+			WithGRPCPort(50123),
+			WithGRPCUnaryInterceptors(unary, unary),
+			WithGRPCStreamInterceptors(stream),
+			WithGRPCServerOptions(grpc.MaxRecvMsgSize(1024)),
+		})
+
+		So(rc.grpcHooks.UnaryInterceptors, ShouldHaveLength, 3)
+		So(rc.grpcHooks.StreamInterceptors, ShouldHaveLength, 1)
+		So(rc.grpcHooks.ServerOptions, ShouldHaveLength, 1)
+		So(rc.modifiers, ShouldHaveLength, 1)
+	})
+}
@@ -0,0 +1,45 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"google.golang.org/grpc"
+)
+
+// WithGRPCUnaryInterceptors registers interceptors called for each unary request handled by plugin
+// (ie. for custom authentication, tracing or auditing).
+// Interceptors are called in order of registration, after library ones (authentication).
+func WithGRPCUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(rc *runConfig) {
+		rc.grpcHooks.UnaryInterceptors = append(rc.grpcHooks.UnaryInterceptors, interceptors...)
+	}
+}
+
+// WithGRPCStreamInterceptors registers interceptors called for each streaming request handled by plugin (ie. Collect).
+func WithGRPCStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(rc *runConfig) {
+		rc.grpcHooks.StreamInterceptors = append(rc.grpcHooks.StreamInterceptors, interceptors...)
+	}
+}
+
+// WithGRPCServerOptions applies additional options to GRPC server.
+// Options are applied after the ones configured by command-line flags. Ignored when plugin is run in-process.
+func WithGRPCServerOptions(opts ...grpc.ServerOption) Option {
+	return func(rc *runConfig) {
+		rc.grpcHooks.ServerOptions = append(rc.grpcHooks.ServerOptions, opts...)
+	}
+}
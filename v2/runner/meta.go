@@ -17,10 +17,8 @@
 package runner
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/service"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
@@ -42,7 +40,13 @@ type meta struct {
 	GRPC struct {
 		IP         string // IP on which GRPC service is being served
 		Port       int    // Port on which GRPC service is being served
+		Socket     string // Unix domain socket on which GRPC service is being served (IP and Port are empty in that case)
 		TLSEnabled bool   // true if TLS is enabled
+
+		TokenAuthEnabled bool // true if each request has to contain authentication token (authorization: Bearer <token>)
+
+		Compression           string   // compression of responses requested by plugin (empty if disabled)
+		SupportedCompressions []string // compressions which might be used by client
 	}
 
 	Constraints struct {
@@ -60,12 +64,25 @@ type meta struct {
 		IP      string // IP on which stats service is being served
 		Port    int    // Port on which stats service is being served
 	}
-}
 
-func metaInformation(ctx context.Context, name string, version string, typ types.PluginType, opt *plugin.Options, r *resources, tasksLimit, instancesLimit int) []byte {
-	logF := logger(ctx).WithField("service", "meta")
+	DebugAPI struct {
+		Enabled bool   // true, if debug API server is enabled (started)
+		IP      string // IP on which debug API is being served
+		Port    int    // Port on which debug API is being served
+	}
+
+	Health struct {
+		Enabled bool   // true, if health server (liveness and readiness probes) is enabled (started)
+		IP      string // IP on which health service is being served
+		Port    int    // Port on which health service is being served
+	}
+}
 
+func metaInformation(name string, version string, typ types.PluginType, opt *plugin.Options, r *resources, tasksLimit, instancesLimit int) ([]byte, error) {
 	ip := r.grpcListenerAddr().IP.String()
+	if opt.GRPCSocket != "" {
+		ip = opt.PluginIP // used by profiling and stats servers
+	}
 
 	m := meta{}
 
@@ -75,9 +92,16 @@ func metaInformation(ctx context.Context, name string, version string, typ types
 	m.Plugin.Version = version
 	m.Plugin.Type = typ
 
-	m.GRPC.IP = ip
-	m.GRPC.Port = r.grpcListenerAddr().Port
+	if opt.GRPCSocket != "" {
+		m.GRPC.Socket = opt.GRPCSocket
+	} else {
+		m.GRPC.IP = ip
+		m.GRPC.Port = r.grpcListenerAddr().Port
+	}
 	m.GRPC.TLSEnabled = opt.EnableTLS
+	m.GRPC.TokenAuthEnabled = opt.AuthToken != "" || opt.AuthTokenFile != ""
+	m.GRPC.Compression = opt.GRPCCompression
+	m.GRPC.SupportedCompressions = service.SupportedCompressions
 
 	m.Constraints.TasksLimit = tasksLimit
 	m.Constraints.InstancesLimit = instancesLimit
@@ -93,13 +117,24 @@ func metaInformation(ctx context.Context, name string, version string, typ types
 		m.Stats.Port = r.statsListenerAddr().Port
 	}
 
+	m.DebugAPI.Enabled = opt.EnableDebugAPI
+	if opt.EnableDebugAPI {
+		m.DebugAPI.IP = ip
+		m.DebugAPI.Port = r.debugAPIListenerAddr().Port
+	}
+
+	m.Health.Enabled = opt.EnableHealthServer
+	if opt.EnableHealthServer {
+		m.Health.IP = ip
+		m.Health.Port = r.healthListenerAddr().Port
+	}
+
 	// Print
 	jsonMeta, err := json.Marshal(m)
 	if err != nil {
-		logF.WithError(err).Error("Can't provide plugin metadata information")
-		os.Exit(errorExitStatus)
+		return nil, fmt.Errorf("can't provide plugin metadata information: %w", err)
 	}
 
 	fmt.Printf("%s\n", string(jsonMeta))
-	return jsonMeta
+	return jsonMeta, nil
 }
@@ -0,0 +1,162 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+// Options below set values used when plugin is run by Run... functions. Precedence of values (increasing):
+// defaults, options set by code, config file (-config-file), command-line arguments, environment (SNAP_PLUGIN_OPT_<OPTION>).
+// It means that values set by code may be changed by the user running the plugin.
+
+func withModifier(modify func(opt *plugin.Options)) Option {
+	return func(rc *runConfig) {
+		rc.modifiers = append(rc.modifiers, modify)
+	}
+}
+
+// WithCustomOptions allows setting any of plugin options not covered by dedicated functions
+func WithCustomOptions(modify func(opt *plugin.Options)) Option {
+	return withModifier(modify)
+}
+
+func WithConfigFile(path string) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.ConfigFile = path
+	})
+}
+
+func WithPluginIP(ip string) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.PluginIP = ip
+	})
+}
+
+func WithGRPCPort(port int) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.GRPCPort = port
+	})
+}
+
+// WithGRPCSocket serves GRPC on Unix domain socket (instead of IP and port)
+func WithGRPCSocket(path string) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.GRPCSocket = path
+	})
+}
+
+func WithGRPCPingTimeout(timeout time.Duration, maxMissed uint) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.GRPCPingTimeout = timeout
+		opt.GRPCPingMaxMissed = maxMissed
+	})
+}
+
+// WithTLS enables secure GRPC communication. Client certificates are verified when clientCAPath is not empty.
+func WithTLS(certPath, keyPath, clientCAPath string) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.EnableTLS = true
+		opt.TLSServerCertPath = certPath
+		opt.TLSServerKeyPath = keyPath
+		opt.TLSClientCAPath = clientCAPath
+	})
+}
+
+func WithAuthTokenFile(path string) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.AuthTokenFile = path
+	})
+}
+
+// WithStats enables gathering plugin statistics. When serverPort is not negative, statistics are served over HTTP (0 - random port).
+func WithStats(serverPort int) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.EnableStats = true
+
+		if serverPort >= 0 {
+			opt.EnableStatsServer = true
+			opt.StatsPort = serverPort
+		}
+	})
+}
+
+// WithProfiling starts pprof server on a given port (0 - random port)
+func WithProfiling(port int) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.EnableProfiling = true
+		opt.PProfPort = port
+	})
+}
+
+// WithDebugAPI starts HTTP server exposing plugin operations as JSON endpoints on a given port (0 - random port)
+func WithDebugAPI(port int) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.EnableDebugAPI = true
+		opt.DebugAPIPort = port
+	})
+}
+
+// WithHealthServer starts HTTP server with liveness (/healthz) and readiness (/readyz) probes on a given port (0 - random port)
+func WithHealthServer(port int) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.EnableHealthServer = true
+		opt.HealthPort = port
+	})
+}
+
+// WithPrometheusExporter runs collector as Prometheus exporter on a given port (0 - default 9464).
+// Task with given configuration (JSON) and filter is collected on each scrape of /metrics.
+func WithPrometheusExporter(port int, config string, filter []string) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.PrometheusExporter = true
+		opt.PrometheusExporterPort = port
+		opt.PluginConfig = config
+		opt.PluginFilter = strings.Join(filter, filterSeparator)
+	})
+}
+
+// WithTracing exports trace spans to OTLP/gRPC endpoint
+func WithTracing(endpoint string, insecure bool, samplingRatio float64) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.OTelExporterEndpoint = endpoint
+		opt.OTelExporterInsecure = insecure
+		opt.OTelSamplingRatio = samplingRatio
+	})
+}
+
+func WithLogLevel(level logrus.Level) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.LogLevel = level
+	})
+}
+
+func WithCollectChunkSize(size uint64) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.CollectChunkSize = size
+	})
+}
+
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return withModifier(func(opt *plugin.Options) {
+		opt.ShutdownTimeout = timeout
+	})
+}
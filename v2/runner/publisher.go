@@ -18,7 +18,7 @@ package runner
 
 import (
 	"context"
-	"os"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
 
@@ -26,6 +26,7 @@ import (
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/publisher/proxy"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/service"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/tracing"
 	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 )
@@ -34,13 +35,23 @@ func StartPublisher(publisher plugin.Publisher, name string, version string) {
 	StartPublisherWithContext(context.Background(), publisher, name, version)
 }
 
+// StartPublisherWithContext runs publisher until it's requested to stop (Kill, SIGTERM or ctx cancellation).
+// Process is terminated when error occurs (use RunPublisher to handle errors by yourself).
 func StartPublisherWithContext(ctx context.Context, publisher plugin.Publisher, name string, version string) {
-	var err error
+	ctx, inProc, stop := startContext(ctx, publisher)
+	defer stop()
+
+	exitOnError(ctx, RunPublisher(ctx, publisher, name, version), inProc)
+}
+
+// RunPublisher runs publisher until Kill is requested or ctx is done (tasks are unloaded then).
+// Returns error when plugin can't be started or hasn't been stopped gracefully.
+func RunPublisher(ctx context.Context, publisher plugin.Publisher, name string, version string, opts ...Option) error {
+	rc := newRunConfig(opts)
 
-	var opt *plugin.Options
 	inprocPlugin, inProc := publisher.(inProcessPlugin)
 	if inProc {
-		opt = inprocPlugin.Options()
+		rc.opt = inprocPlugin.Options()
 
 		logger := inprocPlugin.Logger()
 		ctx = log.ToCtx(ctx, logger)
@@ -50,30 +61,25 @@ func StartPublisherWithContext(ctx context.Context, publisher plugin.Publisher,
 
 	logF := logger(ctx).WithField("service", "publisher")
 
-	if opt == nil {
-		opt, err = ParseCmdLineOptions(os.Args[0], types.PluginTypePublisher, os.Args[1:])
-		if err != nil {
-			logF.WithError(err).Error("Error occured during plugin startup")
-			os.Exit(errorExitStatus)
-		}
-	}
-
-	opt, err = ParseEnvOptions(os.Environ(), opt)
+	opt, err := rc.options(types.PluginTypePublisher)
 	if err != nil {
-		logF.WithError(err).Error("Error occurred during plugin startup while parsing env")
-		os.Exit(errorExitStatus)
+		return err
 	}
 
-	err = ValidateOptions(opt)
+	shutdownTracing, err := tracing.Setup(ctx, name, version, opt)
 	if err != nil {
-		logF.WithError(err).Error("Invalid plugin options")
-		os.Exit(errorExitStatus)
+		return fmt.Errorf("can't initialize tracing: %w", err)
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logF.WithError(err).Warn("Can't flush remaining spans")
+		}
+	}()
 
-	statsController, err := stats.NewController(ctx, name, version, types.PluginTypePublisher, opt)
+	// statistics are updated until all tasks are unloaded (which happens after ctx is done)
+	statsController, err := stats.NewController(context.WithoutCancel(ctx), name, version, types.PluginTypePublisher, opt)
 	if err != nil {
-		logF.WithError(err).Error("Error occured when starting statistics controller")
-		os.Exit(errorExitStatus)
+		return fmt.Errorf("error occured when starting statistics controller: %w", err)
 	}
 	defer statsController.Close()
 
@@ -83,21 +89,25 @@ func StartPublisherWithContext(ctx context.Context, publisher plugin.Publisher,
 
 	if opt.PrintVersion {
 		printVersion(name, version)
-		os.Exit(normalExitStatus)
+		return nil
 	}
 
 	if opt.PrintExampleTask {
 		printExampleTask(ctxMan.ExampleConfig, name, types.PluginTypePublisher)
-		os.Exit(normalExitStatus)
+		return nil
 	}
 
 	r, err := acquireResources(opt)
 	if err != nil {
-		logF.WithError(err).Error("Can't acquire resources for plugin services")
-		os.Exit(errorExitStatus)
+		return fmt.Errorf("can't acquire resources for plugin services: %w", err)
+	}
+
+	jsonMeta, err := metaInformation(name, version, types.PluginTypePublisher, opt, r, ctxMan.TasksLimit, ctxMan.InstancesLimit)
+	if err != nil {
+		r.release()
+		return err
 	}
 
-	jsonMeta := metaInformation(ctx, name, version, types.PluginTypePublisher, opt, r, ctxMan.TasksLimit, ctxMan.InstancesLimit)
 	if inProc {
 		inprocPlugin.MetaChannel() <- jsonMeta
 		close(inprocPlugin.MetaChannel())
@@ -113,10 +123,27 @@ func StartPublisherWithContext(ctx context.Context, publisher plugin.Publisher,
 		defer r.statsListener.Close() // close stats service when GRPC service has been shut down
 	}
 
-	srv, err := service.NewGRPCServer(ctx, opt)
+	if opt.EnableDebugAPI {
+		debugAPI, err := service.NewPublisherDebugAPI(ctx, ctxMan, opt)
+		if err != nil {
+			r.release()
+			return fmt.Errorf("can't initialize debug API: %w", err)
+		}
+
+		startDebugAPIServer(ctx, r.debugAPIListener, debugAPI)
+		defer r.debugAPIListener.Close() // close debug API service when GRPC service has been shut down
+	}
+
+	health := service.NewHealth(!opt.DebugMode)
+	if opt.EnableHealthServer {
+		startHealthServer(ctx, r.healthListener, service.NewHealthHandler(ctx, health, ctxMan))
+		defer r.healthListener.Close() // close health service when GRPC service has been shut down
+	}
+
+	srv, err := service.NewGRPCServer(ctx, opt, rc.grpcHooks)
 	if err != nil {
-		logF.WithError(err).Error("Can't initialize GRPC Server")
-		os.Exit(errorExitStatus)
+		r.release()
+		return fmt.Errorf("can't initialize GRPC server: %w", err)
 	}
 
 	// We need to bind the gRPC client on the other end to the same channel so need to return it from here
@@ -125,5 +152,5 @@ func StartPublisherWithContext(ctx context.Context, publisher plugin.Publisher,
 	}
 
 	// main blocking operation
-	service.StartPublisherGRPC(ctx, srv, ctxMan, r.grpcListener, opt.GRPCPingTimeout, opt.GRPCPingMaxMissed)
+	return service.StartPublisherGRPC(ctx, srv, ctxMan, r.grpcListener, opt.GRPCPingTimeout, opt.GRPCPingMaxMissed, opt.ShutdownTimeout, health)
 }
@@ -24,6 +24,8 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -86,7 +88,7 @@ func (s *PublisherMediumSuite) startCollector(collector plugin.Collector) net.Li
 	go func() {
 		statsController, _ := stats.NewEmptyController()
 		contextManager := collProxy.NewContextManager(context.Background(), types.NewCollector("test-collector", "1.0.0", collector), statsController)
-		service.StartCollectorGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize)
+		_ = service.StartCollectorGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0, defaultCollectChunkSize, service.DefaultShutdownTimeout, nil)
 		s.endControllerCh <- true
 	}()
 
@@ -102,7 +104,7 @@ func (s *PublisherMediumSuite) startPublisher(publisher plugin.Publisher) net.Li
 	go func() {
 		statsController := &stats.EmptyController{}
 		contextManager := pubProxy.NewContextManager(publisher, statsController)
-		service.StartPublisherGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0)
+		_ = service.StartPublisherGRPC(context.Background(), grpc.NewServer(), contextManager, ln, 0, 0, service.DefaultShutdownTimeout, nil)
 		s.endPublisherCh <- true
 	}()
 
@@ -636,3 +638,47 @@ func (s *PublisherMediumSuite) TestSimplePublisher() {
 		})
 	})
 }
+
+/*****************************************************************************/
+
+func (s *PublisherMediumSuite) TestRunPublisher() {
+	Convey("Validate that publisher run by RunPublisher ignores environment options specific to collector", s.T(), func() {
+		// Arrange
+		s.T().Setenv("SNAP_PLUGIN_OPT_DEBUG_MODE", "true")
+		s.T().Setenv("SNAP_PLUGIN_OPT_PROMETHEUS_EXPORTER", "true")
+
+		ctx, cancelFn := context.WithCancel(context.Background())
+		defer cancelFn()
+
+		socketPath := filepath.Join(s.T().TempDir(), "publisher.sock")
+
+		errRunCh := make(chan error, 1)
+		go func() {
+			errRunCh <- RunPublisher(ctx, &simplePublisher{}, "test-publisher", "1.0.0",
+				WithArgs(nil), WithGRPCSocket(socketPath), WithGRPCPingTimeout(0, 0))
+		}()
+
+		for i := 0; i < 50; i++ {
+			if _, err := os.Stat(socketPath); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		s.startPublisherClient("unix://" + socketPath)
+
+		// Act
+		_, errPing := s.publisherControlClient.Ping(context.Background(), &pluginrpc.PingRequest{})
+		cancelFn()
+
+		// Assert
+		So(errPing, ShouldBeNil)
+
+		select {
+		case err := <-errRunCh:
+			So(err, ShouldBeNil)
+		case <-time.After(expectedGracefulShutdownTimeout):
+			s.T().Fatal("plugin should have been ended")
+		}
+	})
+}
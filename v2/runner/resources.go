@@ -19,6 +19,9 @@ package runner
 import (
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
 )
@@ -27,11 +30,17 @@ type resources struct {
 	grpcListener  net.Listener
 	pprofListener net.Listener
 	statsListener net.Listener
+
+	debugAPIListener net.Listener
+	healthListener   net.Listener
+	exporterListener net.Listener
 }
 
 func safeListenerAddr(ln net.Listener) net.TCPAddr {
 	if ln != nil {
-		return *ln.Addr().(*net.TCPAddr)
+		if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+			return *addr
+		}
 	}
 
 	return net.TCPAddr{
@@ -51,20 +60,50 @@ func (r *resources) statsListenerAddr() net.TCPAddr {
 	return safeListenerAddr(r.statsListener)
 }
 
-func acquireResources(opt *plugin.Options) (*resources, error) {
-	var err error
-	r := &resources{}
+func (r *resources) debugAPIListenerAddr() net.TCPAddr {
+	return safeListenerAddr(r.debugAPIListener)
+}
 
-	if !opt.AsThread && !opt.DebugMode {
-		r.grpcListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", opt.PluginIP, opt.GRPCPort))
+func (r *resources) healthListenerAddr() net.TCPAddr {
+	return safeListenerAddr(r.healthListener)
+}
+
+func acquireResources(opt *plugin.Options) (_ *resources, err error) {
+	r := &resources{}
+	defer func() {
 		if err != nil {
-			return nil, fmt.Errorf("can't create tcp connection for GRPC server (%s)", err)
+			r.release()
+		}
+	}()
+
+	if opt.AsThread {
+		// plugin running inside Snap is driven by GRPC only
+		opt.PrometheusExporter = false
+	}
+
+	if !opt.AsThread && !opt.DebugMode && !opt.PrometheusExporter {
+		if opt.GRPCSocket != "" {
+			r.grpcListener, err = listenUnixSocket(opt.GRPCSocket, opt.GRPCSocketPermissions)
+			if err != nil {
+				return nil, fmt.Errorf("can't create unix socket for GRPC server (%s)", err)
+			}
+		} else {
+			r.grpcListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", opt.PluginIP, opt.GRPCPort))
+			if err != nil {
+				return nil, fmt.Errorf("can't create tcp connection for GRPC server (%s)", err)
+			}
 		}
 	}
 
 	if opt.AsThread {
 		// force disable profiling as plugin running as goroutine inside Snap will be covered by its pprof anyway
 		opt.EnableProfiling = false
+
+		// plugin running inside Snap shouldn't be driven by anything else
+		opt.EnableDebugAPI = false
+
+		// health of plugin running inside Snap is the health of Snap itself
+		opt.EnableHealthServer = false
 	}
 
 	if opt.EnableProfiling {
@@ -81,5 +120,101 @@ func acquireResources(opt *plugin.Options) (*resources, error) {
 		}
 	}
 
+	if opt.EnableDebugAPI {
+		r.debugAPIListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", opt.PluginIP, opt.DebugAPIPort))
+		if err != nil {
+			return nil, fmt.Errorf("can't create tcp connection for Debug API server (%s)", err)
+		}
+	}
+
+	if opt.EnableHealthServer {
+		r.healthListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", opt.PluginIP, opt.HealthPort))
+		if err != nil {
+			return nil, fmt.Errorf("can't create tcp connection for Health server (%s)", err)
+		}
+	}
+
+	if opt.PrometheusExporter {
+		port := opt.PrometheusExporterPort
+		if port == 0 {
+			port = defaultPrometheusPort
+		}
+
+		r.exporterListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", opt.PluginIP, port))
+		if err != nil {
+			return nil, fmt.Errorf("can't create tcp connection for Prometheus exporter (%s)", err)
+		}
+	}
+
 	return r, nil
 }
+
+// release closes listeners which haven't been handed over to servers
+func (r *resources) release() {
+	for _, ln := range []net.Listener{r.grpcListener, r.pprofListener, r.statsListener, r.debugAPIListener, r.healthListener, r.exporterListener} {
+		if ln != nil {
+			_ = ln.Close()
+		}
+	}
+}
+
+func listenUnixSocket(path string, perm os.FileMode) (net.Listener, error) {
+	// remove socket file left by previous instance of plugin (which wasn't shut down gracefully)
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("file %s already exists and is not a socket", path)
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Socket is created in a private directory (accessible only by the owner) and moved to the destination path
+	// when its permissions are already set, so other users can't connect in the meantime.
+	privDir, err := os.MkdirTemp(filepath.Dir(path), ".sock")
+	if err != nil {
+		return nil, fmt.Errorf("can't create directory for socket file: %v", err)
+	}
+	defer os.RemoveAll(privDir)
+
+	privPath := filepath.Join(privDir, "s")
+
+	ln, err := net.Listen("unix", privPath)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false) // socket file is removed from the destination path (see: unixSocketListener)
+
+	err = os.Chmod(privPath, perm)
+	if err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("can't set permissions of socket file: %v", err)
+	}
+
+	err = os.Rename(privPath, path)
+	if err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("can't move socket file: %v", err)
+	}
+
+	return &unixSocketListener{Listener: ln, path: path}, nil
+}
+
+// unixSocketListener removes socket file when closed
+type unixSocketListener struct {
+	net.Listener
+	path string
+
+	closeOnce sync.Once
+}
+
+func (l *unixSocketListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() {
+		_ = os.Remove(l.path)
+	})
+
+	return err
+}
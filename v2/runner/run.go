@@ -0,0 +1,118 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/service"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+// Option configures how plugin is run by Run... functions
+type Option func(rc *runConfig)
+
+type runConfig struct {
+	args      []string                    // command-line arguments (without program name)
+	opt       *plugin.Options             // options used instead of parsing command-line arguments
+	modifiers []func(opt *plugin.Options) // values of options set by code (see options.go)
+	grpcHooks service.ServerHooks         // interceptors and options of GRPC server (see grpc.go)
+}
+
+func newRunConfig(opts []Option) *runConfig {
+	rc := &runConfig{
+		args: os.Args[1:],
+	}
+
+	for _, o := range opts {
+		o(rc)
+	}
+
+	return rc
+}
+
+// WithArgs provides command-line arguments parsed instead of os.Args (ie. when plugin is embedded in other application)
+func WithArgs(args []string) Option {
+	return func(rc *runConfig) {
+		rc.args = args
+	}
+}
+
+// WithOptions provides complete plugin options, so command-line arguments are not parsed
+func WithOptions(opt *plugin.Options) Option {
+	return func(rc *runConfig) {
+		rc.opt = opt
+	}
+}
+
+// options returns plugin options provided directly or parsed from command-line arguments, config file and environment
+func (rc *runConfig) options(pluginType types.PluginType) (*plugin.Options, error) {
+	var err error
+
+	opt := rc.opt
+	if opt == nil {
+		opt, err = parseOptions(os.Args[0], pluginType, rc.args, os.Environ(), rc.modifiers)
+		if err != nil {
+			return nil, fmt.Errorf("error occurred during plugin startup while parsing commandline options: %w", err)
+		}
+	} else {
+		for _, modify := range rc.modifiers {
+			modify(opt)
+		}
+	}
+
+	opt, err = parseEnvOptions(os.Environ(), pluginType, opt)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred during plugin startup while parsing env: %w", err)
+	}
+
+	err = ValidateOptions(opt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin options: %w", err)
+	}
+
+	return opt, nil
+}
+
+// startContext prepares context for Start... functions. Termination signals are handled (graceful shutdown is started)
+// only when plugin is run as a separate process, otherwise they're handled by host.
+func startContext(ctx context.Context, p interface{}) (_ context.Context, inProc bool, stop context.CancelFunc) {
+	if inprocPlugin, ok := p.(inProcessPlugin); ok {
+		return log.ToCtx(ctx, inprocPlugin.Logger()), true, func() {}
+	}
+
+	ctx, stop = withTerminationSignal(ctx)
+	return ctx, false, stop
+}
+
+// exitOnError is used by Start... functions which (unlike Run... ones) terminate process when plugin fails.
+// Plugin run in-process doesn't own the process, so error is only logged.
+func exitOnError(ctx context.Context, err error, inProc bool) {
+	if err == nil {
+		return
+	}
+
+	logger(ctx).WithError(err).Error("Plugin has ended with error")
+
+	if !inProc {
+		os.Exit(errorExitStatus)
+	}
+}
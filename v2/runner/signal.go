@@ -0,0 +1,37 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// withTerminationSignal returns context which is done when plugin receives SIGTERM or SIGINT (graceful shutdown is started).
+// Default handling is restored afterwards, so repeated signal terminates plugin immediately.
+func withTerminationSignal(ctx context.Context) (context.Context, context.CancelFunc) {
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+
+	return sigCtx, stop
+}
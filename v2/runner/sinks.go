@@ -0,0 +1,556 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/exposition"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/log"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+const (
+	sinkPrint      = "print"
+	sinkStdout     = "stdout"
+	sinkFile       = "file"
+	sinkHTTP       = "http"
+	sinkPrometheus = "prometheus"
+
+	sinkSeparator = ","
+
+	sinkHTTPTimeout = 10 * time.Second
+
+	prometheusTaskLabel = "task"
+
+	bytesInMB = 1024 * 1024
+)
+
+var availableSinks = []string{sinkPrint, sinkStdout, sinkFile, sinkHTTP, sinkPrometheus}
+
+func parseSinks(s string) ([]string, error) {
+	var sinks []string
+
+	for _, name := range strings.Split(s, sinkSeparator) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		known := false
+		for _, available := range availableSinks {
+			known = known || name == available
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown sink: %s (available: %s)", name, strings.Join(availableSinks, sinkSeparator))
+		}
+
+		sinks = append(sinks, name)
+	}
+
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("at least one sink should be defined")
+	}
+
+	return sinks, nil
+}
+
+// metricSink is a destination of metrics gathered in standalone (debug) mode
+type metricSink interface {
+	write(taskID string, mts []*types.Metric, warnings []types.Warning) error
+	close() error
+}
+
+// sinkGroup passes metrics from all tasks to all configured sinks (one task at a time)
+type sinkGroup struct {
+	mu    sync.Mutex
+	sinks []metricSink
+}
+
+// newSinks creates sinks requested by options. When streaming is set, metrics are written chunk by chunk (not as complete collects).
+func newSinks(ctx context.Context, opt *plugin.Options, withTaskID bool, streaming bool) (*sinkGroup, error) {
+	names, err := parseSinks(opt.Sinks)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &sinkGroup{}
+
+	for _, name := range names {
+		var s metricSink
+
+		switch name {
+		case sinkPrint:
+			s = &printSink{withTaskID: withTaskID}
+		case sinkStdout:
+			s = &jsonLinesSink{w: os.Stdout}
+		case sinkFile:
+			var f *rotatingFile
+			f, err = openRotatingFile(opt.SinkFilePath, int64(opt.SinkFileMaxSize)*bytesInMB, opt.SinkFileMaxBackups)
+			s = &jsonLinesSink{w: f, closer: f}
+		case sinkHTTP:
+			s = &httpSink{url: opt.SinkHTTPURL, client: &http.Client{Timeout: sinkHTTPTimeout}}
+		case sinkPrometheus:
+			s, err = newPrometheusSink(ctx, fmt.Sprintf("%s:%d", opt.PluginIP, opt.SinkPrometheusPort), withTaskID, streaming)
+		}
+
+		if err != nil {
+			_ = g.close()
+			return nil, fmt.Errorf("can't initialize %s sink: %w", name, err)
+		}
+
+		g.sinks = append(g.sinks, s)
+	}
+
+	return g, nil
+}
+
+func (g *sinkGroup) write(taskID string, mts []*types.Metric, warnings []types.Warning) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	for _, s := range g.sinks {
+		errs = append(errs, s.write(taskID, mts, warnings))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (g *sinkGroup) close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	for _, s := range g.sinks {
+		errs = append(errs, s.close())
+	}
+
+	return errors.Join(errs...)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// printSink prints metrics in human-readable form
+type printSink struct {
+	withTaskID bool
+}
+
+func (s *printSink) write(taskID string, mts []*types.Metric, warnings []types.Warning) error {
+	task := ""
+	if s.withTaskID {
+		task = fmt.Sprintf("task=%s, ", taskID)
+	}
+
+	fmt.Printf("Gathered metrics (%slength=%d): \n", task, len(mts))
+	for _, mt := range mts {
+		fmt.Printf("%s\n", mt)
+	}
+	fmt.Printf("\n")
+
+	if len(warnings) != 0 {
+		fmt.Printf("Gathered warnings (%slength=%d): \n", task, len(warnings))
+		for _, w := range warnings {
+			fmt.Printf("%s\n", w)
+		}
+		fmt.Printf("\n")
+	}
+
+	return nil
+}
+
+func (s *printSink) close() error {
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// sinkRecord is JSON representation of metric used by stdout, file and http sinks
+type sinkRecord struct {
+	Task        string            `json:"task"`
+	Namespace   string            `json:"namespace"`
+	Value       interface{}       `json:"value"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Unit        string            `json:"unit,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+type sinkSummary struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+type sinkHistogram struct {
+	Buckets map[string]float64 `json:"buckets"` // upper bound -> value
+	Count   int                `json:"count"`
+	Sum     float64            `json:"sum"`
+}
+
+func toSinkRecord(taskID string, mt *types.Metric) sinkRecord {
+	return sinkRecord{
+		Task:        taskID,
+		Namespace:   mt.Namespace().String(),
+		Value:       toSinkValue(mt.Value_),
+		Tags:        mt.Tags_,
+		Unit:        mt.Unit_,
+		Type:        metricTypeName(mt.Type_),
+		Description: mt.Description_,
+		Timestamp:   mt.Timestamp_,
+	}
+}
+
+func toSinkValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case plugin.Summary:
+		return sinkSummary{Count: t.Count, Sum: t.Sum}
+	case *plugin.Summary:
+		return sinkSummary{Count: t.Count, Sum: t.Sum}
+	case plugin.Histogram:
+		return toSinkHistogram(&t)
+	case *plugin.Histogram:
+		return toSinkHistogram(t)
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return strconv.FormatFloat(t, 'g', -1, 64) // not supported by JSON
+		}
+	case float32:
+		if math.IsNaN(float64(t)) || math.IsInf(float64(t), 0) {
+			return strconv.FormatFloat(float64(t), 'g', -1, 32)
+		}
+	}
+
+	return v
+}
+
+func toSinkHistogram(h *plugin.Histogram) sinkHistogram {
+	buckets := make(map[string]float64, len(h.DataPoints))
+	for b, v := range h.DataPoints {
+		buckets[strconv.FormatFloat(b, 'g', -1, 64)] = v
+	}
+
+	return sinkHistogram{Buckets: buckets, Count: h.Count, Sum: h.Sum}
+}
+
+func metricTypeName(t plugin.MetricType) string {
+	switch t {
+	case plugin.GaugeType:
+		return "gauge"
+	case plugin.SumType:
+		return "sum"
+	case plugin.SummaryType:
+		return "summary"
+	case plugin.HistogramType:
+		return "histogram"
+	default:
+		return ""
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// jsonLinesSink writes each metric as a separate JSON document (line)
+type jsonLinesSink struct {
+	w      io.Writer
+	closer io.Closer // optional
+}
+
+func (s *jsonLinesSink) write(taskID string, mts []*types.Metric, _ []types.Warning) error {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+
+	for _, mt := range mts {
+		err := enc.Encode(toSinkRecord(taskID, mt))
+		if err != nil {
+			return fmt.Errorf("can't marshal metric %s: %w", mt.Namespace(), err)
+		}
+	}
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+func (s *jsonLinesSink) close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+// rotatingFile is rotated when its size would exceed maxSize (previous content is kept in path.1, path.2, ... path.<maxBackups>)
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	f    *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	r.f, r.size = f, fi.Size()
+	return r, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		err := r.rotate()
+		if err != nil {
+			return 0, fmt.Errorf("can't rotate file %s: %w", r.path, err)
+		}
+	}
+
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	err := r.f.Close()
+	if err != nil {
+		return err
+	}
+
+	if r.maxBackups > 0 {
+		for i := r.maxBackups - 1; i > 0; i-- {
+			err = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		err = os.Rename(r.path, r.path+".1")
+		if err != nil {
+			return err
+		}
+	}
+
+	r.f, err = os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	r.size = 0
+	return err
+}
+
+func (r *rotatingFile) Close() error {
+	return r.f.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// httpSink sends metrics gathered by a single collect request as JSON array (POST)
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSink) write(taskID string, mts []*types.Metric, _ []types.Warning) error {
+	records := make([]sinkRecord, 0, len(mts))
+	for _, mt := range mts {
+		records = append(records, toSinkRecord(taskID, mt))
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("can't marshal metrics: %w", err)
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can't send metrics to %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("can't send metrics to %s: unexpected status %s", s.url, resp.Status)
+	}
+
+	return nil
+}
+
+func (s *httpSink) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// prometheusSink exposes metrics gathered by the latest collect request of each task (/metrics).
+// Chunks of streaming collector are merged, so the latest value of each series is exposed.
+type prometheusSink struct {
+	mu      sync.RWMutex
+	metrics map[string][]*types.Metric // task id -> metrics
+
+	withTaskID bool // add "task" label, so series of different tasks aren't duplicated
+	merge      bool // written metrics update series of a task instead of replacing all of them
+	ln         net.Listener
+	logF       logrus.FieldLogger
+}
+
+func newPrometheusSink(ctx context.Context, addr string, withTaskID bool, merge bool) (*prometheusSink, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	logF := log.WithCtx(ctx).WithFields(moduleFields)
+	s := &prometheusSink{metrics: map[string][]*types.Metric{}, withTaskID: withTaskID, merge: merge, ln: ln, logF: logF}
+
+	logF.Infof("Running prometheus sink on address %s", ln.Addr())
+
+	h := http.NewServeMux()
+	h.HandleFunc("GET /metrics", s.handle)
+
+	go func() {
+		err := http.Serve(ln, h)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			logF.WithError(err).Warn("Prometheus sink stopped")
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *prometheusSink) write(taskID string, mts []*types.Metric, _ []types.Warning) error {
+	if s.withTaskID {
+		mts = withTaskLabel(taskID, mts)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.merge {
+		mts = mergeSeries(s.metrics[taskID], mts)
+	}
+
+	s.metrics[taskID] = mts
+	return nil
+}
+
+func (s *prometheusSink) handle(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	taskIDs := make([]string, 0, len(s.metrics))
+	for id := range s.metrics {
+		taskIDs = append(taskIDs, id)
+	}
+	sort.Strings(taskIDs)
+
+	var mts []*types.Metric
+	for _, id := range taskIDs {
+		mts = append(mts, s.metrics[id]...)
+	}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", exposition.ContentType)
+	duplicates, _ := exposition.WriteText(w, mts)
+	if len(duplicates) > 0 {
+		s.logF.WithField("series", duplicates).Warn("Metrics with the same name and labels as other ones were omitted")
+	}
+}
+
+func (s *prometheusSink) close() error {
+	return s.ln.Close()
+}
+
+// mergeSeries returns current metrics with series updated by received ones (new series are appended)
+func mergeSeries(current []*types.Metric, received []*types.Metric) []*types.Metric {
+	merged := make([]*types.Metric, len(current), len(current)+len(received))
+	copy(merged, current)
+
+	positions := make(map[string]int, len(merged))
+	for i, mt := range merged {
+		positions[seriesKey(mt)] = i
+	}
+
+	for _, mt := range received {
+		key := seriesKey(mt)
+		if pos, ok := positions[key]; ok {
+			merged[pos] = mt
+			continue
+		}
+
+		positions[key] = len(merged)
+		merged = append(merged, mt)
+	}
+
+	return merged
+}
+
+// seriesKey identifies series by namespace and tags (ordered by keys)
+func seriesKey(mt *types.Metric) string {
+	var sb strings.Builder
+	sb.WriteString(types.Namespace(mt.Namespace_).EscapedString())
+
+	keys := make([]string, 0, len(mt.Tags_))
+	for k := range mt.Tags_ {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sb.WriteString("\x00")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(mt.Tags_[k])
+	}
+
+	return sb.String()
+}
+
+// withTaskLabel returns copies of metrics with additional "task" tag (exposed as label)
+func withTaskLabel(taskID string, mts []*types.Metric) []*types.Metric {
+	labeled := make([]*types.Metric, 0, len(mts))
+
+	for _, mt := range mts {
+		mtCopy := *mt
+		mtCopy.Tags_ = nil // tags map may be shared with other sinks
+		mtCopy.AddTags(mt.Tags_)
+		mtCopy.AddTags(map[string]string{prometheusTaskLabel: taskID})
+		labeled = append(labeled, &mtCopy)
+	}
+
+	return labeled
+}
@@ -0,0 +1,302 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/plugins/collector/proxy"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	debugModeTaskID = "task-1"
+
+	infiniteDebugCollectCount = -1
+
+	supportedTaskVersion = 2
+)
+
+// standaloneTask is a task executed by collector running without snap (debug mode)
+type standaloneTask struct {
+	id       string
+	config   []byte
+	filter   []string
+	tags     map[string]map[string]string // namespace prefix -> tags
+	schedule schedule
+}
+
+// taskDocument reflects (a subset of) snap task format, as printed by -print-example-task
+type taskDocument struct {
+	Version  int `yaml:"version"`
+	Schedule struct {
+		Type     string `yaml:"type"`
+		Interval string `yaml:"interval"`
+	} `yaml:"schedule"`
+	Plugins []struct {
+		PluginName string                       `yaml:"plugin_name"`
+		Name       string                       `yaml:"name"`
+		Config     map[string]interface{}       `yaml:"config"`
+		Metrics    []string                     `yaml:"metrics"`
+		Tags       map[string]map[string]string `yaml:"tags"`
+	} `yaml:"plugins"`
+}
+
+func standaloneTasks(opt *plugin.Options) ([]standaloneTask, error) {
+	if opt.TaskFile == "" {
+		var filter []string
+		if opt.PluginFilter != defaultFilter {
+			filter = strings.Split(opt.PluginFilter, filterSeparator)
+		}
+
+		return []standaloneTask{{
+			id:       debugModeTaskID,
+			config:   []byte(opt.PluginConfig),
+			filter:   filter,
+			schedule: intervalSchedule(opt.DebugCollectInterval),
+		}}, nil
+	}
+
+	f, err := os.Open(opt.TaskFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't open task file: %w", err)
+	}
+	defer f.Close()
+
+	tasks, err := parseTaskFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task file %s: %w", opt.TaskFile, err)
+	}
+
+	return tasks, nil
+}
+
+// parseTaskFile reads all documents from the file. Each entry of plugins section is a separate task.
+func parseTaskFile(r io.Reader) ([]standaloneTask, error) {
+	var tasks []standaloneTask
+
+	dec := yaml.NewDecoder(r)
+	for docNo := 1; ; docNo++ {
+		doc := taskDocument{}
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't parse document %d: %w", docNo, err)
+		}
+
+		if len(doc.Plugins) == 0 {
+			continue
+		}
+
+		if doc.Version != 0 && doc.Version != supportedTaskVersion {
+			return nil, fmt.Errorf("unsupported version of document %d: %d", docNo, doc.Version)
+		}
+
+		sched, err := newSchedule(doc.Schedule.Type, doc.Schedule.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule of document %d: %w", docNo, err)
+		}
+
+		for _, p := range doc.Plugins {
+			config := []byte(defaultConfig)
+			if p.Config != nil {
+				config, err = json.Marshal(p.Config)
+				if err != nil {
+					return nil, fmt.Errorf("invalid config of document %d: %w", docNo, err)
+				}
+			}
+
+			tasks = append(tasks, standaloneTask{
+				id:       fmt.Sprintf("task-%d", len(tasks)+1),
+				config:   config,
+				filter:   p.Metrics,
+				tags:     p.Tags,
+				schedule: sched,
+			})
+		}
+	}
+
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no task defined")
+	}
+
+	return tasks, nil
+}
+
+func (t *standaloneTask) applyTags(mts []*types.Metric) {
+	for prefix, tags := range t.tags {
+		for _, mt := range mts {
+			if strings.HasPrefix(mt.Namespace().String(), prefix) {
+				mt.AddTags(tags)
+			}
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func startCollectorInDebugMode(ctx context.Context, ctxManager *proxy.ContextManager, opt *plugin.Options, streaming bool) error {
+	tasks, err := standaloneTasks(opt)
+	if err != nil {
+		return err
+	}
+
+	sinks, err := newSinks(ctx, opt, opt.TaskFile != "", streaming)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sinks.close() }()
+
+	for i, task := range tasks {
+		errLoad := ctxManager.LoadTask(ctx, task.id, task.config, task.filter)
+		if errLoad != nil {
+			_ = unloadStandaloneTasks(ctx, ctxManager, tasks[:i])
+			return fmt.Errorf("couldn't load a task %s in a standalone mode: %w", task.id, errLoad)
+		}
+	}
+
+	errs := make([]error, len(tasks))
+	wg := sync.WaitGroup{}
+
+	for i := range tasks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = runStandaloneTask(ctx, ctxManager, &tasks[i], sinks, opt, streaming)
+		}(i)
+	}
+
+	wg.Wait()
+
+	errUnload := unloadStandaloneTasks(ctx, ctxManager, tasks)
+	if errUnload != nil {
+		return fmt.Errorf("couldn't unload a task in a standalone mode: %w", errUnload)
+	}
+
+	return errors.Join(errs...)
+}
+
+// runStandaloneTask requests collection according to task schedule until ctx is done or requested number of collections is reached.
+// Collect errors don't break the loop (task is collected again according to schedule) - the last one is returned.
+func runStandaloneTask(ctx context.Context, ctxManager *proxy.ContextManager, task *standaloneTask, sinks *sinkGroup, opt *plugin.Options, streaming bool) error {
+	logF := logger(ctx).WithField("service", "collector").WithField("task-id", task.id)
+
+	var lastErr error
+
+	scheduled := time.Now() // simple schedule starts immediately, cron one at first matching time
+	if _, ok := task.schedule.(intervalSchedule); !ok {
+		scheduled = task.schedule.next(scheduled)
+	}
+
+	for runCount := 0; ctx.Err() == nil; {
+		if scheduled.IsZero() {
+			return fmt.Errorf("schedule of task %s doesn't match any time", task.id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return lastErr
+		case <-time.After(time.Until(scheduled) + jitter(opt.DebugCollectJitter)):
+		}
+
+		errCollect := collectStandaloneTask(ctx, ctxManager, task, streaming, func(mts []*types.Metric, warnings []types.Warning) {
+			errSink := sinks.write(task.id, mts, warnings)
+			if errSink != nil {
+				logF.WithError(errSink).Warn("Can't write metrics to sink(s)")
+			}
+		})
+
+		if errCollect != nil {
+			logF.WithError(errCollect).Warn("Error occurred during metrics collection")
+			lastErr = fmt.Errorf("error occurred during metrics collection in a standalone mode (task %s): %w", task.id, errCollect)
+		}
+
+		if opt.DebugCollectCounts != infiniteDebugCollectCount {
+			runCount++
+			if runCount == opt.DebugCollectCounts {
+				break
+			}
+		}
+
+		// don't try to catch up when collection took longer than interval
+		scheduled = task.schedule.next(scheduled)
+		if now := time.Now(); !scheduled.IsZero() && scheduled.Before(now) {
+			scheduled = task.schedule.next(now)
+		}
+	}
+
+	return lastErr
+}
+
+// collectStandaloneTask requests collection of a task and passes gathered metrics to handle. Metrics of collector are
+// passed at once, when collect is completed. Metrics of streaming collector are passed chunk by chunk as they arrive,
+// since streaming collect lasts until ctx is done.
+func collectStandaloneTask(ctx context.Context, ctxManager *proxy.ContextManager, task *standaloneTask, streaming bool, handle func([]*types.Metric, []types.Warning)) error {
+	var mts []*types.Metric
+	var warnings []types.Warning
+	var errCollect error
+
+	if streaming {
+		// streaming collect is stopped only when task is released (otherwise it would last until task is unloaded)
+		stop := context.AfterFunc(ctx, func() { ctxManager.ReleaseTask(task.id) })
+		defer stop()
+	}
+
+	for chunk := range ctxManager.RequestCollect(ctx, task.id) {
+		if chunk.Err != nil {
+			errCollect = chunk.Err
+			continue // read remaining chunks, so collect can be completed
+		}
+
+		if streaming {
+			task.applyTags(chunk.Metrics)
+			handle(chunk.Metrics, chunk.Warnings)
+			continue
+		}
+
+		mts = append(mts, chunk.Metrics...)
+		warnings = append(warnings, chunk.Warnings...)
+	}
+
+	if !streaming {
+		task.applyTags(mts)
+		handle(mts, warnings)
+	}
+
+	return errCollect
+}
+
+func unloadStandaloneTasks(ctx context.Context, ctxManager *proxy.ContextManager, tasks []standaloneTask) error {
+	var errs []error
+	for _, task := range tasks {
+		errs = append(errs, ctxManager.UnloadTask(ctx, task.id))
+	}
+
+	return errors.Join(errs...)
+}
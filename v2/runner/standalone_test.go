@@ -0,0 +1,171 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+)
+
+const taskFileContent = `
+---
+version: 2
+schedule:
+    type: simple
+    interval: 30s
+plugins:
+  - plugin_name: example
+    config:
+        format: short
+    metrics:
+      - /example/date/day
+    tags:
+        /example/date:
+            plugin_tag: tag
+    publish:
+        - plugin_name: publisher-appoptics
+---
+version: 2
+schedule:
+  type: cron
+  interval: "0 */5 * * * *"
+plugins:
+  - plugin_name: example
+  - plugin_name: example
+    config:
+        format: long
+`
+
+func TestParseTaskFile(t *testing.T) {
+	Convey("Validate that all tasks are read from multi-document task file", t, func() {
+		tasks, err := parseTaskFile(strings.NewReader(taskFileContent))
+		So(err, ShouldBeNil)
+		So(len(tasks), ShouldEqual, 3)
+
+		So(tasks[0].id, ShouldEqual, "task-1")
+		So(string(tasks[0].config), ShouldEqual, `{"format":"short"}`)
+		So(tasks[0].filter, ShouldResemble, []string{"/example/date/day"})
+		So(tasks[0].schedule, ShouldEqual, intervalSchedule(30*time.Second))
+
+		So(tasks[1].id, ShouldEqual, "task-2")
+		So(string(tasks[1].config), ShouldEqual, defaultConfig)
+		So(tasks[1].filter, ShouldBeNil)
+		So(tasks[1].schedule, ShouldHaveSameTypeAs, &cronSchedule{})
+
+		So(tasks[2].id, ShouldEqual, "task-3")
+		So(string(tasks[2].config), ShouldEqual, `{"format":"long"}`)
+	})
+
+	Convey("Validate that task tags are applied to metrics by namespace prefix", t, func() {
+		tasks, err := parseTaskFile(strings.NewReader(taskFileContent))
+		So(err, ShouldBeNil)
+
+		mts := []*types.Metric{
+			{Namespace_: []types.NamespaceElement{{Value_: "example"}, {Value_: "date"}, {Value_: "day"}}},
+			{Namespace_: []types.NamespaceElement{{Value_: "example"}, {Value_: "time"}, {Value_: "hour"}}},
+		}
+		tasks[0].applyTags(mts)
+
+		So(mts[0].Tags_, ShouldResemble, map[string]string{"plugin_tag": "tag"})
+		So(mts[1].Tags_, ShouldBeNil)
+	})
+
+	Convey("Validate that invalid task files are rejected", t, func() {
+		for _, content := range []string{
+			"",
+			"version: 1\nplugins:\n  - plugin_name: example\n",
+			"schedule:\n  type: streaming\nplugins:\n  - plugin_name: example\n",
+			"schedule:\n  type: cron\n  interval: \"* * *\"\nplugins:\n  - plugin_name: example\n",
+		} {
+			_, err := parseTaskFile(strings.NewReader(content))
+			So(err, ShouldBeError)
+		}
+	})
+}
+
+func TestCronSchedule(t *testing.T) {
+	base := time.Date(2024, time.May, 6, 10, 7, 30, 0, time.UTC) // Monday
+
+	Convey("Validate that next time matching cron expression is calculated", t, func() {
+		testCases := []struct {
+			expr string
+			next time.Time
+		}{
+			{"0 * * * * *", time.Date(2024, time.May, 6, 10, 8, 0, 0, time.UTC)},
+			{"*/20 * * * * *", time.Date(2024, time.May, 6, 10, 7, 40, 0, time.UTC)},
+			{"0 */15 * * * *", time.Date(2024, time.May, 6, 10, 15, 0, 0, time.UTC)},
+			{"30 7 10 * * *", time.Date(2024, time.May, 7, 10, 7, 30, 0, time.UTC)},
+			{"0 0 9-17 * * 1-5", time.Date(2024, time.May, 6, 11, 0, 0, 0, time.UTC)},
+			{"0 0 0 * * 0", time.Date(2024, time.May, 12, 0, 0, 0, 0, time.UTC)},
+			{"0 0 0 * * 7", time.Date(2024, time.May, 12, 0, 0, 0, 0, time.UTC)},
+			{"0 0 1 1,15 * *", time.Date(2024, time.May, 15, 1, 0, 0, 0, time.UTC)},
+			{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		}
+
+		for _, tc := range testCases {
+			s, err := parseCron(tc.expr)
+			So(err, ShouldBeNil)
+			So(s.next(base), ShouldEqual, tc.next)
+		}
+	})
+
+	Convey("Validate that invalid cron expressions are rejected", t, func() {
+		for _, expr := range []string{"* * * *", "60 * * * * *", "* * 24 * * *", "*/0 * * * * *", "a * * * * *", "5-1 * * * * *"} {
+			_, err := parseCron(expr)
+			So(err, ShouldBeError)
+		}
+	})
+
+	Convey("Validate that schedule which doesn't match any time is recognized", t, func() {
+		s, err := parseCron("0 0 0 30 2 *")
+		So(err, ShouldBeNil)
+		So(s.next(base).IsZero(), ShouldBeTrue)
+	})
+}
+
+func TestRotatingFile(t *testing.T) {
+	Convey("Validate that file is rotated when its size exceeds limit", t, func() {
+		path := filepath.Join(t.TempDir(), "metrics.json")
+
+		f, err := openRotatingFile(path, 10, 2)
+		So(err, ShouldBeNil)
+
+		for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+			_, err = f.Write([]byte(line))
+			So(err, ShouldBeNil)
+		}
+		So(f.Close(), ShouldBeNil)
+
+		for file, content := range map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"} {
+			b, err := os.ReadFile(file)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, content)
+		}
+
+		_, err = os.Stat(path + ".3")
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,348 @@
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package scrape
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+const (
+	acceptHeader = "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5"
+
+	nsSeparator      = "/"
+	nameSeparator    = "_"
+	anyElement       = "*"
+	bucketLabel      = "le"
+	quantileLabel    = "quantile"
+	createdSuffix    = "_created"
+	counterSuffix    = "_total"
+	infoSuffix       = "_info"
+	missingLabelText = "none"
+)
+
+// Config defines how samples are converted to metrics
+type Config struct {
+	// Namespace elements preceding metric name (the first one should match plugin definition, ie. "myapp")
+	Prefix []string
+
+	// Labels converted to dynamic elements of namespace, placed between prefix and metric name in a given order
+	// (ie. /myapp/[instance=host1]/http_requests_total). Other labels are added as tags.
+	// When sample doesn't have label, "none" is used as a value of element.
+	DynamicLabels []string
+
+	// When true, metric name is split into namespace elements (ie. http_requests_total -> /myapp/http/requests/total)
+	SplitName bool
+}
+
+// Scrape requests exposition from url and adds metrics to collect context (see: AddMetrics).
+// When client is nil, http.DefaultClient is used.
+func Scrape(ctx plugin.CollectContext, client *http.Client, url string, cfg Config) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx.RawContext(), http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't scrape %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("can't scrape %s: unexpected status %s", url, resp.Status)
+	}
+
+	return AddMetrics(ctx, resp.Body, FormatFromContentType(resp.Header.Get("Content-Type")), cfg)
+}
+
+// AddMetrics parses exposition and adds metrics to collect context:
+//   - counters, gauges, info, stateset and untyped samples are added as separate metrics (with type sum or gauge),
+//   - histograms are added as plugin.Histogram (buckets, sum and count of each label set),
+//   - summaries are added as plugin.Summary (sum and count of each label set - quantiles are omitted).
+//
+// Families for which ctx.ShouldProcess returns false are skipped without parsing their samples.
+// Metrics which can't be added (ie. not defined by plugin) are reported as a single warning.
+func AddMetrics(ctx plugin.CollectContext, r io.Reader, format Format, cfg Config) error {
+	families, err := parse(r, format, func(name, typ string) bool {
+		return ctx.ShouldProcess(cfg.familyNamespace(name, typ, format))
+	})
+	if err != nil {
+		return err
+	}
+
+	a := &adder{ctx: ctx, cfg: cfg}
+	for _, f := range families {
+		switch f.Type {
+		case TypeHistogram, TypeGaugeHistogram:
+			a.addHistograms(f)
+		case TypeSummary:
+			a.addSummaries(f)
+		default:
+			a.addSamples(f)
+		}
+	}
+
+	if a.failed != 0 {
+		ctx.AddWarning(fmt.Sprintf("%d scraped metric(s) couldn't be added, first error: %v", a.failed, a.firstErr))
+	}
+
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// namespace builds metric namespace (dynamic elements are formatted as [label=value])
+func (c *Config) namespace(name string, labelValue func(label string) string) string {
+	var sb strings.Builder
+
+	for _, el := range c.Prefix {
+		sb.WriteString(nsSeparator)
+		sb.WriteString(plugin.NsElement(el))
+	}
+
+	for _, label := range c.DynamicLabels {
+		fmt.Fprintf(&sb, "%s[%s=%s]", nsSeparator, label, labelValue(label))
+	}
+
+	nameElements := []string{name}
+	if c.SplitName {
+		nameElements = strings.Split(name, nameSeparator)
+	}
+
+	for _, el := range nameElements {
+		if el == "" {
+			continue
+		}
+		sb.WriteString(nsSeparator)
+		sb.WriteString(plugin.NsElement(el))
+	}
+
+	return sb.String()
+}
+
+// familyNamespace is used to check if family should be processed (values of dynamic elements aren't known yet)
+func (c *Config) familyNamespace(name, typ string, format Format) string {
+	return c.namespace(metricName(name, typ, format), func(string) string { return anyElement })
+}
+
+// metricNamespace builds namespace of a metric with values of dynamic elements taken from sample labels
+func (c *Config) metricNamespace(name string, labels map[string]string) string {
+	return c.namespace(name, func(label string) string {
+		v, ok := labels[label]
+		if !ok || v == "" {
+			return missingLabelText
+		}
+		return plugin.NsElement(v)
+	})
+}
+
+// tags returns labels which aren't converted to dynamic elements
+func (c *Config) tags(labels map[string]string, skip string) map[string]string {
+	tags := map[string]string{}
+
+	for k, v := range labels {
+		if k == skip || c.isDynamic(k) {
+			continue
+		}
+		tags[k] = v
+	}
+
+	return tags
+}
+
+func (c *Config) isDynamic(label string) bool {
+	for _, l := range c.DynamicLabels {
+		if l == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metricName returns name of samples of a family, the same as used by metrics created from them
+// (OpenMetrics counters and infos are exposed with suffix, text format samples are named after the family)
+func metricName(familyName, typ string, format Format) string {
+	if format != FormatOpenMetrics {
+		return familyName
+	}
+
+	switch {
+	case typ == TypeCounter && !strings.HasSuffix(familyName, counterSuffix):
+		return familyName + counterSuffix
+	case typ == TypeInfo && !strings.HasSuffix(familyName, infoSuffix):
+		return familyName + infoSuffix
+	}
+
+	return familyName
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+type adder struct {
+	ctx plugin.CollectContext
+	cfg Config
+
+	failed   int
+	firstErr error
+}
+
+func (a *adder) add(name string, labels map[string]string, skipLabel string, value interface{}, f *Family, s *Sample, modifiers ...plugin.MetricModifier) {
+	if tags := a.cfg.tags(labels, skipLabel); len(tags) != 0 {
+		modifiers = append(modifiers, plugin.MetricTags(tags))
+	}
+	if f.Help != "" {
+		modifiers = append(modifiers, plugin.MetricDescription(f.Help))
+	}
+	if f.Unit != "" {
+		modifiers = append(modifiers, plugin.MetricUnit(f.Unit))
+	}
+	if s != nil && !s.Timestamp.IsZero() {
+		modifiers = append(modifiers, plugin.MetricTimestamp(s.Timestamp))
+	}
+
+	err := a.ctx.AddMetric(a.cfg.metricNamespace(name, labels), value, modifiers...)
+	if err != nil {
+		if a.failed == 0 {
+			a.firstErr = err
+		}
+		a.failed++
+	}
+}
+
+func (a *adder) addSamples(f *Family) {
+	var typeModifier []plugin.MetricModifier
+	switch f.Type {
+	case TypeCounter:
+		typeModifier = append(typeModifier, plugin.MetricTypeSum())
+	case TypeGauge, TypeInfo, TypeStateSet:
+		typeModifier = append(typeModifier, plugin.MetricTypeGauge())
+	}
+
+	for i := range f.Samples {
+		s := &f.Samples[i]
+		if strings.HasSuffix(s.Name, createdSuffix) && s.Name != f.Name {
+			continue
+		}
+
+		a.add(s.Name, s.Labels, "", s.Value, f, s, typeModifier...)
+	}
+}
+
+// sampleGroup gathers samples with the same labels (apart from "le" or "quantile")
+type sampleGroup struct {
+	labels  map[string]string
+	samples []*Sample
+}
+
+func groupSamples(f *Family, skipLabel string) []*sampleGroup {
+	var groups []*sampleGroup
+	index := map[string]*sampleGroup{}
+
+	for i := range f.Samples {
+		s := &f.Samples[i]
+		key := labelsKey(s.Labels, skipLabel)
+
+		g, ok := index[key]
+		if !ok {
+			g = &sampleGroup{labels: s.Labels}
+			index[key] = g
+			groups = append(groups, g)
+		}
+
+		g.samples = append(g.samples, s)
+	}
+
+	return groups
+}
+
+func (a *adder) addHistograms(f *Family) {
+	for _, g := range groupSamples(f, bucketLabel) {
+		h := plugin.Histogram{DataPoints: map[float64]float64{}}
+		var last *Sample
+
+		for _, s := range g.samples {
+			switch strings.TrimPrefix(s.Name, f.Name) {
+			case "_bucket":
+				bound, err := parseFloat(s.Labels[bucketLabel])
+				if err != nil {
+					continue
+				}
+				h.DataPoints[bound] = s.Value
+			case "_sum", "_gsum":
+				h.Sum = s.Value
+			case "_count", "_gcount":
+				h.Count = int(s.Value)
+				last = s
+			}
+		}
+
+		if _, ok := h.DataPoints[math.Inf(1)]; !ok && last != nil {
+			h.DataPoints[math.Inf(1)] = float64(h.Count)
+		}
+
+		a.add(f.Name, g.labels, bucketLabel, h, f, last, plugin.MetricTypeHistogram())
+	}
+}
+
+func (a *adder) addSummaries(f *Family) {
+	for _, g := range groupSamples(f, quantileLabel) {
+		sm := plugin.Summary{}
+		var last *Sample
+
+		for _, s := range g.samples {
+			switch strings.TrimPrefix(s.Name, f.Name) {
+			case "_sum":
+				sm.Sum = s.Value
+			case "_count":
+				sm.Count = int(s.Value)
+				last = s
+			}
+		}
+
+		a.add(f.Name, g.labels, quantileLabel, sm, f, last, plugin.MetricTypeSummary())
+	}
+}
+
+func labelsKey(labels map[string]string, skip string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if k != skip {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s=%q,", k, labels[k])
+	}
+
+	return sb.String()
+}
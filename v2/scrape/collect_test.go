@@ -0,0 +1,208 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package scrape
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+	pluginMock "github.com/solarwinds/snap-plugin-lib/v2/mock"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/stretchr/testify/mock"
+)
+
+// addedMetrics registers AddMetric calls on mocked context (modifiers are applied to returned metrics)
+func addedMetrics(ctx *pluginMock.Context, addErr error) map[string]*types.Metric {
+	mts := map[string]*types.Metric{}
+
+	ctx.On("AddMetric", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			mt := &types.Metric{Value_: args.Get(1)}
+			for _, m := range args.Get(2).([]plugin.MetricModifier) {
+				m.UpdateMetric(mt)
+			}
+			mts[args.String(0)] = mt
+		}).
+		Return(addErr)
+
+	return mts
+}
+
+func TestAddMetrics(t *testing.T) {
+	Convey("Validate that samples are converted to metrics", t, func() {
+		ctx := &pluginMock.Context{}
+		ctx.On("ShouldProcess", mock.Anything).Return(true)
+		mts := addedMetrics(ctx, nil)
+
+		cfg := Config{Prefix: []string{"app"}, DynamicLabels: []string{"code"}}
+		err := AddMetrics(ctx, strings.NewReader(textExposition), FormatText, cfg)
+		So(err, ShouldBeNil)
+
+		So(len(mts), ShouldEqual, 6)
+
+		counter := mts["/app/[code=200]/http_requests_total"]
+		So(counter, ShouldNotBeNil)
+		So(counter.Value_, ShouldEqual, 1027)
+		So(counter.Type_, ShouldEqual, plugin.SumType)
+		So(counter.Tags_, ShouldResemble, map[string]string{"method": "post"})
+		So(counter.Description_, ShouldEqual, "The total number of HTTP requests.")
+		So(counter.Timestamp_.UnixMilli(), ShouldEqual, 1395066363000)
+
+		So(mts["/app/[code=400]/http_requests_total"], ShouldNotBeNil)
+		So(mts["/app/[code=none]/metric_without_timestamp_and_labels"].Type_, ShouldEqual, plugin.UnknownType)
+		So(mts["/app/[code=none]/escaped"].Type_, ShouldEqual, plugin.GaugeType)
+
+		histogram := mts["/app/[code=none]/http_request_duration_seconds"]
+		So(histogram.Type_, ShouldEqual, plugin.HistogramType)
+		So(histogram.Value_, ShouldResemble, plugin.Histogram{
+			DataPoints: map[float64]float64{0.05: 24054, 0.5: 129389, math.Inf(1): 144320},
+			Count:      144320,
+			Sum:        53423,
+		})
+
+		summary := mts["/app/[code=none]/rpc_duration_seconds"]
+		So(summary.Type_, ShouldEqual, plugin.SummaryType)
+		So(summary.Value_, ShouldResemble, plugin.Summary{Count: 2693, Sum: 1.7560473e+07})
+	})
+
+	Convey("Validate that OpenMetrics families are converted to metrics", t, func() {
+		ctx := &pluginMock.Context{}
+		ctx.On("ShouldProcess", mock.Anything).Return(true)
+		mts := addedMetrics(ctx, nil)
+
+		cfg := Config{Prefix: []string{"app"}, SplitName: true}
+		err := AddMetrics(ctx, strings.NewReader(openMetricsExposition), FormatOpenMetrics, cfg)
+		So(err, ShouldBeNil)
+
+		So(len(mts), ShouldEqual, 4)
+
+		summary := mts["/app/acme/http/router/request/seconds"]
+		So(summary.Value_, ShouldResemble, plugin.Summary{Count: 807283, Sum: 9036.32})
+		So(summary.Unit_, ShouldEqual, "seconds")
+		So(summary.Tags_, ShouldResemble, map[string]string{"path": "/api/v1", "method": "GET"})
+
+		So(mts["/app/process/cpu/seconds/total"].Type_, ShouldEqual, plugin.SumType)
+		So(mts["/app/build/info"].Tags_, ShouldResemble, map[string]string{"version": "1.0"})
+		So(mts["/app/go/goroutines"].Value_, ShouldEqual, 69)
+	})
+
+	Convey("Validate that families which shouldn't be processed are skipped", t, func() {
+		ctx := &pluginMock.Context{}
+		ctx.On("ShouldProcess", "/app/[code=*]/http_requests_total").Return(true)
+		ctx.On("ShouldProcess", mock.Anything).Return(false)
+		mts := addedMetrics(ctx, nil)
+
+		cfg := Config{Prefix: []string{"app"}, DynamicLabels: []string{"code"}}
+		err := AddMetrics(ctx, strings.NewReader(textExposition), FormatText, cfg)
+		So(err, ShouldBeNil)
+
+		So(len(mts), ShouldEqual, 2)
+		ctx.AssertCalled(t, "ShouldProcess", "/app/[code=*]/rpc_duration_seconds")
+	})
+
+	Convey("Validate that families are filtered by the same namespace as the one of added metrics", t, func() {
+		ctx := &pluginMock.Context{}
+		ctx.On("ShouldProcess", mock.Anything).Return(true)
+		mts := addedMetrics(ctx, nil)
+
+		cfg := Config{Prefix: []string{"app"}}
+
+		err := AddMetrics(ctx, strings.NewReader("# TYPE requests counter\nrequests 1\n"), FormatText, cfg)
+		So(err, ShouldBeNil)
+		So(mts["/app/requests"], ShouldNotBeNil)
+		ctx.AssertCalled(t, "ShouldProcess", "/app/requests")
+		ctx.AssertNotCalled(t, "ShouldProcess", "/app/requests_total")
+
+		err = AddMetrics(ctx, strings.NewReader("# TYPE requests counter\nrequests_total 1\n# EOF\n"), FormatOpenMetrics, cfg)
+		So(err, ShouldBeNil)
+		So(mts["/app/requests_total"], ShouldNotBeNil)
+		ctx.AssertCalled(t, "ShouldProcess", "/app/requests_total")
+	})
+
+	Convey("Validate that metrics which couldn't be added are reported as warning", t, func() {
+		ctx := &pluginMock.Context{}
+		ctx.On("ShouldProcess", mock.Anything).Return(true)
+		ctx.On("AddWarning", mock.Anything).Return()
+		_ = addedMetrics(ctx, errMetricNotDefined)
+
+		err := AddMetrics(ctx, strings.NewReader("a 1\nb 2\n"), FormatText, Config{Prefix: []string{"app"}})
+		So(err, ShouldBeNil)
+
+		ctx.AssertCalled(t, "AddWarning", "2 scraped metric(s) couldn't be added, first error: metric not defined")
+	})
+
+	Convey("Validate that invalid exposition is reported as error", t, func() {
+		ctx := &pluginMock.Context{}
+		ctx.On("ShouldProcess", mock.Anything).Return(true)
+
+		err := AddMetrics(ctx, strings.NewReader("a{b=c} 1\n"), FormatText, Config{Prefix: []string{"app"}})
+		So(err, ShouldBeError)
+	})
+}
+
+type testError string
+
+func (e testError) Error() string { return string(e) }
+
+const errMetricNotDefined = testError("metric not defined")
+
+func TestScrape(t *testing.T) {
+	Convey("Validate that metrics are scraped from HTTP endpoint", t, func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+			_, _ = w.Write([]byte(openMetricsExposition))
+		}))
+		defer srv.Close()
+
+		ctx := &pluginMock.Context{}
+		ctx.On("RawContext").Return(context.Background())
+		ctx.On("ShouldProcess", mock.Anything).Return(true)
+		mts := addedMetrics(ctx, nil)
+
+		err := Scrape(ctx, nil, srv.URL, Config{Prefix: []string{"app"}})
+		So(err, ShouldBeNil)
+		So(mts["/app/process_cpu_seconds_total"], ShouldNotBeNil)
+
+		err = Scrape(ctx, nil, srv.URL+"/%zz", Config{Prefix: []string{"app"}})
+		So(err, ShouldBeError)
+	})
+
+	Convey("Validate that unexpected status is reported as error", t, func() {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		defer srv.Close()
+
+		ctx := &pluginMock.Context{}
+		ctx.On("RawContext").Return(context.Background())
+
+		err := Scrape(ctx, srv.Client(), srv.URL, Config{Prefix: []string{"app"}})
+		So(err, ShouldBeError)
+		So(err.Error(), ShouldContainSubstring, "404")
+	})
+}
@@ -0,0 +1,406 @@
+/*
+Package scrape helps writing collectors which gather metrics exposed by applications in Prometheus text or OpenMetrics format.
+
+Exposition is parsed family by family, so families which shouldn't be processed (see: plugin.CollectContext.ShouldProcess)
+are skipped without parsing their samples.
+*/
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package scrape
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format of exposition
+type Format int
+
+const (
+	FormatText        Format = iota // Prometheus text format (version 0.0.4)
+	FormatOpenMetrics               // OpenMetrics text format
+)
+
+const openMetricsContentType = "application/openmetrics-text"
+
+// Types of families
+const (
+	TypeCounter        = "counter"
+	TypeGauge          = "gauge"
+	TypeHistogram      = "histogram"
+	TypeGaugeHistogram = "gaugehistogram"
+	TypeSummary        = "summary"
+	TypeInfo           = "info"
+	TypeStateSet       = "stateset"
+	TypeUntyped        = "untyped"
+	TypeUnknown        = "unknown"
+)
+
+// sample name suffixes which belong to family of a given type
+var typeSuffixes = map[string][]string{
+	TypeCounter:        {"_total", "_created"},
+	TypeHistogram:      {"_bucket", "_sum", "_count", "_created"},
+	TypeGaugeHistogram: {"_bucket", "_gsum", "_gcount"},
+	TypeSummary:        {"_sum", "_count", "_created"},
+	TypeInfo:           {"_info"},
+}
+
+// FormatFromContentType recognizes format based on Content-Type header
+func FormatFromContentType(contentType string) Format {
+	if strings.HasPrefix(strings.TrimSpace(contentType), openMetricsContentType) {
+		return FormatOpenMetrics
+	}
+
+	return FormatText
+}
+
+// Family groups samples of one metric, described by HELP, TYPE and UNIT metadata
+type Family struct {
+	Name    string
+	Type    string
+	Help    string
+	Unit    string
+	Samples []Sample
+}
+
+// Sample is a single line of exposition
+type Sample struct {
+	Name      string // name of sample (with suffix, ie. "_bucket")
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time // zero, when not exposed
+}
+
+// Parse reads all metric families from exposition
+func Parse(r io.Reader, format Format) ([]*Family, error) {
+	return parse(r, format, nil)
+}
+
+// parse reads exposition family by family. When accept returns false, samples of family are skipped (not parsed).
+func parse(r io.Reader, format Format, accept func(name, typ string) bool) ([]*Family, error) {
+	p := &parser{format: format, accept: accept}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+
+		err := p.parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exposition (line %d): %w", lineNo, err)
+		}
+		if p.eof {
+			break
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("can't read exposition: %w", err)
+	}
+
+	p.closeFamily()
+	return p.families, nil
+}
+
+type parser struct {
+	format Format
+	accept func(name, typ string) bool
+
+	families []*Family
+	current  *Family
+	accepted bool // false when samples of current family should be skipped
+	hasType  bool // true when TYPE of current family was defined
+	eof      bool
+}
+
+func (p *parser) parseLine(line string) error {
+	switch {
+	case line == "":
+		return nil
+	case line == "# EOF":
+		p.eof = true
+		return nil
+	case strings.HasPrefix(line, "#"):
+		return p.parseMetadata(line)
+	default:
+		return p.parseSampleLine(line)
+	}
+}
+
+// parseMetadata handles "# HELP", "# TYPE" and "# UNIT" lines (other comments are ignored)
+func (p *parser) parseMetadata(line string) error {
+	fields := strings.SplitN(strings.TrimSpace(line[1:]), " ", 3)
+	if len(fields) < 2 {
+		return nil
+	}
+
+	keyword, name, rest := fields[0], fields[1], ""
+	if len(fields) == 3 {
+		rest = strings.TrimSpace(fields[2])
+	}
+
+	switch keyword {
+	case "HELP", "TYPE", "UNIT":
+	default:
+		return nil
+	}
+
+	if p.current == nil || p.current.Name != name {
+		p.openFamily(name)
+	}
+
+	switch keyword {
+	case "HELP":
+		p.current.Help = unescapeHelp(rest, p.format)
+	case "TYPE":
+		typ := strings.ToLower(rest)
+		if !isKnownType(typ) {
+			return fmt.Errorf("unknown metric type: %s", typ)
+		}
+		p.current.Type = typ
+		p.hasType = true
+		p.accepted = p.accept == nil || p.accept(p.current.Name, p.current.Type)
+	case "UNIT":
+		p.current.Unit = rest
+	}
+
+	return nil
+}
+
+func (p *parser) parseSampleLine(line string) error {
+	name := sampleName(line)
+	if name == "" {
+		return fmt.Errorf("missing metric name")
+	}
+
+	if p.current == nil || !belongsTo(name, p.current) {
+		p.openFamily(name)
+		p.current.Type = TypeUntyped
+		if p.format == FormatOpenMetrics {
+			p.current.Type = TypeUnknown
+		}
+		p.hasType = true
+		p.accepted = p.accept == nil || p.accept(p.current.Name, p.current.Type)
+	} else if !p.hasType {
+		// family was opened by HELP/UNIT only - TYPE won't be provided anymore
+		p.current.Type = TypeUntyped
+		p.hasType = true
+		p.accepted = p.accept == nil || p.accept(p.current.Name, p.current.Type)
+	}
+
+	if !p.accepted {
+		return nil
+	}
+
+	s, err := parseSample(line, name, p.format)
+	if err != nil {
+		return err
+	}
+
+	p.current.Samples = append(p.current.Samples, s)
+	return nil
+}
+
+func (p *parser) openFamily(name string) {
+	p.closeFamily()
+
+	p.current = &Family{Name: name}
+	p.hasType = false
+	p.accepted = true
+}
+
+func (p *parser) closeFamily() {
+	if p.current != nil && p.accepted && len(p.current.Samples) != 0 {
+		p.families = append(p.families, p.current)
+	}
+
+	p.current = nil
+}
+
+func isKnownType(typ string) bool {
+	switch typ {
+	case TypeCounter, TypeGauge, TypeHistogram, TypeGaugeHistogram, TypeSummary, TypeInfo, TypeStateSet, TypeUntyped, TypeUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// belongsTo checks if sample of a given name is part of family
+func belongsTo(sampleName string, f *Family) bool {
+	if sampleName == f.Name {
+		return true
+	}
+
+	if !strings.HasPrefix(sampleName, f.Name) {
+		return false
+	}
+
+	suffix := sampleName[len(f.Name):]
+	for _, s := range typeSuffixes[f.Type] {
+		if suffix == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sampleName(line string) string {
+	end := strings.IndexAny(line, "{ \t")
+	if end == -1 {
+		return ""
+	}
+
+	return line[:end]
+}
+
+// parseSample parses line: name{label="value",...} value [timestamp] [# exemplar]
+func parseSample(line string, name string, format Format) (Sample, error) {
+	s := Sample{Name: name}
+	rest := line[len(name):]
+
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parseLabels(rest)
+		if err != nil {
+			return s, err
+		}
+		s.Labels = labels
+		rest = rest[n:]
+	}
+
+	if i := strings.Index(rest, " #"); i != -1 && format == FormatOpenMetrics {
+		rest = rest[:i] // exemplar
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return s, fmt.Errorf("invalid sample of %s", name)
+	}
+
+	var err error
+	s.Value, err = parseFloat(fields[0])
+	if err != nil {
+		return s, fmt.Errorf("invalid value of %s: %w", name, err)
+	}
+
+	if len(fields) == 2 {
+		s.Timestamp, err = parseTimestamp(fields[1], format)
+		if err != nil {
+			return s, fmt.Errorf("invalid timestamp of %s: %w", name, err)
+		}
+	}
+
+	return s, nil
+}
+
+// parseLabels parses {label="value",...} and returns number of consumed bytes
+func parseLabels(s string) (map[string]string, int, error) {
+	labels := map[string]string{}
+	i := 1 // skip '{'
+
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq == -1 {
+			return nil, 0, fmt.Errorf("invalid label set")
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("value of label %s should be quoted", name)
+		}
+		i++
+
+		var sb strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				default:
+					sb.WriteByte(s[i])
+				}
+				continue
+			}
+			sb.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated value of label %s", name)
+		}
+		i++ // skip closing quote
+
+		labels[name] = sb.String()
+	}
+}
+
+func parseFloat(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+// timestamp is expressed in milliseconds (text format) or seconds (OpenMetrics)
+func parseTimestamp(s string, format Format) (time.Time, error) {
+	if format == FormatOpenMetrics {
+		sec, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(sec*float64(time.Second))), nil
+	}
+
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func unescapeHelp(s string, format Format) string {
+	replacer := strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+	if format == FormatOpenMetrics {
+		replacer = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\"`, `"`)
+	}
+
+	return replacer.Replace(s)
+}
@@ -0,0 +1,167 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package scrape
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const textExposition = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+
+# Minimalistic line:
+metric_without_timestamp_and_labels 12.47
+
+# HELP escaped Help with \\ and \n.
+# TYPE escaped gauge
+escaped{path="C:\\DIR\\FILE.TXT",msg="Cannot find file:\n\"FILE.TXT\""} 1.458255915e9
+
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{le="0.05"} 24054
+http_request_duration_seconds_bucket{le="0.5"} 129389
+http_request_duration_seconds_bucket{le="+Inf"} 144320
+http_request_duration_seconds_sum 53423
+http_request_duration_seconds_count 144320
+
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 4773
+rpc_duration_seconds_sum 1.7560473e+07
+rpc_duration_seconds_count 2693
+`
+
+const openMetricsExposition = `# TYPE acme_http_router_request_seconds summary
+# UNIT acme_http_router_request_seconds seconds
+# HELP acme_http_router_request_seconds Latency though all of ACME's HTTP request router.
+acme_http_router_request_seconds_sum{path="/api/v1",method="GET"} 9036.32
+acme_http_router_request_seconds_count{path="/api/v1",method="GET"} 807283.0
+acme_http_router_request_seconds_created{path="/api/v1",method="GET"} 1605281325.0
+# TYPE go_goroutines gauge
+go_goroutines 69
+# TYPE process_cpu_seconds counter
+# UNIT process_cpu_seconds seconds
+process_cpu_seconds_total 4.20072246e+06 1605281325.5 # {trace_id="KOO5S4vxi0o"} 0.67
+# TYPE build info
+build_info{version="1.0"} 1
+# EOF
+ignored_after_eof 1
+`
+
+func TestParse(t *testing.T) {
+	Convey("Validate that Prometheus text format is parsed", t, func() {
+		families, err := Parse(strings.NewReader(textExposition), FormatText)
+		So(err, ShouldBeNil)
+		So(len(families), ShouldEqual, 5)
+
+		So(families[0].Name, ShouldEqual, "http_requests_total")
+		So(families[0].Type, ShouldEqual, TypeCounter)
+		So(families[0].Help, ShouldEqual, "The total number of HTTP requests.")
+		So(families[0].Samples, ShouldResemble, []Sample{
+			{Name: "http_requests_total", Labels: map[string]string{"method": "post", "code": "200"}, Value: 1027, Timestamp: time.UnixMilli(1395066363000)},
+			{Name: "http_requests_total", Labels: map[string]string{"method": "post", "code": "400"}, Value: 3, Timestamp: time.UnixMilli(1395066363000)},
+		})
+
+		So(families[1].Name, ShouldEqual, "metric_without_timestamp_and_labels")
+		So(families[1].Type, ShouldEqual, TypeUntyped)
+		So(families[1].Samples[0].Value, ShouldEqual, 12.47)
+
+		So(families[2].Help, ShouldEqual, "Help with \\ and \n.")
+		So(families[2].Samples[0].Labels, ShouldResemble, map[string]string{"path": `C:\DIR\FILE.TXT`, "msg": "Cannot find file:\n\"FILE.TXT\""})
+
+		So(families[3].Type, ShouldEqual, TypeHistogram)
+		So(len(families[3].Samples), ShouldEqual, 5)
+		So(families[3].Samples[2].Labels["le"], ShouldEqual, "+Inf")
+
+		So(families[4].Type, ShouldEqual, TypeSummary)
+		So(len(families[4].Samples), ShouldEqual, 3)
+	})
+
+	Convey("Validate that OpenMetrics format is parsed", t, func() {
+		families, err := Parse(strings.NewReader(openMetricsExposition), FormatOpenMetrics)
+		So(err, ShouldBeNil)
+		So(len(families), ShouldEqual, 4)
+
+		So(families[0].Name, ShouldEqual, "acme_http_router_request_seconds")
+		So(families[0].Unit, ShouldEqual, "seconds")
+		So(len(families[0].Samples), ShouldEqual, 3)
+
+		So(families[2].Name, ShouldEqual, "process_cpu_seconds")
+		So(families[2].Samples[0].Name, ShouldEqual, "process_cpu_seconds_total")
+		So(families[2].Samples[0].Value, ShouldEqual, 4.20072246e+06)
+		So(families[2].Samples[0].Timestamp, ShouldEqual, time.Unix(1605281325, 500000000))
+
+		So(families[3].Type, ShouldEqual, TypeInfo)
+		So(families[3].Samples[0].Name, ShouldEqual, "build_info")
+	})
+
+	Convey("Validate that special values are parsed", t, func() {
+		families, err := Parse(strings.NewReader("a +Inf\nb -Inf\nc NaN\n"), FormatText)
+		So(err, ShouldBeNil)
+		So(math.IsInf(families[0].Samples[0].Value, 1), ShouldBeTrue)
+		So(math.IsInf(families[1].Samples[0].Value, -1), ShouldBeTrue)
+		So(math.IsNaN(families[2].Samples[0].Value), ShouldBeTrue)
+	})
+
+	Convey("Validate that invalid exposition is rejected", t, func() {
+		for _, expo := range []string{
+			"metric\n",
+			"metric abc\n",
+			"metric{label=\"value\" 1\n",
+			"metric{label=value} 1\n",
+			"metric 1 2 3\n",
+			"# TYPE metric histogramm\n",
+		} {
+			_, err := Parse(strings.NewReader(expo), FormatText)
+			So(err, ShouldBeError)
+		}
+	})
+
+	Convey("Validate that samples of not accepted families aren't parsed", t, func() {
+		var checked []string
+		families, err := parse(strings.NewReader(textExposition+"# TYPE invalid gauge\ninvalid{ 1\n"), FormatText, func(name, typ string) bool {
+			checked = append(checked, name+":"+typ)
+			return typ == TypeHistogram
+		})
+
+		So(err, ShouldBeNil)
+		So(len(families), ShouldEqual, 1)
+		So(families[0].Name, ShouldEqual, "http_request_duration_seconds")
+		So(checked, ShouldResemble, []string{
+			"http_requests_total:counter",
+			"metric_without_timestamp_and_labels:untyped",
+			"escaped:gauge",
+			"http_request_duration_seconds:histogram",
+			"rpc_duration_seconds:summary",
+			"invalid:gauge",
+		})
+	})
+
+	Convey("Validate that format is recognized based on content type", t, func() {
+		So(FormatFromContentType("application/openmetrics-text; version=1.0.0; charset=utf-8"), ShouldEqual, FormatOpenMetrics)
+		So(FormatFromContentType("text/plain; version=0.0.4"), ShouldEqual, FormatText)
+		So(FormatFromContentType(""), ShouldEqual, FormatText)
+	})
+}
@@ -0,0 +1,135 @@
+/*
+ Copyright (c) 2022 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+const (
+	pluginStartTimeout = 10 * time.Second // max time to wait for meta information printed by plugin
+	pluginStopTimeout  = 10 * time.Second // max time to wait for plugin graceful shutdown, then it's killed
+)
+
+// launched plugin (if any) is stopped before snap-mock exits
+var launched *pluginProcess
+
+// pluginProcess represents plugin started by snap-mock (-plugin-binary)
+type pluginProcess struct {
+	cmd     *exec.Cmd
+	done    chan struct{} // closed when process has ended
+	exitErr error
+}
+
+type metaResult struct {
+	meta *pluginMeta
+	err  error
+}
+
+// launchPlugin starts plugin binary and waits until it prints meta information
+func launchPlugin(binary string, args []string) (*pluginProcess, *pluginMeta, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't create pipe for plugin output: %v", err)
+	}
+	defer func() { _ = pr.Close() }()
+
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout = pw
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Start()
+	_ = pw.Close() // plugin holds its own copy
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't start plugin: %v", err)
+	}
+
+	p := &pluginProcess{
+		cmd:  cmd,
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		p.exitErr = cmd.Wait()
+		close(p.done)
+	}()
+
+	metaCh := make(chan metaResult, 1)
+	go func() {
+		meta, err := parsePluginMeta(pr)
+		metaCh <- metaResult{meta: meta, err: err}
+
+		// rest of the output is consumed, so plugin isn't blocked on writing
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	select {
+	case res := <-metaCh:
+		if res.err != nil {
+			p.stop()
+			return nil, nil, res.err
+		}
+		return p, res.meta, nil
+	case <-p.done:
+		return nil, nil, fmt.Errorf("plugin has ended before providing meta information (%v)", p.exitErr)
+	case <-time.After(pluginStartTimeout):
+		p.stop()
+		return nil, nil, errors.New("timeout when waiting for plugin meta information")
+	}
+}
+
+// stop requests plugin to shut down gracefully (kills it after timeout) and waits for process to end
+func (p *pluginProcess) stop() {
+	if p == nil {
+		return
+	}
+
+	select {
+	case <-p.done:
+	default:
+		err := p.cmd.Process.Signal(syscall.SIGTERM)
+		if err != nil { // not supported on all platforms
+			_ = p.cmd.Process.Kill()
+		}
+
+		select {
+		case <-p.done:
+		case <-time.After(pluginStopTimeout):
+			fmt.Printf("!! Plugin hasn't stopped in %v, killing\n", pluginStopTimeout)
+			_ = p.cmd.Process.Kill()
+			<-p.done
+		}
+	}
+
+	if p.exitErr != nil {
+		fmt.Printf("Plugin has ended: %v\n", p.exitErr)
+	} else {
+		fmt.Printf("Plugin has ended\n")
+	}
+}
+
+// exit stops launched plugin before snap-mock ends with given code
+func exit(code int) {
+	launched.stop()
+	os.Exit(code)
+}
@@ -0,0 +1,262 @@
+/*
+ Copyright (c) 2022 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultLoadRate     = 10.0
+	defaultLoadDuration = 30 * time.Second
+)
+
+// taskLoadStats holds results of collect requests sent for a single task
+type taskLoadStats struct {
+	taskID    string
+	latencies []time.Duration
+	errors    int
+	metrics   int
+	busy      bool // collect request for the task is in progress
+}
+
+type loadTester struct {
+	opt        *Options
+	collClient pluginrpc.CollectorClient
+	rec        *recorder
+
+	tasks []*Options
+
+	mu        sync.Mutex // guards stats, errorMsgs and skipped
+	stats     []*taskLoadStats
+	errorMsgs map[string]int
+	skipped   int // requests not sent because previous request for the same task was in progress
+}
+
+// runLoadTest loads several tasks and sends collect requests at given rate, returns exit code
+func runLoadTest(opt *Options, cl *grpc.ClientConn, rec *recorder) int {
+	if opt.LoadRate <= 0 {
+		fmt.Printf("-load-rate has to be positive\n")
+		return 1
+	}
+
+	lt := &loadTester{
+		opt:        opt,
+		collClient: pluginrpc.NewCollectorClient(cl),
+		rec:        rec,
+		errorMsgs:  map[string]int{},
+	}
+
+	tasksCount := loadTasksCount(opt.LoadTasks, opt.TasksLimit)
+	if tasksCount <= 0 {
+		fmt.Printf("-load-tasks has to be positive\n")
+		return 1
+	}
+	if tasksCount < opt.LoadTasks {
+		fmt.Printf("Plugin handles up to %d task(s), number of tasks is limited\n", opt.TasksLimit)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go pingLoop(ctx, pluginrpc.NewControllerClient(cl), opt.PingInterval)
+
+	defer lt.unloadTasks()
+
+	for i := 0; i < tasksCount; i++ {
+		taskOpt := *opt
+		taskOpt.TaskId = fmt.Sprintf("%s-%d", opt.TaskId, i+1)
+		taskOpt.IsStream = false
+
+		err := doLoadRequest(lt.collClient, &taskOpt)
+		if err != nil {
+			fmt.Printf("Can't load task %s (%v)\n", taskOpt.TaskId, err)
+			return 1
+		}
+
+		lt.tasks = append(lt.tasks, &taskOpt)
+		lt.stats = append(lt.stats, &taskLoadStats{taskID: taskOpt.TaskId})
+	}
+
+	fmt.Printf("Loaded %d task(s), sending %.1f collect request(s) per second for %v\n", len(lt.tasks), opt.LoadRate, opt.LoadDuration)
+
+	elapsed := lt.run()
+	lt.report(os.Stdout, elapsed)
+
+	return 0
+}
+
+// loadTasksCount returns number of tasks used in load test, limited by number of tasks handled by plugin
+func loadTasksCount(requested int, tasksLimit int) int {
+	if tasksLimit != plugin.NoLimit && tasksLimit > 0 && requested > tasksLimit {
+		return tasksLimit
+	}
+
+	return requested
+}
+
+// run sends collect requests to tasks (round-robin) at constant rate, regardless of responses time.
+// As snap does, request isn't sent when previous one for the same task is still in progress.
+func (lt *loadTester) run() time.Duration {
+	if len(lt.tasks) == 0 {
+		return 0
+	}
+
+	wg := sync.WaitGroup{}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / lt.opt.LoadRate))
+	defer ticker.Stop()
+
+	start := time.Now()
+	end := time.After(lt.opt.LoadDuration)
+
+	for i := 0; ; i++ {
+		select {
+		case <-end:
+			wg.Wait()
+			return time.Since(start)
+		case <-ticker.C:
+		}
+
+		taskIdx := i % len(lt.tasks)
+
+		lt.mu.Lock()
+		busy := lt.stats[taskIdx].busy
+		if busy {
+			lt.skipped++
+		} else {
+			lt.stats[taskIdx].busy = true
+		}
+		lt.mu.Unlock()
+
+		if busy {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lt.collect(taskIdx)
+		}()
+	}
+}
+
+func (lt *loadTester) collect(taskIdx int) {
+	var err error
+	mtsCount := 0
+
+	reqStart := time.Now()
+	for chunk := range doCollectRequest(lt.collClient, lt.tasks[taskIdx], lt.rec) {
+		mtsCount += len(chunk.mts)
+		if chunk.err != nil {
+			err = chunk.err
+		}
+	}
+	latency := time.Since(reqStart)
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	stats := lt.stats[taskIdx]
+	stats.busy = false
+	stats.latencies = append(stats.latencies, latency)
+	stats.metrics += mtsCount
+	if err != nil {
+		stats.errors++
+		lt.errorMsgs[err.Error()]++
+	}
+}
+
+func (lt *loadTester) unloadTasks() {
+	for _, taskOpt := range lt.tasks {
+		err := doUnloadRequest(lt.collClient, taskOpt)
+		if err != nil {
+			fmt.Printf("Can't unload task %s (%v)\n", taskOpt.TaskId, err)
+		}
+	}
+}
+
+func (lt *loadTester) report(w io.Writer, elapsed time.Duration) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	_, _ = fmt.Fprintf(tw, "\nTask\tRequests\tErrors\tp50\tp90\tp99\tMax\tMetrics/s\t\n")
+
+	var allLatencies []time.Duration
+	totalErrors, totalMetrics := 0, 0
+
+	for _, stats := range lt.stats {
+		allLatencies = append(allLatencies, stats.latencies...)
+		totalErrors += stats.errors
+		totalMetrics += stats.metrics
+
+		writeLoadRow(tw, stats.taskID, stats.latencies, stats.errors, stats.metrics, elapsed)
+	}
+
+	writeLoadRow(tw, "Total", allLatencies, totalErrors, totalMetrics, elapsed)
+	_ = tw.Flush()
+
+	requests := len(allLatencies)
+	errorRate := 0.0
+	if requests > 0 {
+		errorRate = 100 * float64(totalErrors) / float64(requests)
+	}
+
+	_, _ = fmt.Fprintf(w, "\nDuration: %v\n", elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(w, "Throughput: %.2f request(s)/s, %.2f metric(s)/s\n", float64(requests)/elapsed.Seconds(), float64(totalMetrics)/elapsed.Seconds())
+	_, _ = fmt.Fprintf(w, "Error rate: %.2f%% (%d of %d)\n", errorRate, totalErrors, requests)
+	_, _ = fmt.Fprintf(w, "Skipped requests (previous request for task in progress): %d\n", lt.skipped)
+
+	for msg, count := range lt.errorMsgs {
+		_, _ = fmt.Fprintf(w, " %dx %s\n", count, msg)
+	}
+}
+
+func writeLoadRow(tw *tabwriter.Writer, name string, latencies []time.Duration, errors int, metrics int, elapsed time.Duration) {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\t%v\t%.2f\t\n",
+		name, len(sorted), errors,
+		percentile(sorted, 0.5), percentile(sorted, 0.9), percentile(sorted, 0.99), percentile(sorted, 1),
+		float64(metrics)/elapsed.Seconds())
+}
+
+// percentile returns p-th percentile (0.0-1.0) of sorted durations (nearest-rank method)
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank].Round(time.Microsecond)
+}
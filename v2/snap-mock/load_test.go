@@ -0,0 +1,70 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/plugin"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1 * time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond}
+
+	testCases := []struct {
+		name      string
+		latencies []time.Duration
+		p         float64
+		want      time.Duration
+	}{
+		{"no samples", nil, 0.5, 0},
+		{"single sample (p50)", []time.Duration{5 * time.Millisecond}, 0.5, 5 * time.Millisecond},
+		{"single sample (p99)", []time.Duration{5 * time.Millisecond}, 0.99, 5 * time.Millisecond},
+		{"lowest rank", sorted, 0, 1 * time.Millisecond},
+		{"median", sorted, 0.5, 2 * time.Millisecond},
+		{"p90", sorted, 0.9, 4 * time.Millisecond},
+		{"max", sorted, 1, 4 * time.Millisecond},
+	}
+
+	Convey("Validate that percentiles of latencies are calculated with nearest-rank method", t, func() {
+		for _, tc := range testCases {
+			Convey(fmt.Sprintf("Scenario: %v", tc.name), func() {
+				So(percentile(tc.latencies, tc.p), ShouldEqual, tc.want)
+			})
+		}
+	})
+}
+
+func TestLoadTasksCount(t *testing.T) {
+	Convey("Validate that number of tasks in load test is limited by plugin", t, func() {
+		So(loadTasksCount(4, plugin.NoLimit), ShouldEqual, 4)
+		So(loadTasksCount(4, 2), ShouldEqual, 2)
+		So(loadTasksCount(4, 8), ShouldEqual, 4)
+		So(loadTasksCount(4, -1), ShouldEqual, 4)
+	})
+
+	Convey("Validate that load test without tasks doesn't send requests", t, func() {
+		lt := &loadTester{opt: &Options{LoadRate: 100, LoadDuration: time.Second}}
+		So(lt.run(), ShouldEqual, 0)
+	})
+}
@@ -18,6 +18,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"io"
@@ -27,8 +29,10 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/service"
 	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -52,12 +56,25 @@ const (
 type Options struct {
 	PluginIP           string
 	CollectorPort      int
+	CollectorSocket    string
 	PublisherPort      int
 	CollectInterval    time.Duration
 	PingInterval       time.Duration
 	MaxCollectRequests int
 	SendKill           bool
 	RequestInfo        bool
+	AuthToken          string
+	AuthTokenFile      string
+
+	EnableTLS     bool
+	TLSCertPath   string
+	TLSKeyPath    string
+	TLSCAPath     string
+	TLSServerName string
+	PluginMeta    string
+
+	PluginBinary string
+	PluginArgs   string
 
 	IsStream       bool
 	StreamDuration time.Duration
@@ -65,6 +82,17 @@ type Options struct {
 	PluginConfig string
 	PluginFilter string
 	TaskId       string
+
+	Scenario string
+
+	LoadTasks    int
+	LoadRate     float64
+	LoadDuration time.Duration
+	TasksLimit   int // provided by plugin meta
+
+	Record      string
+	Replay      string
+	ReplaySpeed float64
 }
 
 const (
@@ -93,6 +121,10 @@ func parseCmdLine() *Options {
 		"collector-port", defaultGRPCPort,
 		"Port of GRPC Server run by plugin")
 
+	flag.StringVar(&opt.CollectorSocket,
+		"collector-socket", "",
+		"Unix domain socket of GRPC Server run by plugin (used instead of IP and port)")
+
 	flag.IntVar(&opt.PublisherPort,
 		"publisher-port", defaultGRPCPort,
 		"Port of GRPC Server run by publisher plugin")
@@ -137,8 +169,80 @@ func parseCmdLine() *Options {
 		"stream-duration", defaultStreamDuration,
 		"Duration of debugging streaming collector, after this time Unload request will be send")
 
+	flag.StringVar(&opt.AuthToken,
+		"auth-token", "",
+		"Authentication token sent in each request (when required by plugin)")
+
+	flag.StringVar(&opt.AuthTokenFile,
+		"auth-token-file", "",
+		"Path to file containing authentication token sent in each request (when required by plugin)")
+
+	flag.BoolVar(&opt.EnableTLS,
+		"tls", false,
+		"When set, TLS is used to connect to plugin (enabled automatically when client certificate is provided)")
+
+	flag.StringVar(&opt.TLSCertPath,
+		"tls-cert", "",
+		"Path to client certificate (PEM) presented to plugin")
+
+	flag.StringVar(&opt.TLSKeyPath,
+		"tls-key", "",
+		"Path to client private key (PEM)")
+
+	flag.StringVar(&opt.TLSCAPath,
+		"tls-ca", "",
+		"Path to CA certificates (PEM) used to verify plugin certificate (system CAs are used when not provided)")
+
+	flag.StringVar(&opt.TLSServerName,
+		"tls-server-name", "",
+		"Server name used to verify plugin certificate (by default host part of plugin address)")
+
+	flag.StringVar(&opt.PluginMeta,
+		"plugin-meta", "",
+		"Path to file with plugin meta information (printed by plugin on startup, '-' for stdin) used to configure connection")
+
+	flag.StringVar(&opt.PluginBinary,
+		"plugin-binary", "",
+		"Path to plugin executable started by snap-mock (connection is configured based on its meta information, plugin is stopped at the end)")
+
+	flag.StringVar(&opt.PluginArgs,
+		"plugin-args", "",
+		"Arguments passed to plugin started with -plugin-binary (separated by spaces), ie. '-log-level=debug -tls'")
+
+	flag.StringVar(&opt.Scenario,
+		"scenario", "",
+		"Path to scenario file (YAML or JSON) describing tasks and steps to execute (task and collect related flags are ignored)")
+
+	flag.IntVar(&opt.LoadTasks,
+		"load-tasks", 0,
+		"Number of tasks loaded in load-testing mode (0 - load-testing disabled). Limited by number of tasks handled by plugin, when known from meta information")
+
+	flag.Float64Var(&opt.LoadRate,
+		"load-rate", defaultLoadRate,
+		"Number of collect requests sent per second (for all tasks) in load-testing mode")
+
+	flag.DurationVar(&opt.LoadDuration,
+		"load-duration", defaultLoadDuration,
+		"Duration of load-testing")
+
+	flag.StringVar(&opt.Record,
+		"record", "",
+		"Path to file (NDJSON) where all collect responses received from plugin are recorded")
+
+	flag.StringVar(&opt.Replay,
+		"replay", "",
+		"Path to file created with -record, which content is sent to publisher (requires -publisher-port, collector is not used)")
+
+	flag.Float64Var(&opt.ReplaySpeed,
+		"replay-speed", 1.0,
+		"Replay speed relative to the recorded one, ie. 2 - twice as fast (0 means no delays between publish requests)")
+
 	flag.Parse()
 
+	if opt.TLSCertPath != "" {
+		opt.EnableTLS = true
+	}
+
 	if opt.TaskId == defaultTaskID {
 		opt.TaskId = fmt.Sprintf("task-%s", uuid.New().String())
 	}
@@ -146,6 +250,73 @@ func parseCmdLine() *Options {
 	return opt
 }
 
+// setFlags returns names of flags provided in command line
+func setFlags() map[string]bool {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	return set
+}
+
+func grpcDialOptions(opt *Options) ([]grpc.DialOption, error) {
+	transportCreds := insecure.NewCredentials()
+	if opt.EnableTLS {
+		var err error
+		transportCreds, err = tlsDialCredentials(opt)
+		if err != nil {
+			return nil, fmt.Errorf("can't configure TLS: %v", err)
+		}
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(transportCreds)}
+
+	token := opt.AuthToken
+	if opt.AuthTokenFile != "" {
+		content, err := os.ReadFile(opt.AuthTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read authentication token: %v", err)
+		}
+		token = strings.TrimSpace(string(content))
+	}
+
+	if token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(service.NewTokenCredentials(token)))
+	}
+
+	return dialOpts, nil
+}
+
+func tlsDialCredentials(opt *Options) (credentials.TransportCredentials, error) {
+	tlsConfig := &tls.Config{
+		ServerName: opt.TLSServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if opt.TLSCertPath != "" || opt.TLSKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(opt.TLSCertPath, opt.TLSKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if opt.TLSCAPath != "" {
+		caPEM, err := os.ReadFile(opt.TLSCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA certificates: %v", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificate found in %s", opt.TLSCAPath)
+		}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 func main() {
@@ -153,26 +324,86 @@ func main() {
 
 	opt := parseCmdLine()
 
+	if opt.PluginBinary != "" {
+		proc, meta, err := launchPlugin(opt.PluginBinary, strings.Fields(opt.PluginArgs))
+		if err != nil {
+			fmt.Printf("Can't launch plugin (%v)\n", err)
+			os.Exit(1)
+		}
+		launched = proc
+
+		fmt.Printf("Started %s plugin %s %s (pid %d)\n", meta.Plugin.Type, meta.Plugin.Name, meta.Plugin.Version, proc.cmd.Process.Pid)
+
+		err = applyPluginMeta(opt, meta, setFlags())
+		if err != nil {
+			fmt.Printf("Can't configure connection based on plugin meta (%v)\n", err)
+			exit(1)
+		}
+	} else if opt.PluginMeta != "" {
+		meta, err := readPluginMeta(opt.PluginMeta)
+		if err == nil {
+			err = applyPluginMeta(opt, meta, setFlags())
+		}
+		if err != nil {
+			fmt.Printf("Can't configure connection based on plugin meta (%v)\n", err)
+			os.Exit(1)
+		}
+	}
+
 	usePublisher := false
 	if opt.PublisherPort != defaultGRPCPort {
 		usePublisher = true
 	}
+	dialOpts, err := grpcDialOptions(opt)
+	if err != nil {
+		fmt.Printf("Invalid GRPC client options (%v)", err)
+		exit(1)
+	}
+
+	if opt.Replay != "" {
+		exit(runReplay(opt, dialOpts))
+	}
+
+	rec, err := newRecorder(opt.Record)
+	if err != nil {
+		fmt.Printf("%v", err)
+		exit(1)
+	}
+	defer func() { _ = rec.Close() }()
+
 	// Create connection
 	grpcServerCollAddr := fmt.Sprintf("%s:%d", opt.PluginIP, opt.CollectorPort)
-	clColl, err := grpc.Dial(grpcServerCollAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if opt.CollectorSocket != "" {
+		grpcServerCollAddr = fmt.Sprintf("unix://%s", opt.CollectorSocket)
+	}
+	clColl, err := grpc.Dial(grpcServerCollAddr, dialOpts...)
 	if err != nil {
 		fmt.Printf("Can't start GRPC Server on %s (%v)", grpcServerCollAddr, err)
-		os.Exit(1)
+		exit(1)
 	}
 	defer func() { _ = clColl.Close() }()
 
+	if opt.LoadTasks > 0 {
+		exitCode := runLoadTest(opt, clColl, rec)
+		_ = clColl.Close()
+		_ = rec.Close()
+		exit(exitCode)
+	}
+
+	if opt.Scenario != "" {
+		exitCode := runScenario(opt, clColl, rec)
+		_ = clColl.Close()
+		_ = rec.Close()
+		exit(exitCode)
+	}
+
 	var clPub *grpc.ClientConn
 	if usePublisher {
 		grpcServerPubAddr := fmt.Sprintf("%s:%d", opt.PluginIP, opt.PublisherPort)
-		clPub, err = grpc.Dial(grpcServerPubAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		clPub, err = grpc.Dial(grpcServerPubAddr, dialOpts...)
 		if err != nil {
 			fmt.Printf("Can't start GRPC Server on %s (%v)", grpcServerPubAddr, err)
-			os.Exit(1)
+			exit(1)
 		}
 		defer func() { _ = clPub.Close() }()
 	}
@@ -223,7 +454,7 @@ func main() {
 					break
 				}
 			}
-			os.Exit(stoppedByUser)
+			exit(stoppedByUser)
 		}()
 		time.Sleep(grpcLoadDelay)
 
@@ -246,7 +477,7 @@ func main() {
 
 			var mtsChunks [][]*pluginrpc.Metric
 
-			chunkCh := doCollectRequest(collClient, opt)
+			chunkCh := doCollectRequest(collClient, opt, rec)
 			for chunk := range chunkCh {
 				if err != nil {
 					doneCh <- fmt.Errorf("can't send collect request to plugin: %v", err)
@@ -333,13 +564,15 @@ func main() {
 	if opt.SendKill {
 		err := doKillRequest(contClient)
 		if err != nil {
-			doneCh <- fmt.Errorf("can't send kill request to plugin: %v", err)
+			fmt.Printf("Can't send kill request to plugin: %v\n", err)
 		}
 	}
 
 	if doneErr != nil {
-		fmt.Printf("Snap-mock exists because of error: %v", doneErr)
+		fmt.Printf("Snap-mock exists because of error: %v\n", doneErr)
 	}
+
+	launched.stop()
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -430,7 +663,10 @@ func doInfoRequest(cc pluginrpc.CollectorClient, opt *Options) ([]byte, error) {
 }
 
 func doPublishRequest(pc pluginrpc.PublisherClient, mts [][]*pluginrpc.Metric, opt *Options) error {
-	stream, _ := pc.Publish(context.Background())
+	stream, err := pc.Publish(context.Background())
+	if err != nil {
+		return err
+	}
 
 	for _, chunk := range mts {
 		reqPubl := &pluginrpc.PublishRequest{
@@ -443,11 +679,11 @@ func doPublishRequest(pc pluginrpc.PublisherClient, mts [][]*pluginrpc.Metric, o
 		}
 	}
 
-	_, err := stream.CloseAndRecv()
+	_, err = stream.CloseAndRecv()
 	return err
 }
 
-func doCollectRequest(cc pluginrpc.CollectorClient, opt *Options) chan collectChunk {
+func doCollectRequest(cc pluginrpc.CollectorClient, opt *Options, rec *recorder) chan collectChunk {
 	var recvMts []*pluginrpc.Metric
 	var recvWarns []string
 
@@ -468,6 +704,8 @@ func doCollectRequest(cc pluginrpc.CollectorClient, opt *Options) chan collectCh
 
 		defer func() { close(chunkCh) }()
 
+		collectSeq := rec.nextCollect()
+
 		stream, err := cc.Collect(ctx, reqColl)
 		if err != nil {
 			chunkCh <- collectChunk{
@@ -492,6 +730,11 @@ func doCollectRequest(cc pluginrpc.CollectorClient, opt *Options) chan collectCh
 				return
 			}
 
+			err = rec.record(opt.TaskId, collectSeq, resp)
+			if err != nil {
+				fmt.Printf("!! Can't record collect response (%v)\n", err)
+			}
+
 			recvMts = append(recvMts, resp.MetricSet...)
 
 			for _, warns := range resp.Warnings {
@@ -507,6 +750,7 @@ func doCollectRequest(cc pluginrpc.CollectorClient, opt *Options) chan collectCh
 
 				recvMts = nil
 				recvWarns = nil
+				collectSeq = rec.nextCollect()
 			}
 		}
 
@@ -520,6 +764,19 @@ func doCollectRequest(cc pluginrpc.CollectorClient, opt *Options) chan collectCh
 	return chunkCh
 }
 
+// pingLoop keeps plugin alive until ctx is cancelled. Errors are ignored - they are expected ie. after Kill request.
+func pingLoop(ctx context.Context, cc pluginrpc.ControllerClient, interval time.Duration) {
+	for {
+		_, _ = cc.Ping(ctx, &pluginrpc.PingRequest{})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 func grpcMetricToString(metric *pluginrpc.Metric) string {
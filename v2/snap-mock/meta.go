@@ -0,0 +1,151 @@
+/*
+ Copyright (c) 2022 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+)
+
+// pluginMeta contains fields of meta information (printed by plugin on startup) used by snap-mock
+type pluginMeta struct {
+	Plugin struct {
+		Name    string
+		Version string
+		Type    types.PluginType
+	}
+
+	GRPC struct {
+		IP               string
+		Port             int
+		Socket           string
+		TLSEnabled       bool
+		TokenAuthEnabled bool
+	}
+
+	Constraints struct {
+		InstancesLimit int
+		TasksLimit     int
+	}
+
+	Stats struct {
+		Enabled bool
+		IP      string
+		Port    int
+	}
+}
+
+// readPluginMeta reads meta information from file ("-" for stdin)
+func readPluginMeta(filePath string) (*pluginMeta, error) {
+	if filePath == "-" {
+		meta, err := parsePluginMeta(os.Stdin)
+
+		// plugin output piped to snap-mock is consumed, so plugin isn't blocked on writing
+		go func() { _, _ = io.Copy(io.Discard, os.Stdin) }()
+
+		return meta, err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("can't open plugin meta file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return parsePluginMeta(f)
+}
+
+// parsePluginMeta finds meta information in plugin output (first line being JSON object with GRPC section)
+func parsePluginMeta(r io.Reader) (*pluginMeta, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if json.Unmarshal([]byte(line), &fields) != nil || fields["GRPC"] == nil {
+			continue
+		}
+
+		meta := &pluginMeta{}
+		err := json.Unmarshal([]byte(line), meta)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plugin meta: %v", err)
+		}
+		return meta, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read plugin meta: %v", err)
+	}
+
+	return nil, errors.New("plugin meta not found")
+}
+
+// applyPluginMeta configures connection based on plugin meta. Flags set explicitly have precedence.
+func applyPluginMeta(opt *Options, meta *pluginMeta, setFlags map[string]bool) error {
+	isPublisher := meta.Plugin.Type == types.PluginTypePublisher
+
+	if !setFlags["plugin-ip"] && meta.GRPC.IP != "" {
+		opt.PluginIP = meta.GRPC.IP
+	}
+
+	switch {
+	case isPublisher && meta.GRPC.Socket != "":
+		return errors.New("unix domain socket is not supported for publishers")
+	case isPublisher:
+		if !setFlags["publisher-port"] {
+			opt.PublisherPort = meta.GRPC.Port
+		}
+	case meta.GRPC.Socket != "":
+		if !setFlags["collector-socket"] {
+			opt.CollectorSocket = meta.GRPC.Socket
+		}
+	default:
+		if !setFlags["collector-port"] {
+			opt.CollectorPort = meta.GRPC.Port
+		}
+	}
+
+	opt.TasksLimit = meta.Constraints.TasksLimit
+
+	if !setFlags["stream"] && meta.Plugin.Type == types.PluginTypeStreamingCollector {
+		opt.IsStream = true
+	}
+
+	if meta.GRPC.TLSEnabled {
+		opt.EnableTLS = true
+		if opt.TLSCertPath == "" || opt.TLSKeyPath == "" {
+			return errors.New("plugin requires TLS: client certificate and key have to be provided (-tls-cert, -tls-key)")
+		}
+	}
+
+	if meta.GRPC.TokenAuthEnabled && opt.AuthToken == "" && opt.AuthTokenFile == "" {
+		return errors.New("plugin requires authentication token (-auth-token or -auth-token-file)")
+	}
+
+	return nil
+}
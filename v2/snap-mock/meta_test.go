@@ -0,0 +1,111 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/internal/util/types"
+)
+
+func TestParsePluginMeta(t *testing.T) {
+	Convey("Validate that plugin meta is found in plugin output", t, func() {
+		Convey("Meta is the first JSON line with GRPC section", func() {
+			output := strings.Join([]string{
+				"starting plugin",
+				`{"level": "info", "msg": "not a meta"}`,
+				fmt.Sprintf(`{"Plugin": {"Name": "example", "Version": "1.0.0", "Type": %d}, "GRPC": {"IP": "127.0.0.1", "Port": 50123, "TLSEnabled": true}, "Constraints": {"TasksLimit": 2}}`, types.PluginTypePublisher),
+				`{"GRPC": {"Port": 1}}`,
+			}, "\n")
+
+			meta, err := parsePluginMeta(strings.NewReader(output))
+			So(err, ShouldBeNil)
+			So(meta.Plugin.Name, ShouldEqual, "example")
+			So(meta.Plugin.Type, ShouldEqual, types.PluginTypePublisher)
+			So(meta.GRPC.IP, ShouldEqual, "127.0.0.1")
+			So(meta.GRPC.Port, ShouldEqual, 50123)
+			So(meta.GRPC.TLSEnabled, ShouldBeTrue)
+			So(meta.Constraints.TasksLimit, ShouldEqual, 2)
+		})
+
+		Convey("Missing meta is reported", func() {
+			_, err := parsePluginMeta(strings.NewReader("starting plugin\n{\"level\": \"info\"}\n"))
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldEqual, "plugin meta not found")
+		})
+
+		Convey("Invalid meta is reported", func() {
+			_, err := parsePluginMeta(strings.NewReader(`{"GRPC": {"Port": "port"}}`))
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldStartWith, "invalid plugin meta")
+		})
+	})
+}
+
+func TestApplyPluginMeta(t *testing.T) {
+	Convey("Validate that connection options are configured based on plugin meta", t, func() {
+		meta := &pluginMeta{}
+		meta.Plugin.Type = types.PluginTypeStreamingCollector
+		meta.GRPC.IP = "127.0.0.2"
+		meta.GRPC.Port = 50123
+		meta.Constraints.TasksLimit = 3
+
+		Convey("Values from meta are used", func() {
+			opt := &Options{}
+			So(applyPluginMeta(opt, meta, map[string]bool{}), ShouldBeNil)
+			So(opt.PluginIP, ShouldEqual, "127.0.0.2")
+			So(opt.CollectorPort, ShouldEqual, 50123)
+			So(opt.TasksLimit, ShouldEqual, 3)
+			So(opt.IsStream, ShouldBeTrue)
+		})
+
+		Convey("Flags set explicitly have precedence", func() {
+			opt := &Options{PluginIP: "127.0.0.1", CollectorPort: 50000}
+			So(applyPluginMeta(opt, meta, map[string]bool{"plugin-ip": true, "collector-port": true, "stream": true}), ShouldBeNil)
+			So(opt.PluginIP, ShouldEqual, "127.0.0.1")
+			So(opt.CollectorPort, ShouldEqual, 50000)
+			So(opt.IsStream, ShouldBeFalse)
+		})
+
+		Convey("Publisher port and socket are handled", func() {
+			meta.Plugin.Type = types.PluginTypePublisher
+
+			opt := &Options{}
+			So(applyPluginMeta(opt, meta, map[string]bool{}), ShouldBeNil)
+			So(opt.PublisherPort, ShouldEqual, 50123)
+
+			meta.GRPC.Socket = "/tmp/plugin.sock"
+			So(applyPluginMeta(&Options{}, meta, map[string]bool{}), ShouldBeError)
+		})
+
+		Convey("Credentials required by plugin have to be provided", func() {
+			meta.GRPC.TLSEnabled = true
+			So(applyPluginMeta(&Options{}, meta, map[string]bool{}), ShouldBeError)
+			So(applyPluginMeta(&Options{TLSCertPath: "cli.crt", TLSKeyPath: "cli.key"}, meta, map[string]bool{}), ShouldBeNil)
+
+			meta.GRPC.TokenAuthEnabled = true
+			So(applyPluginMeta(&Options{TLSCertPath: "cli.crt", TLSKeyPath: "cli.key"}, meta, map[string]bool{}), ShouldBeError)
+			So(applyPluginMeta(&Options{TLSCertPath: "cli.crt", TLSKeyPath: "cli.key", AuthToken: "secret"}, meta, map[string]bool{}), ShouldBeNil)
+		})
+	})
+}
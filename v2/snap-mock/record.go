@@ -0,0 +1,245 @@
+/*
+ Copyright (c) 2022 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+	"google.golang.org/grpc"
+)
+
+const maxRecordLineSize = 64 * 1024 * 1024
+
+// collectRecord is a single line of record file (NDJSON) holding one CollectResponse received from plugin
+type collectRecord struct {
+	Time     time.Time       `json:"time"`
+	TaskID   string          `json:"taskId"`
+	Collect  int             `json:"collect"` // sequence number of collect request (responses with the same number are replayed as one Publish)
+	Response json.RawMessage `json:"response"`
+}
+
+// recordedBatch groups metrics received as a result of single collect request
+type recordedBatch struct {
+	time   time.Time
+	chunks [][]*pluginrpc.Metric
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+// recorder writes collect responses to file. nil recorder is valid and doesn't record anything.
+type recorder struct {
+	mu       sync.Mutex
+	file     *os.File
+	enc      *json.Encoder
+	marsh    *jsonpb.Marshaler
+	sequence int
+}
+
+func newRecorder(filePath string) (*recorder, error) {
+	if filePath == "" {
+		return nil, nil
+	}
+
+	f, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("can't create record file: %v", err)
+	}
+
+	return &recorder{
+		file:  f,
+		enc:   json.NewEncoder(f),
+		marsh: &jsonpb.Marshaler{},
+	}, nil
+}
+
+func (r *recorder) nextCollect() int {
+	if r == nil {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sequence++
+	return r.sequence
+}
+
+func (r *recorder) record(taskID string, collect int, resp *pluginrpc.CollectResponse) error {
+	if r == nil {
+		return nil
+	}
+
+	respJSON, err := r.marsh.MarshalToString(resp)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.enc.Encode(&collectRecord{
+		Time:     time.Now(),
+		TaskID:   taskID,
+		Collect:  collect,
+		Response: json.RawMessage(respJSON),
+	})
+}
+
+func (r *recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func readRecords(filePath string) ([]recordedBatch, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("can't open record file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var batches []recordedBatch
+	lastCollect := -1
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxRecordLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		rec := collectRecord{}
+		err := json.Unmarshal(scanner.Bytes(), &rec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record in line %d: %v", line, err)
+		}
+
+		resp := &pluginrpc.CollectResponse{}
+		err = jsonpb.UnmarshalString(string(rec.Response), resp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collect response in line %d: %v", line, err)
+		}
+
+		if rec.Collect != lastCollect || len(batches) == 0 {
+			batches = append(batches, recordedBatch{time: rec.Time})
+			lastCollect = rec.Collect
+		}
+
+		lastBatch := &batches[len(batches)-1]
+		lastBatch.chunks = append(lastBatch.chunks, resp.MetricSet)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read record file: %v", err)
+	}
+
+	return batches, nil
+}
+
+// runReplay connects to publisher and replays recorded metrics, returns exit code
+func runReplay(opt *Options, dialOpts []grpc.DialOption) int {
+	if opt.PublisherPort == defaultGRPCPort {
+		fmt.Printf("Replay requires -publisher-port\n")
+		return 1
+	}
+
+	grpcServerPubAddr := fmt.Sprintf("%s:%d", opt.PluginIP, opt.PublisherPort)
+	clPub, err := grpc.Dial(grpcServerPubAddr, dialOpts...)
+	if err != nil {
+		fmt.Printf("Can't start GRPC Server on %s (%v)\n", grpcServerPubAddr, err)
+		return 1
+	}
+	defer func() { _ = clPub.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go pingLoop(ctx, pluginrpc.NewControllerClient(clPub), opt.PingInterval)
+
+	err = replay(pluginrpc.NewPublisherClient(clPub), opt)
+	if err != nil {
+		fmt.Printf("Replay failed: %v\n", err)
+		return 1
+	}
+
+	if opt.SendKill {
+		err := doKillRequest(pluginrpc.NewControllerClient(clPub))
+		if err != nil {
+			fmt.Printf("Can't send kill request to plugin: %v\n", err)
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// replay sends recorded batches to publisher keeping original intervals between them divided by speed (0 - no delay)
+func replay(pc pluginrpc.PublisherClient, opt *Options) error {
+	batches, err := readRecords(opt.Replay)
+	if err != nil {
+		return err
+	}
+
+	err = doPubLoadRequest(pc, opt)
+	if err != nil {
+		return fmt.Errorf("can't send load request to plugin: %v", err)
+	}
+
+	failures := 0
+	for i, batch := range batches {
+		if i > 0 && opt.ReplaySpeed > 0 {
+			delay := batch.time.Sub(batches[i-1].time)
+			time.Sleep(time.Duration(float64(delay) / opt.ReplaySpeed))
+		}
+
+		mtsCount := 0
+		for _, chunk := range batch.chunks {
+			mtsCount += len(chunk)
+		}
+
+		err := doPublishRequest(pc, batch.chunks, opt)
+		if err != nil {
+			fmt.Printf("!! Publish request %d/%d failed (%v)\n", i+1, len(batches), err)
+			failures++
+			continue
+		}
+
+		fmt.Printf("Published %d metric(s) (%d/%d)\n", mtsCount, i+1, len(batches))
+	}
+
+	err = doPubUnloadRequest(pc, opt)
+	if err != nil {
+		return fmt.Errorf("can't send unload request to plugin: %v", err)
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d publish request(s) failed", failures, len(batches))
+	}
+
+	return nil
+}
@@ -0,0 +1,380 @@
+/*
+ Copyright (c) 2022 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	actionLoad    = "load"
+	actionCollect = "collect"
+	actionInfo    = "info"
+	actionUnload  = "unload"
+	actionKill    = "kill"
+	actionSleep   = "sleep"
+
+	scenarioFailed = 1
+)
+
+// Scenario describes scripted session with a collector: tasks with their configuration and steps executed against them.
+// Scenario file may be written in YAML or JSON, ie:
+//
+//	tasks:
+//	  - id: task-1
+//	    config: {format: short}
+//	    filter: [/example/date/*]
+//	steps:
+//	  - action: load
+//	    task: task-1
+//	  - parallel:
+//	      - {action: collect, task: task-1, expect: {min-metrics: 2, namespaces: [/example/date/day]}}
+//	      - {action: info, task: task-1}
+//	  - {action: sleep, duration: 1s}
+//	  - {action: unload, task: task-1}
+type Scenario struct {
+	Tasks []ScenarioTask `yaml:"tasks"`
+	Steps []ScenarioStep `yaml:"steps"`
+}
+
+type ScenarioTask struct {
+	ID     string                 `yaml:"id"`
+	Config map[string]interface{} `yaml:"config"`
+	Filter []string               `yaml:"filter"`
+}
+
+// ScenarioStep is a single request sent to plugin (or group of steps executed concurrently when Parallel is set).
+type ScenarioStep struct {
+	Action   string         `yaml:"action"`
+	Task     string         `yaml:"task"`
+	Delay    time.Duration  `yaml:"delay"`    // wait before step is executed
+	Duration time.Duration  `yaml:"duration"` // used by sleep action
+	Parallel []ScenarioStep `yaml:"parallel"`
+	Expect   Expectation    `yaml:"expect"`
+}
+
+// Expectation defines the outcome of a step. By default, step is expected to succeed.
+type Expectation struct {
+	Error      bool     `yaml:"error"`
+	MinMetrics int      `yaml:"min-metrics"` // collect only
+	Namespaces []string `yaml:"namespaces"`  // collect only, patterns as in path.Match (ie. /example/*/day)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+func loadScenario(filePath string) (*Scenario, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("can't read scenario file: %v", err)
+	}
+
+	scenario := &Scenario{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	err = dec.Decode(scenario)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse scenario file: %v", err)
+	}
+
+	err = scenario.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario: %v", err)
+	}
+
+	return scenario, nil
+}
+
+func (s *Scenario) validate() error {
+	taskIDs := map[string]bool{}
+	for i, task := range s.Tasks {
+		if task.ID == "" {
+			return fmt.Errorf("task %d: id is required", i+1)
+		}
+		if taskIDs[task.ID] {
+			return fmt.Errorf("task %d: duplicated id %q", i+1, task.ID)
+		}
+		taskIDs[task.ID] = true
+	}
+
+	if len(s.Steps) == 0 {
+		return errors.New("no steps defined")
+	}
+
+	return validateSteps(s.Steps, "", taskIDs)
+}
+
+func validateSteps(steps []ScenarioStep, prefix string, taskIDs map[string]bool) error {
+	for i, step := range steps {
+		label := stepLabel(prefix, i)
+
+		if len(step.Parallel) > 0 {
+			if step.Action != "" {
+				return fmt.Errorf("step %s: action and parallel can't be used together", label)
+			}
+
+			err := validateSteps(step.Parallel, label, taskIDs)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch step.Action {
+		case actionLoad, actionCollect, actionInfo, actionUnload:
+			if !taskIDs[step.Task] {
+				return fmt.Errorf("step %s: unknown task %q", label, step.Task)
+			}
+		case actionKill:
+		case actionSleep:
+			if step.Duration <= 0 {
+				return fmt.Errorf("step %s: sleep requires positive duration", label)
+			}
+		default:
+			return fmt.Errorf("step %s: unknown action %q", label, step.Action)
+		}
+
+		if step.Action != actionCollect && (step.Expect.MinMetrics != 0 || len(step.Expect.Namespaces) != 0) {
+			return fmt.Errorf("step %s: metric expectations are allowed only for collect", label)
+		}
+	}
+
+	return nil
+}
+
+func stepLabel(prefix string, i int) string {
+	if prefix == "" {
+		return fmt.Sprintf("%d", i+1)
+	}
+	return fmt.Sprintf("%s.%d", prefix, i+1)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+type scenarioRunner struct {
+	collClient pluginrpc.CollectorClient
+	contClient pluginrpc.ControllerClient
+	tasks      map[string]*Options
+	rec        *recorder
+
+	mu       sync.Mutex // guards output and failures
+	failures int
+}
+
+// runScenario executes scenario and returns exit code (non-zero when any step didn't meet expectations)
+func runScenario(opt *Options, cl *grpc.ClientConn, rec *recorder) int {
+	scenario, err := loadScenario(opt.Scenario)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return scenarioFailed
+	}
+
+	r := &scenarioRunner{
+		collClient: pluginrpc.NewCollectorClient(cl),
+		contClient: pluginrpc.NewControllerClient(cl),
+		tasks:      map[string]*Options{},
+		rec:        rec,
+	}
+
+	for _, task := range scenario.Tasks {
+		r.tasks[task.ID], err = taskOptions(opt, task)
+		if err != nil {
+			fmt.Printf("Invalid configuration of task %s (%v)\n", task.ID, err)
+			return scenarioFailed
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go pingLoop(ctx, r.contClient, opt.PingInterval)
+
+	r.runSteps(scenario.Steps, "")
+
+	if r.failures > 0 {
+		fmt.Printf("\nScenario failed: %d step(s) didn't meet expectations\n", r.failures)
+		return scenarioFailed
+	}
+
+	fmt.Printf("\nScenario completed successfully\n")
+	return 0
+}
+
+func taskOptions(opt *Options, task ScenarioTask) (*Options, error) {
+	taskOpt := *opt
+	taskOpt.TaskId = task.ID
+	taskOpt.PluginConfig = defaultConfig
+	taskOpt.PluginFilter = strings.Join(task.Filter, filterSeparator)
+	taskOpt.IsStream = false
+
+	if task.Config != nil {
+		config, err := json.Marshal(task.Config)
+		if err != nil {
+			return nil, err
+		}
+		taskOpt.PluginConfig = string(config)
+	}
+
+	return &taskOpt, nil
+}
+
+func (r *scenarioRunner) runSteps(steps []ScenarioStep, prefix string) {
+	for i, step := range steps {
+		r.runStep(step, stepLabel(prefix, i))
+	}
+}
+
+func (r *scenarioRunner) runParallel(steps []ScenarioStep, prefix string) {
+	wg := sync.WaitGroup{}
+	for i, step := range steps {
+		wg.Add(1)
+		go func(step ScenarioStep, label string) {
+			defer wg.Done()
+			r.runStep(step, label)
+		}(step, stepLabel(prefix, i))
+	}
+	wg.Wait()
+}
+
+func (r *scenarioRunner) runStep(step ScenarioStep, label string) {
+	time.Sleep(step.Delay)
+
+	if len(step.Parallel) > 0 {
+		r.runParallel(step.Parallel, label)
+		return
+	}
+
+	var mts []*pluginrpc.Metric
+	var err error
+	details := ""
+
+	taskOpt := r.tasks[step.Task]
+
+	switch step.Action {
+	case actionLoad:
+		err = doLoadRequest(r.collClient, taskOpt)
+	case actionCollect:
+		var warnings []string
+		mts, warnings, err = r.collect(taskOpt)
+		details = fmt.Sprintf("%d metric(s), %d warning(s)", len(mts), len(warnings))
+	case actionInfo:
+		var info []byte
+		info, err = doInfoRequest(r.collClient, taskOpt)
+		details = fmt.Sprintf("%d byte(s) of info", len(info))
+	case actionUnload:
+		err = doUnloadRequest(r.collClient, taskOpt)
+	case actionKill:
+		err = doKillRequest(r.contClient)
+	case actionSleep:
+		time.Sleep(step.Duration)
+	}
+
+	mismatch := step.Expect.check(err, mts)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := step.Action
+	if step.Task != "" {
+		name = fmt.Sprintf("%s %s", step.Action, step.Task)
+	}
+	if err != nil {
+		details = fmt.Sprintf("error: %v", err)
+	}
+
+	if mismatch != nil {
+		r.failures++
+		fmt.Printf("[%s] %s: FAILED (%v)\n", label, name, mismatch)
+		return
+	}
+
+	if details != "" {
+		fmt.Printf("[%s] %s: OK (%s)\n", label, name, details)
+	} else {
+		fmt.Printf("[%s] %s: OK\n", label, name)
+	}
+}
+
+func (r *scenarioRunner) collect(taskOpt *Options) ([]*pluginrpc.Metric, []string, error) {
+	var mts []*pluginrpc.Metric
+	var warnings []string
+	var err error
+
+	for chunk := range doCollectRequest(r.collClient, taskOpt, r.rec) {
+		mts = append(mts, chunk.mts...)
+		warnings = append(warnings, chunk.warnings...)
+		if chunk.err != nil {
+			err = chunk.err
+		}
+	}
+
+	return mts, warnings, err
+}
+
+func (e Expectation) check(err error, mts []*pluginrpc.Metric) error {
+	if e.Error {
+		if err == nil {
+			return errors.New("expected error, but request succeeded")
+		}
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("unexpected error: %v", err)
+	}
+
+	if len(mts) < e.MinMetrics {
+		return fmt.Errorf("expected at least %d metric(s), received %d", e.MinMetrics, len(mts))
+	}
+
+	for _, pattern := range e.Namespaces {
+		if !anyMetricMatches(pattern, mts) {
+			return fmt.Errorf("no metric matching %s", pattern)
+		}
+	}
+
+	return nil
+}
+
+func anyMetricMatches(pattern string, mts []*pluginrpc.Metric) bool {
+	for _, mt := range mts {
+		var nsStr []string
+		for _, ns := range mt.Namespace {
+			nsStr = append(nsStr, ns.Value)
+		}
+
+		matched, _ := path.Match(pattern, "/"+strings.Join(nsStr, "/"))
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
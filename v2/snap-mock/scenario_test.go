@@ -0,0 +1,222 @@
+//go:build small
+// +build small
+
+/*
+ Copyright (c) 2024 SolarWinds Worldwide, LLC
+
+    Licensed under the Apache License, Version 2.0 (the "License");
+    you may not use this file except in compliance with the License.
+    You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+    Unless required by applicable law or agreed to in writing, software
+    distributed under the License is distributed on an "AS IS" BASIS,
+    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+    See the License for the specific language governing permissions and
+    limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/solarwinds/snap-plugin-lib/v2/pluginrpc"
+)
+
+func TestValidateScenario(t *testing.T) {
+	tasks := []ScenarioTask{{ID: "task-1"}}
+
+	testCases := []struct {
+		name     string
+		scenario Scenario
+		wantErr  string
+	}{
+		{
+			"valid steps",
+			Scenario{Tasks: tasks, Steps: []ScenarioStep{
+				{Action: actionLoad, Task: "task-1"},
+				{Parallel: []ScenarioStep{
+					{Action: actionCollect, Task: "task-1", Expect: Expectation{MinMetrics: 1}},
+					{Action: actionInfo, Task: "task-1"},
+				}},
+				{Action: actionSleep, Duration: time.Second},
+				{Action: actionUnload, Task: "task-1"},
+				{Action: actionKill},
+			}},
+			"",
+		},
+		{
+			"task without id",
+			Scenario{Tasks: []ScenarioTask{{}}, Steps: []ScenarioStep{{Action: actionKill}}},
+			"task 1: id is required",
+		},
+		{
+			"duplicated task",
+			Scenario{Tasks: []ScenarioTask{{ID: "task-1"}, {ID: "task-1"}}, Steps: []ScenarioStep{{Action: actionKill}}},
+			`task 2: duplicated id "task-1"`,
+		},
+		{
+			"no steps",
+			Scenario{Tasks: tasks},
+			"no steps defined",
+		},
+		{
+			"unknown task",
+			Scenario{Tasks: tasks, Steps: []ScenarioStep{{Action: actionLoad, Task: "task-2"}}},
+			`step 1: unknown task "task-2"`,
+		},
+		{
+			"unknown action",
+			Scenario{Tasks: tasks, Steps: []ScenarioStep{{Action: "publish", Task: "task-1"}}},
+			`step 1: unknown action "publish"`,
+		},
+		{
+			"sleep without duration",
+			Scenario{Tasks: tasks, Steps: []ScenarioStep{{Action: actionSleep}}},
+			"step 1: sleep requires positive duration",
+		},
+		{
+			"action and parallel",
+			Scenario{Tasks: tasks, Steps: []ScenarioStep{
+				{Action: actionLoad, Task: "task-1", Parallel: []ScenarioStep{{Action: actionKill}}},
+			}},
+			"step 1: action and parallel can't be used together",
+		},
+		{
+			"invalid nested step",
+			Scenario{Tasks: tasks, Steps: []ScenarioStep{
+				{Action: actionLoad, Task: "task-1"},
+				{Parallel: []ScenarioStep{{Action: actionKill}, {Action: actionCollect, Task: "task-3"}}},
+			}},
+			`step 2.2: unknown task "task-3"`,
+		},
+		{
+			"metric expectations for other action",
+			Scenario{Tasks: tasks, Steps: []ScenarioStep{
+				{Action: actionInfo, Task: "task-1", Expect: Expectation{Namespaces: []string{"/example/*"}}},
+			}},
+			"step 1: metric expectations are allowed only for collect",
+		},
+	}
+
+	Convey("Validate that scenario is validated before execution", t, func() {
+		for _, tc := range testCases {
+			Convey(fmt.Sprintf("Scenario: %v", tc.name), func() {
+				err := tc.scenario.validate()
+
+				if tc.wantErr != "" {
+					So(err, ShouldBeError)
+					So(err.Error(), ShouldEqual, tc.wantErr)
+				} else {
+					So(err, ShouldBeNil)
+				}
+			})
+		}
+	})
+}
+
+func TestLoadScenario(t *testing.T) {
+	Convey("Validate that scenario file is parsed", t, func() {
+		dir := t.TempDir()
+
+		Convey("YAML file with nested steps", func() {
+			filePath := filepath.Join(dir, "scenario.yaml")
+			content := `
+tasks:
+  - id: task-1
+    config: {format: short}
+    filter: [/example/date/*]
+steps:
+  - {action: load, task: task-1}
+  - parallel:
+      - {action: collect, task: task-1, expect: {min-metrics: 2, namespaces: [/example/date/day]}}
+      - {action: info, task: task-1, delay: 100ms}
+  - {action: sleep, duration: 1s}
+`
+			So(os.WriteFile(filePath, []byte(content), 0600), ShouldBeNil)
+
+			scenario, err := loadScenario(filePath)
+			So(err, ShouldBeNil)
+			So(scenario.Tasks, ShouldHaveLength, 1)
+			So(scenario.Tasks[0].Filter, ShouldResemble, []string{"/example/date/*"})
+			So(scenario.Steps, ShouldHaveLength, 3)
+			So(scenario.Steps[1].Parallel[0].Expect, ShouldResemble, Expectation{MinMetrics: 2, Namespaces: []string{"/example/date/day"}})
+			So(scenario.Steps[1].Parallel[1].Delay, ShouldEqual, 100*time.Millisecond)
+			So(scenario.Steps[2].Duration, ShouldEqual, time.Second)
+		})
+
+		Convey("Unknown fields are rejected", func() {
+			filePath := filepath.Join(dir, "scenario.yaml")
+			So(os.WriteFile(filePath, []byte("steps:\n  - {action: kill, timeout: 1s}\n"), 0600), ShouldBeNil)
+
+			_, err := loadScenario(filePath)
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldContainSubstring, "can't parse scenario file")
+		})
+
+		Convey("Invalid scenario is rejected", func() {
+			filePath := filepath.Join(dir, "scenario.json")
+			So(os.WriteFile(filePath, []byte(`{"steps": [{"action": "collect", "task": "task-1"}]}`), 0600), ShouldBeNil)
+
+			_, err := loadScenario(filePath)
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldContainSubstring, "invalid scenario")
+		})
+	})
+}
+
+func testMetric(elements ...string) *pluginrpc.Metric {
+	mt := &pluginrpc.Metric{}
+	for _, el := range elements {
+		mt.Namespace = append(mt.Namespace, &pluginrpc.Namespace{Value: el})
+	}
+	return mt
+}
+
+func TestExpectationCheck(t *testing.T) {
+	mts := []*pluginrpc.Metric{
+		testMetric("example", "date", "day"),
+		testMetric("example", "date", "month"),
+	}
+	reqErr := errors.New("task not found")
+
+	testCases := []struct {
+		name      string
+		expect    Expectation
+		err       error
+		mts       []*pluginrpc.Metric
+		wantError string
+	}{
+		{"success by default", Expectation{}, nil, nil, ""},
+		{"unexpected error", Expectation{}, reqErr, nil, "unexpected error: task not found"},
+		{"expected error", Expectation{Error: true}, reqErr, nil, ""},
+		{"missing expected error", Expectation{Error: true}, nil, mts, "expected error, but request succeeded"},
+		{"enough metrics", Expectation{MinMetrics: 2}, nil, mts, ""},
+		{"too few metrics", Expectation{MinMetrics: 3}, nil, mts, "expected at least 3 metric(s), received 2"},
+		{"matching namespaces", Expectation{Namespaces: []string{"/example/date/day", "/example/*/month"}}, nil, mts, ""},
+		{"missing namespace", Expectation{Namespaces: []string{"/example/time/*"}}, nil, mts, "no metric matching /example/time/*"},
+	}
+
+	Convey("Validate that step outcome is checked against expectations", t, func() {
+		for _, tc := range testCases {
+			Convey(fmt.Sprintf("Scenario: %v", tc.name), func() {
+				err := tc.expect.check(tc.err, tc.mts)
+
+				if tc.wantError != "" {
+					So(err, ShouldBeError)
+					So(err.Error(), ShouldEqual, tc.wantError)
+				} else {
+					So(err, ShouldBeNil)
+				}
+			})
+		}
+	})
+}